@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// clfTimeFormat is the timestamp layout used by the Common Log Format.
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// accessLogMiddleware writes one Apache/nginx combined-format line per request
+// to out, followed by the request duration in seconds:
+//
+//	ip - - [time] "GET /weather?size=50 HTTP/1.1" 200 1234 "referer" "user-agent" 0.123
+func accessLogMiddleware(out io.Writer, next http.Handler) http.Handler {
+	var mu sync.Mutex
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, req)
+
+		host, _, err := net.SplitHostPort(req.RemoteAddr)
+		if err != nil {
+			host = req.RemoteAddr
+		}
+		size := "-"
+		if rec.BytesWritten() > 0 {
+			size = strconv.FormatInt(rec.BytesWritten(), 10)
+		}
+		line := fmt.Sprintf("%s - - [%s] %q %d %s %q %q %.3f\n",
+			host,
+			start.Format(clfTimeFormat),
+			req.Method+" "+req.URL.RequestURI()+" "+req.Proto,
+			rec.Status(),
+			size,
+			req.Referer(),
+			req.UserAgent(),
+			time.Since(start).Seconds(),
+		)
+
+		mu.Lock()
+		defer mu.Unlock()
+		io.WriteString(out, line)
+	})
+}
+
+// openAccessLog returns the writer for WEATHER_ACCESS_LOG: "stdout", "stderr"
+// or a file path opened for appending. It returns nil when access logs are off.
+func openAccessLog(target string) (io.Writer, error) {
+	switch target {
+	case "":
+		return nil, nil
+	case "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	default:
+		return os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// CityOutage takes City offline every day between From and To, given as
+// offsets from midnight UTC. A window with From after To wraps past midnight.
+type CityOutage struct {
+	City     string
+	From, To time.Duration
+}
+
+// contains reports whether the time of day t falls within the outage window.
+func (o CityOutage) contains(t time.Time) bool {
+	t = t.UTC()
+	offset := t.Sub(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC))
+	if o.From <= o.To {
+		return offset >= o.From && offset < o.To
+	}
+	return offset >= o.From || offset < o.To
+}
+
+// AvailabilitySchedule simulates stations going offline on a daily schedule.
+// Offline cities get 503 from /weather/{city} and are left out of /weather.
+type AvailabilitySchedule struct {
+	Outages []CityOutage
+	// Now returns the current time; tests can replace it. Defaults to time.Now.
+	Now func() time.Time
+}
+
+func (s *AvailabilitySchedule) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// Offline reports whether city is in a scheduled outage. A nil schedule
+// keeps every city online.
+func (s *AvailabilitySchedule) Offline(city string) bool {
+	if s == nil {
+		return false
+	}
+	now := s.now()
+	for _, outage := range s.Outages {
+		if outage.City == city && outage.contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// onlineCities returns the cities not in a scheduled outage, all of them for
+// a nil schedule.
+func (s *AvailabilitySchedule) onlineCities() []string {
+	if s == nil || len(s.Outages) == 0 {
+		return cities
+	}
+	var online []string
+	for _, city := range cities {
+		if !s.Offline(city) {
+			online = append(online, city)
+		}
+	}
+	return online
+}
+
+// omitOffline moves readings for offline cities to random online ones, so
+// the response contains no data for them. It returns no readings if every
+// city is offline.
+func (s *AvailabilitySchedule) omitOffline(readings []WeatherReading, r *rand.Rand) []WeatherReading {
+	online := s.onlineCities()
+	if len(online) == 0 {
+		return readings[:0]
+	}
+	if len(online) == len(cities) {
+		return readings
+	}
+	for i := range readings {
+		if s.Offline(readings[i].City) {
+			readings[i].City = online[r.Intn(len(online))]
+			readings[i].ID = readingID(readings[i])
+		}
+	}
+	return readings
+}
+
+// parseCityOutages parses a value such as "Lagos@00:00-06:00,Tokyo@22:00-02:00"
+// into outage windows in UTC.
+func parseCityOutages(value string) ([]CityOutage, error) {
+	var outages []CityOutage
+	for _, entry := range strings.Split(value, ",") {
+		name, window, ok := strings.Cut(strings.TrimSpace(entry), "@")
+		if !ok {
+			return nil, errorOf(ErrBadConfig, "invalid city outage %q, expected City@HH:MM-HH:MM", entry)
+		}
+		city, ok := lookupCity(strings.TrimSpace(name))
+		if !ok {
+			return nil, errorOf(ErrBadConfig, "unknown city %q", name)
+		}
+		fromStr, toStr, ok := strings.Cut(window, "-")
+		if !ok {
+			return nil, errorOf(ErrBadConfig, "invalid outage window %q for city %q, expected HH:MM-HH:MM", window, city)
+		}
+		from, err := parseTimeOfDay(fromStr)
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseTimeOfDay(toStr)
+		if err != nil {
+			return nil, err
+		}
+		outages = append(outages, CityOutage{City: city, From: from, To: to})
+	}
+	return outages, nil
+}
+
+// parseTimeOfDay parses "HH:MM" into an offset from midnight.
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, errorOf(ErrBadConfig, "invalid time of day %q, expected HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
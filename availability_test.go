@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestParseCityOutages tests parsing the WEATHER_CITY_OUTAGES format.
+func TestParseCityOutages(t *testing.T) {
+	outages, err := parseCityOutages("lagos@00:00-06:00, Tokyo@22:00-02:30")
+	if err != nil {
+		t.Fatalf("Could not parse outages: %v", err)
+	}
+	expected := []CityOutage{
+		{City: "Lagos", From: 0, To: 6 * time.Hour},
+		{City: "Tokyo", From: 22 * time.Hour, To: 2*time.Hour + 30*time.Minute},
+	}
+	if len(outages) != len(expected) || outages[0] != expected[0] || outages[1] != expected[1] {
+		t.Errorf("Parsed wrong outages: got %+v want %+v", outages, expected)
+	}
+
+	for _, value := range []string{"Lagos", "Atlantis@00:00-06:00", "Lagos@00:00", "Lagos@25:00-06:00"} {
+		if _, err := parseCityOutages(value); err == nil {
+			t.Errorf("Expected an error parsing %q", value)
+		}
+	}
+}
+
+// TestAvailabilityScheduleOffline tests outage windows, including ones that
+// wrap past midnight.
+func TestAvailabilityScheduleOffline(t *testing.T) {
+	now := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	s := &AvailabilitySchedule{
+		Outages: []CityOutage{
+			{City: "Lagos", From: 0, To: 6 * time.Hour},
+			{City: "Tokyo", From: 22 * time.Hour, To: 2 * time.Hour},
+		},
+		Now: func() time.Time { return now },
+	}
+
+	testCases := []struct {
+		hour    int
+		city    string
+		offline bool
+	}{
+		{3, "Lagos", true},
+		{6, "Lagos", false},
+		{23, "Tokyo", true},
+		{1, "Tokyo", true},
+		{3, "Tokyo", false},
+		{3, "Paris", false},
+	}
+	for _, tc := range testCases {
+		now = time.Date(2024, 1, 1, tc.hour, 0, 0, 0, time.UTC)
+		if got := s.Offline(tc.city); got != tc.offline {
+			t.Errorf("Wrong availability for %s at %02d:00: got offline=%v want %v", tc.city, tc.hour, got, tc.offline)
+		}
+	}
+}
+
+// TestWeatherCityOffline tests that an offline city gets 503 from
+// /weather/{city} and is left out of /weather.
+func TestWeatherCityOffline(t *testing.T) {
+	svc := &WeatherService{
+		Sleeper:      sleeper,
+		Chooser:      &FixedStatusChooser{Status: http.StatusOK},
+		RequestLog:   NewRequestLog(10),
+		Metrics:      &Metrics{},
+		Health:       &HealthChecker{},
+		Availability: &AvailabilitySchedule{Outages: []CityOutage{{City: "Lagos", From: 0, To: 24 * time.Hour}}},
+	}
+	router := svc.Router()
+
+	testCases := []struct {
+		path   string
+		status int
+	}{
+		{"/weather/Lagos", http.StatusServiceUnavailable},
+		{"/weather?city=Lagos", http.StatusServiceUnavailable},
+		{"/weather/new%20york", http.StatusOK},
+		{"/weather/Atlantis", http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", tc.path, nil))
+		if rr.Code != tc.status {
+			t.Errorf("Router returned wrong status code for %s: got %v want %v", tc.path, rr.Code, tc.status)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/weather?size=100", nil))
+	var responseData DataResponse
+	if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	if len(responseData.Readings) != 100 {
+		t.Errorf("Handler returned wrong number of readings: got %d want %d", len(responseData.Readings), 100)
+	}
+	for _, reading := range responseData.Readings {
+		if reading.City == "Lagos" {
+			t.Errorf("Response contains a reading for offline city Lagos")
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxBackfillPoints caps the number of timestamps a backfill may span.
+	maxBackfillPoints = 10000
+	// maxBackfillStepMinutes is the largest backfill interval (one day).
+	maxBackfillStepMinutes = 1440
+	// defaultBackfillGapRate is the share of points dropped when gapRate isn't given.
+	defaultBackfillGapRate = 0.05
+)
+
+// generateBackfill generates one reading for city every step from from to to
+// inclusive, dropping each point with probability gapRate to simulate gaps in
+// the historical data.
+func generateBackfill(city string, from, to time.Time, step time.Duration, gapRate float64) []WeatherReading {
+	var readings []WeatherReading
+	for ts := from; !ts.After(to); ts = ts.Add(step) {
+		if r.Float64() < gapRate {
+			continue
+		}
+		reading := generateDummyWeatherReadings(r, 1)[0]
+		reading.City = city
+		reading.Timestamp = ts
+		reading.ID = readingID(reading)
+		readings = append(readings, reading)
+	}
+	return readings
+}
+
+// backfillHandler handles GET requests to the /weather/backfill endpoint.
+func backfillHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	q := req.URL.Query()
+
+	city, ok := lookupCity(q.Get("city"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, DataResponse{
+			Message: fmt.Sprintf("Unknown or missing 'city' parameter: %q.", q.Get("city")),
+		})
+		return
+	}
+
+	from, err := time.Parse(time.RFC3339, q.Get("from"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, DataResponse{
+			Message: fmt.Sprintf("Invalid or missing 'from' parameter %q, expected an RFC 3339 timestamp.", q.Get("from")),
+		})
+		return
+	}
+	to, err := time.Parse(time.RFC3339, q.Get("to"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, DataResponse{
+			Message: fmt.Sprintf("Invalid or missing 'to' parameter %q, expected an RFC 3339 timestamp.", q.Get("to")),
+		})
+		return
+	}
+	if to.Before(from) {
+		writeJSON(w, http.StatusBadRequest, DataResponse{Message: "'to' must not be before 'from'."})
+		return
+	}
+
+	stepMinutes := 60 // Default backfill interval
+	if stepStr := q.Get("stepMinutes"); stepStr != "" {
+		stepMinutes, err = strconv.Atoi(stepStr)
+		if err != nil || stepMinutes < 1 || stepMinutes > maxBackfillStepMinutes {
+			writeJSON(w, http.StatusBadRequest, DataResponse{
+				Message: fmt.Sprintf("Invalid 'stepMinutes' parameter %q, expected 1 to %d.", stepStr, maxBackfillStepMinutes),
+			})
+			return
+		}
+	}
+	step := time.Duration(stepMinutes) * time.Minute
+	if points := to.Sub(from)/step + 1; points > maxBackfillPoints {
+		writeJSON(w, http.StatusBadRequest, DataResponse{
+			Message: fmt.Sprintf("Range spans %d points, more than the limit of %d; narrow the range or increase 'stepMinutes'.", points, maxBackfillPoints),
+		})
+		return
+	}
+
+	gapRate := defaultBackfillGapRate
+	if gapStr := q.Get("gapRate"); gapStr != "" {
+		gapRate, err = strconv.ParseFloat(gapStr, 64)
+		if err != nil || gapRate < 0 || gapRate > 1 {
+			writeJSON(w, http.StatusBadRequest, DataResponse{
+				Message: fmt.Sprintf("Invalid 'gapRate' parameter %q, expected 0 to 1.", gapStr),
+			})
+			return
+		}
+	}
+
+	readings := generateBackfill(city, from, to, step, gapRate)
+	slog.Info("Responding with backfill", "city", city, "from", from, "to", to, "step", step, "readings", len(readings))
+	writeJSON(w, http.StatusOK, DataResponse{
+		Readings:    readings,
+		Message:     fmt.Sprintf("Successfully retrieved %d backfilled readings for %s.", len(readings), city),
+		GeneratedAt: time.Now().UTC(),
+	})
+}
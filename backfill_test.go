@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestBackfillHandler tests that backfilled readings fall on the requested
+// steps within the range.
+func TestBackfillHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/weather/backfill?city=lagos&from=2024-01-01T00:00:00Z&to=2024-01-01T06:00:00Z&stepMinutes=15&gapRate=0", nil)
+	rr := httptest.NewRecorder()
+	backfillHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Backfill handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var responseData DataResponse
+	if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	if len(responseData.Readings) != 25 {
+		t.Fatalf("Backfill handler returned unexpected number of readings: got %d want %d", len(responseData.Readings), 25)
+	}
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, reading := range responseData.Readings {
+		if want := from.Add(time.Duration(i) * 15 * time.Minute); !reading.Timestamp.Equal(want) {
+			t.Errorf("Reading %d has wrong timestamp: got %v want %v", i, reading.Timestamp, want)
+		}
+		if reading.City != "Lagos" {
+			t.Errorf("Reading %d has wrong city: got %v want %v", i, reading.City, "Lagos")
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/weather/backfill?city=Lagos&from=2024-01-01T00:00:00Z&to=2024-01-02T00:00:00Z&gapRate=1", nil)
+	rr = httptest.NewRecorder()
+	backfillHandler(rr, req)
+	if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	if len(responseData.Readings) != 0 {
+		t.Errorf("Backfill with gapRate=1 returned %d readings", len(responseData.Readings))
+	}
+}
+
+// TestBackfillHandlerInvalidParams tests the /weather/backfill endpoint with invalid parameters.
+func TestBackfillHandlerInvalidParams(t *testing.T) {
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"MissingCity", "from=2024-01-01T00:00:00Z&to=2024-01-02T00:00:00Z"},
+		{"MissingFrom", "city=Lagos&to=2024-01-02T00:00:00Z"},
+		{"BadTo", "city=Lagos&from=2024-01-01T00:00:00Z&to=tomorrow"},
+		{"Reversed", "city=Lagos&from=2024-01-02T00:00:00Z&to=2024-01-01T00:00:00Z"},
+		{"ZeroStep", "city=Lagos&from=2024-01-01T00:00:00Z&to=2024-01-02T00:00:00Z&stepMinutes=0"},
+		{"TooManyPoints", "city=Lagos&from=2000-01-01T00:00:00Z&to=2024-01-01T00:00:00Z&stepMinutes=1"},
+		{"BadGapRate", "city=Lagos&from=2024-01-01T00:00:00Z&to=2024-01-02T00:00:00Z&gapRate=2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			backfillHandler(rr, httptest.NewRequest("GET", "/weather/backfill?"+tc.query, nil))
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Backfill handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
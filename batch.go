@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxBatchCities is the most cities one batch request may ask for.
+	maxBatchCities = 50
+	// defaultBatchFailRate is the share of items that fail without failRate.
+	defaultBatchFailRate = 0.25
+)
+
+// BatchItem is the result for one city of a batch request, with its own
+// status: a reading for 200, an error message otherwise.
+type BatchItem struct {
+	City    string          `json:"city"`
+	Status  int             `json:"status"`
+	Reading *WeatherReading `json:"reading,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// BatchResponse is the 207 Multi-Status response of /weather/batch.
+type BatchResponse struct {
+	Results   []BatchItem `json:"results"`
+	Succeeded int         `json:"succeeded"`
+	Failed    int         `json:"failed"`
+}
+
+// batch serves GET /weather/batch?cities=Tokyo,Paris, which fetches a reading
+// for each city and reports a status per item, always with 207 Multi-Status:
+// each item fails with 500 with probability failRate, and offline cities fail
+// with 503. This models partial failures that one response status can't.
+func (svc *WeatherService) batch(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	q := req.URL.Query()
+
+	var names []string
+	if value := q.Get("cities"); value != "" {
+		names = strings.Split(value, ",")
+	}
+	if len(names) == 0 || len(names) > maxBatchCities {
+		writeJSON(w, http.StatusBadRequest, DataResponse{
+			Message: fmt.Sprintf("Invalid 'cities' parameter %q, expected 1 to %d comma-separated cities.", q.Get("cities"), maxBatchCities),
+		})
+		return
+	}
+	batchCities := make([]string, len(names))
+	var unknown []string
+	for i, name := range names {
+		city, ok := lookupCity(strings.TrimSpace(name))
+		if !ok {
+			unknown = append(unknown, strconv.Quote(name))
+		}
+		batchCities[i] = city
+	}
+	if len(unknown) > 0 {
+		writeJSON(w, http.StatusBadRequest, DataResponse{
+			Message: fmt.Sprintf("Unknown cities in 'cities' parameter: %s.", strings.Join(unknown, ", ")),
+		})
+		return
+	}
+
+	failRate := defaultBatchFailRate
+	if rateStr := q.Get("failRate"); rateStr != "" {
+		var err error
+		failRate, err = strconv.ParseFloat(rateStr, 64)
+		if err != nil || failRate < 0 || failRate > 1 {
+			writeJSON(w, http.StatusBadRequest, DataResponse{
+				Message: fmt.Sprintf("Invalid 'failRate' parameter %q, expected 0 to 1.", rateStr),
+			})
+			return
+		}
+	}
+
+	// A seed makes the outcome of every item reproducible.
+	rng := svc.rand()
+	if seedStr := q.Get("seed"); seedStr != "" {
+		seed, err := strconv.ParseInt(seedStr, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, DataResponse{
+				Message: fmt.Sprintf("Invalid 'seed' parameter %q, expected an integer.", seedStr),
+			})
+			return
+		}
+		rng = rand.New(rand.NewSource(seed))
+	}
+
+	response := BatchResponse{Results: make([]BatchItem, len(batchCities))}
+	now := svc.now()
+	for i, city := range batchCities {
+		item := BatchItem{City: city, Status: http.StatusOK}
+		switch {
+		case svc.Availability.Offline(city):
+			item.Status = http.StatusServiceUnavailable
+			item.Error = fmt.Sprintf("City %s is offline for scheduled maintenance", city)
+		case rng.Float64() < failRate:
+			item.Status = http.StatusInternalServerError
+			item.Error = "An error occurred with status code 500. This is a dummy error for testing."
+		default:
+			reading := appendDummyWeatherReadings(nil, rng, 1, now)[0]
+			reading.City = city
+			reading.StationID = pickStation(rng, city, svc.StationsPerCity)
+			reading.ID = readingID(reading)
+			item.Reading = &reading
+		}
+		if item.Status == http.StatusOK {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+		response.Results[i] = item
+	}
+
+	slog.Info("Responding with batch results", "cities", len(batchCities), "succeeded", response.Succeeded, "failed", response.Failed)
+	writeJSON(w, http.StatusMultiStatus, response)
+}
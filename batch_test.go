@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// TestBatch tests that /weather/batch reports a status per city with 207,
+// mixing successes and failures, reproducibly for a seed.
+func TestBatch(t *testing.T) {
+	handler := newTestService(1, nil, nil).Handler()
+	get := func(target string) BatchResponse {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != http.StatusMultiStatus {
+			t.Fatalf("%s returned wrong status code: got %v want %v", target, rr.Code, http.StatusMultiStatus)
+		}
+		var response BatchResponse
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatalf("Could not decode batch results: %v", err)
+		}
+		return response
+	}
+
+	const target = "/weather/batch?cities=Tokyo,paris,London,Sydney,Rio,New%20York,Tokyo,Paris&failRate=0.5&seed=3"
+	response := get(target)
+	if len(response.Results) != 8 || response.Succeeded+response.Failed != 8 {
+		t.Fatalf("Handler returned wrong number of results: %+v", response)
+	}
+	if response.Succeeded == 0 || response.Failed == 0 {
+		t.Errorf("Expected a mix of successes and failures, got %d and %d", response.Succeeded, response.Failed)
+	}
+	for i, item := range response.Results {
+		switch item.Status {
+		case http.StatusOK:
+			if item.Reading == nil || item.Reading.City != item.City || item.Error != "" {
+				t.Errorf("Successful item %d is malformed: %+v", i, item)
+			}
+		case http.StatusInternalServerError:
+			if item.Reading != nil || item.Error == "" {
+				t.Errorf("Failed item %d is malformed: %+v", i, item)
+			}
+		default:
+			t.Errorf("Item %d has unexpected status %d", i, item.Status)
+		}
+	}
+	if response.Results[1].City != "Paris" {
+		t.Errorf("City was not canonicalized: got %q want %q", response.Results[1].City, "Paris")
+	}
+	if again := get(target); !reflect.DeepEqual(again, response) {
+		t.Errorf("The same seed returned different results")
+	}
+
+	for failRate, wantFailed := range map[string]int{"0": 0, "1": 2} {
+		if response := get("/weather/batch?cities=Tokyo,Paris&failRate=" + failRate); response.Failed != wantFailed {
+			t.Errorf("failRate=%s failed %d items, want %d", failRate, response.Failed, wantFailed)
+		}
+	}
+}
+
+// TestBatchInvalid tests that missing, unknown and too many cities and
+// invalid failRate and seed values are rejected.
+func TestBatchInvalid(t *testing.T) {
+	handler := newTestService(1, nil, nil).Handler()
+	tooMany := "/weather/batch?cities=Tokyo"
+	for i := 0; i < maxBatchCities; i++ {
+		tooMany += ",Tokyo"
+	}
+	for _, target := range []string{
+		"/weather/batch",
+		"/weather/batch?cities=Tokyo,Atlantis",
+		tooMany,
+		"/weather/batch?cities=Tokyo&failRate=2",
+		"/weather/batch?cities=Tokyo&seed=x",
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s returned wrong status code: got %v want %v", target, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
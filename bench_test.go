@@ -0,0 +1,126 @@
+package main
+
+import (
+	"compress/gzip"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// benchReadings keeps benchmark results alive so the compiler can't elide them.
+var benchReadings []WeatherReading
+
+// BenchmarkGenerateReadings measures generating a maximum-size response.
+func BenchmarkGenerateReadings(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchReadings = generateDummyWeatherReadings(rng, 100)
+	}
+}
+
+// BenchmarkGenerateReadingsPooled measures generating into a recycled buffer,
+// as weatherHandler does.
+func BenchmarkGenerateReadingsPooled(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := readingsPool.Get().(*[]WeatherReading)
+		*buf = appendDummyWeatherReadings((*buf)[:0], rng, 100, time.Now())
+		benchReadings = *buf
+		readingsPool.Put(buf)
+	}
+}
+
+// discardResponseWriter is an http.ResponseWriter that throws the body away,
+// so encoding benchmarks don't measure a recorder's buffer growth.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+
+// BenchmarkEncodeResponse measures writing a maximum-size response with every
+// optional reading field populated.
+func BenchmarkEncodeResponse(b *testing.B) {
+	readings := generateDummyWeatherReadings(rand.New(rand.NewSource(1)), 100)
+	for i := range readings {
+		readings[i].Icon = conditionIcons[readings[i].Condition]
+		readings[i].Anomaly = i%10 == 0
+	}
+	response := DataResponse{Readings: readings, Units: unitsCelsius, Message: "Successfully retrieved 100 weather readings."}
+	w := &discardResponseWriter{header: make(http.Header)}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// BenchmarkGzipResponse measures compressing a maximum-size response at the
+// fastest, default and smallest gzip levels, reporting the compressed size.
+func BenchmarkGzipResponse(b *testing.B) {
+	readings := generateDummyWeatherReadings(rand.New(rand.NewSource(1)), 100)
+	response := DataResponse{Readings: readings, Units: unitsCelsius, Message: "Successfully retrieved 100 weather readings."}
+
+	for _, level := range []int{gzip.BestSpeed, gzip.DefaultCompression, gzip.BestCompression} {
+		b.Run("level="+strconv.Itoa(level), func(b *testing.B) {
+			counter := &countingResponseWriter{discardResponseWriter: discardResponseWriter{header: make(http.Header)}}
+			handler := gzipMiddleware(level, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				writeJSON(w, http.StatusOK, response)
+			}))
+			req, _ := http.NewRequest("GET", "/weather", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				clear(counter.header)
+				handler.ServeHTTP(counter, req)
+			}
+			b.ReportMetric(float64(counter.bytes)/float64(b.N), "bytes/op")
+		})
+	}
+}
+
+// countingResponseWriter is a discardResponseWriter that counts body bytes.
+type countingResponseWriter struct {
+	discardResponseWriter
+	bytes int
+}
+
+func (w *countingResponseWriter) Write(b []byte) (int, error) {
+	w.bytes += len(b)
+	return len(b), nil
+}
+
+// BenchmarkSharedRandParallel compares the mutex-guarded lockedSource with
+// sharedSource's math/rand/v2 generator as the shared source under parallel
+// load, generating a maximum-size response and a status per op. Run it with
+// -cpu 1,4,8 to see the effect of contention.
+func BenchmarkSharedRandParallel(b *testing.B) {
+	sources := []struct {
+		name string
+		src  rand.Source
+	}{
+		{"locked", newLockedSource(1)},
+		{"v2", &sharedSource{}},
+	}
+	for _, bc := range sources {
+		b.Run(bc.name, func(b *testing.B) {
+			rng := rand.New(bc.src)
+			now := time.Now()
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				buf := make([]WeatherReading, 0, 100)
+				for pb.Next() {
+					buf = appendDummyWeatherReadings(buf[:0], rng, 100, now)
+					getResponseStatusCode(rng)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// progressSleeper records how much of the body had been written at each sleep.
+type progressSleeper struct {
+	rr      *httptest.ResponseRecorder
+	written []int
+	total   time.Duration
+}
+
+func (s *progressSleeper) Sleep(d time.Duration) {
+	s.written = append(s.written, s.rr.Body.Len())
+	s.total += d
+}
+
+// TestWeatherHandlerBodyDelay tests that bodyDelay flushes the headers first
+// and then pauses before each chunk of the body.
+func TestWeatherHandlerBodyDelay(t *testing.T) {
+	rr := httptest.NewRecorder()
+	s := &progressSleeper{rr: rr}
+	req := httptest.NewRequest("GET", "/weather?delayMs=0&bodyDelay=500ms", nil)
+	weatherHandler(s, &FixedStatusChooser{Status: http.StatusOK}, rr, req)
+
+	if rr.Code != http.StatusOK || !rr.Flushed {
+		t.Fatalf("Handler returned status %v, flushed %v; want 200 flushed", rr.Code, rr.Flushed)
+	}
+	// The first sleep is the zero pre-response delay.
+	if len(s.written) != 1+bodyDelayChunks {
+		t.Fatalf("Handler slept %d times, want %d", len(s.written), 1+bodyDelayChunks)
+	}
+	for i := 2; i < len(s.written); i++ {
+		if s.written[i] <= s.written[i-1] {
+			t.Errorf("No body was written between pauses %d and %d: %v", i-1, i, s.written)
+		}
+	}
+	if want := bodyDelayChunks * 500 * time.Millisecond; s.total != want {
+		t.Errorf("Handler slept for %v, want %v", s.total, want)
+	}
+	if got := rr.Header().Get("Content-Length"); got != strconv.Itoa(rr.Body.Len()) {
+		t.Errorf("Content-Length is %s, want %d", got, rr.Body.Len())
+	}
+	if !json.Valid(rr.Body.Bytes()) {
+		t.Errorf("Body is not valid JSON: %s", rr.Body)
+	}
+}
+
+// TestWeatherHandlerBodyDelayInvalid tests that invalid and conflicting
+// bodyDelay values are rejected.
+func TestWeatherHandlerBodyDelayInvalid(t *testing.T) {
+	for _, target := range []string{
+		"/weather?bodyDelay=slowly",
+		"/weather?bodyDelay=-1s",
+		"/weather?bodyDelay=2m",
+		"/weather?bodyDelay=100&keepAlive=true",
+	} {
+		rr := httptest.NewRecorder()
+		weatherHandler(sleeper, chooser, rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s returned wrong status code: got %v want %v", target, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	// defaultBombMB is the decompressed size of a decompression bomb without sizeMB.
+	defaultBombMB = 10
+	// maxBombMB is the largest decompressed size a client may request.
+	maxBombMB = 1024
+)
+
+// bombPadding is a chunk of the whitespace decompression bombs are made of.
+var bombPadding = bytes.Repeat([]byte(" "), 64<<10)
+
+// writeDecompressBomb writes v as gzip-compressed JSON padded with leading
+// whitespace to size bytes once decompressed. Whitespace compresses about
+// 1000:1, so a 50 MB body is sent as about 50 KB, for testing that clients
+// cap decompressed sizes. JSON parsers ignore the whitespace, so a client
+// without a cap still gets a valid body. The padding is streamed, so the
+// server doesn't hold the decompressed body in memory.
+func writeDecompressBomb(w http.ResponseWriter, status int, v any, size int64) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("Could not encode JSON response", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(status)
+
+	gz, _ := gzip.NewWriterLevel(w, gzip.BestCompression)
+	defer gz.Close()
+	for padding := size - int64(len(body)); padding > 0; {
+		chunk := bombPadding[:min(padding, int64(len(bombPadding)))]
+		if _, err := gz.Write(chunk); err != nil {
+			slog.Info("Client went away during the decompression bomb", "error", err)
+			return
+		}
+		padding -= int64(len(chunk))
+	}
+	gz.Write(body)
+}
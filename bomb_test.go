@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestDecompressBomb tests that decompressBomb sends a small gzip body that
+// decompresses to sizeMB of valid JSON.
+func TestDecompressBomb(t *testing.T) {
+	svc := newTestService(1, nil, &FixedStatusChooser{Status: http.StatusOK})
+	svc.DecompressBomb = true
+	rr := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/weather?decompressBomb=true&sizeMB=5", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if got := rr.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("Content-Encoding is %q, want gzip", got)
+	}
+	if rr.Body.Len() > 64<<10 {
+		t.Errorf("Compressed body is %d bytes, want at most 64 KiB", rr.Body.Len())
+	}
+	gz, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("Body is not gzip: %v", err)
+	}
+	body, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("Could not decompress body: %v", err)
+	}
+	if len(body) != 5<<20 {
+		t.Errorf("Decompressed body is %d bytes, want %d", len(body), 5<<20)
+	}
+	var responseData DataResponse
+	if err := json.Unmarshal(body, &responseData); err != nil {
+		t.Fatalf("Decompressed body is not valid JSON: %v", err)
+	}
+	if len(responseData.Readings) == 0 {
+		t.Error("Decompressed body has no readings")
+	}
+}
+
+// TestDecompressBombDisabled tests that decompressBomb is ignored unless the
+// server enables it.
+func TestDecompressBombDisabled(t *testing.T) {
+	svc := newTestService(1, nil, &FixedStatusChooser{Status: http.StatusOK})
+	rr := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/weather?decompressBomb=true", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if got := rr.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding is %q, want none", got)
+	}
+	if !json.Valid(bytes.TrimSpace(rr.Body.Bytes())) {
+		t.Errorf("Body is not plain JSON: %.100q", rr.Body.String())
+	}
+}
+
+// TestDecompressBombInvalid tests that bad sizes and conflicting options are
+// rejected.
+func TestDecompressBombInvalid(t *testing.T) {
+	for _, query := range []string{
+		"decompressBomb=true&sizeMB=0",
+		"decompressBomb=true&sizeMB=2000",
+		"decompressBomb=true&truncate=true",
+		"decompressBomb=true&stream=array",
+		"decompressBomb=true&status=204",
+	} {
+		svc := newTestService(1, nil, &FixedStatusChooser{Status: http.StatusOK})
+		svc.DecompressBomb = true
+		rr := httptest.NewRecorder()
+		svc.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/weather?"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s returned wrong status code: got %v want %v", query, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// OutageSimulator models correlated failures: periodic or randomly triggered
+// outages of a fixed Duration. If Probability is positive, each request
+// outside an outage starts one with that probability; otherwise, if Every is
+// positive, the last Duration of every Every-long period since Start is an
+// outage.
+type OutageSimulator struct {
+	Every       time.Duration
+	Probability float64
+	Duration    time.Duration
+	Start       time.Time
+	// Now returns the current time; tests can replace it. Defaults to time.Now.
+	Now func() time.Time
+
+	mu    sync.Mutex
+	until time.Time
+}
+
+func (o *OutageSimulator) now() time.Time {
+	if o.Now != nil {
+		return o.Now()
+	}
+	return time.Now()
+}
+
+// Active reports whether an outage is in progress, possibly starting a
+// random one using r.
+func (o *OutageSimulator) Active(r *rand.Rand) bool {
+	now := o.now()
+
+	if o.Probability > 0 {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if now.Before(o.until) {
+			return true
+		}
+		if r.Float64() < o.Probability {
+			o.until = now.Add(o.Duration)
+			slog.Warn("Outage burst started", "until", o.until)
+			return true
+		}
+		return false
+	}
+
+	if o.Every > 0 {
+		offset := now.Sub(o.Start) % o.Every
+		return offset >= o.Every-o.Duration
+	}
+	return false
+}
+
+// Reset ends a randomly started outage. Periodic outages follow the clock and
+// are unaffected.
+func (o *OutageSimulator) Reset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.until = time.Time{}
+}
+
+// BurstChooser implements StatusChooser by returning 503 for every request
+// during an outage and deferring to Inner otherwise.
+type BurstChooser struct {
+	Inner  StatusChooser
+	Outage *OutageSimulator
+}
+
+// ChooseStatus returns 503 during an outage, or the inner chooser's status.
+func (c *BurstChooser) ChooseStatus(r *rand.Rand) int {
+	if c.Outage.Active(r) {
+		return http.StatusServiceUnavailable
+	}
+	return c.Inner.ChooseStatus(r)
+}
+
+// ChooseRequestedStatus returns 503 during an outage, even when the client
+// asked for another status, and otherwise passes status to the inner chooser.
+func (c *BurstChooser) ChooseRequestedStatus(r *rand.Rand, status int) int {
+	if c.Outage.Active(r) {
+		return http.StatusServiceUnavailable
+	}
+	return chooseRequestedStatus(c.Inner, r, status)
+}
+
+// Reset resets the outage simulator and the inner chooser.
+func (c *BurstChooser) Reset() {
+	c.Outage.Reset()
+	resetChooser(c.Inner)
+}
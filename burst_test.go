@@ -0,0 +1,57 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestOutageSimulatorScheduled tests that scheduled outages cover the end of each period.
+func TestOutageSimulatorScheduled(t *testing.T) {
+	start := time.Unix(0, 0)
+	now := start
+	outage := &OutageSimulator{Every: time.Minute, Duration: 5 * time.Second, Start: start, Now: func() time.Time { return now }}
+	c := &BurstChooser{Inner: &FixedStatusChooser{Status: http.StatusOK}, Outage: outage}
+
+	testCases := []struct {
+		at     time.Duration
+		status int
+	}{
+		{0, http.StatusOK},
+		{54 * time.Second, http.StatusOK},
+		{55 * time.Second, http.StatusServiceUnavailable},
+		{59 * time.Second, http.StatusServiceUnavailable},
+		{60 * time.Second, http.StatusOK},
+		{116 * time.Second, http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		now = start.Add(tc.at)
+		if status := c.ChooseStatus(nil); status != tc.status {
+			t.Errorf("Status at %v: got %d want %d", tc.at, status, tc.status)
+		}
+	}
+}
+
+// TestOutageSimulatorProbabilistic tests that a random outage lasts for its duration.
+func TestOutageSimulatorProbabilistic(t *testing.T) {
+	now := time.Unix(0, 0)
+	outage := &OutageSimulator{Probability: 1, Duration: 3 * time.Second, Now: func() time.Time { return now }}
+	rng := rand.New(rand.NewSource(1))
+
+	if !outage.Active(rng) {
+		t.Fatalf("Expected an outage to start with probability 1.")
+	}
+
+	// Stop new outages from starting; the current one must still run its course.
+	outage.Probability = 1e-9
+	now = now.Add(2 * time.Second)
+	if !outage.Active(rng) {
+		t.Errorf("Expected the outage to still be active after 2s.")
+	}
+	now = now.Add(time.Second)
+	if outage.Active(rng) {
+		t.Errorf("Expected the outage to be over after 3s.")
+	}
+}
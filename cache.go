@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCacheEntries bounds the response cache so unique queries can't grow it forever.
+const maxCacheEntries = 1000
+
+// cachedResponse is a stored /weather response.
+type cachedResponse struct {
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// uncachedHeaders are the response headers a cached response doesn't keep:
+// hop-by-hop headers, those set per request, such as the request ID and
+// clock, and those recomputed when a hit is served. Content-Encoding is left
+// to the compression middleware, which encodes hits like any response.
+var uncachedHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Connection", "Transfer-Encoding", "Trailer", "Upgrade",
+	"Content-Encoding", "Content-Length", "Date", "Set-Cookie", "X-Cache", "X-Request-ID",
+}
+
+// cacheableHeader returns a copy of h without the uncachedHeaders.
+func cacheableHeader(h http.Header) http.Header {
+	header := h.Clone()
+	for _, name := range uncachedHeaders {
+		header.Del(name)
+	}
+	return header
+}
+
+// ResponseCache is an in-memory cache of /weather responses keyed by the
+// path, query string and Prefer header, with a fixed TTL.
+type ResponseCache struct {
+	TTL time.Duration
+	// Now returns the current time; tests can replace it. Defaults to time.Now.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+}
+
+func (c *ResponseCache) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// get returns the unexpired response stored under key.
+func (c *ResponseCache) get(key string) (cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return cachedResponse{}, false
+	}
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+		return cachedResponse{}, false
+	}
+	return entry, true
+}
+
+// Reset discards every stored response.
+func (c *ResponseCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// put stores a response under key. When full, it drops expired entries, or
+// else the oldest one, so new keys are always stored.
+func (c *ResponseCache) put(key string, entry cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]cachedResponse)
+	}
+	now := c.now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxCacheEntries {
+		oldest := ""
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			} else if oldest == "" || e.expires.Before(c.entries[oldest].expires) {
+				oldest = k
+			}
+		}
+		// Every entry has the same TTL, so the first to expire is the oldest.
+		if len(c.entries) >= maxCacheEntries {
+			delete(c.entries, oldest)
+		}
+	}
+	entry.expires = now.Add(c.TTL)
+	c.entries[key] = entry
+}
+
+// bodyRecorder wraps an http.ResponseWriter and keeps a copy of the status
+// and body so the response can be cached.
+type bodyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *bodyRecorder) WriteHeader(code int) {
+	if rec.status == 0 {
+		rec.status = code
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *bodyRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *bodyRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer so http.ResponseController can reach it.
+func (rec *bodyRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// cacheMiddleware serves GET requests from the cache when an identical query
+// was answered within the TTL, skipping the handler (and its sleep) entirely.
+// Misses are passed to next and stored. Hits and misses are logged, counted
+// in m and reported in the X-Cache header.
+func cacheMiddleware(c *ResponseCache, m *Metrics, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// Ranged and echoHeaders responses depend on headers the cache
+		// doesn't keep, X-Chaos faults apply to a single request, a cached
+		// truncate or shortBody response would have the right Content-Length,
+		// and decompression bombs are already encoded.
+		q := req.URL.Query()
+		if req.Method != http.MethodGet || req.Header.Get("Range") != "" || req.Header.Get(chaosHeader) != "" ||
+			q.Get("echoHeaders") == "true" || q.Get("truncate") == "true" || q.Get("shortBody") == "true" ||
+			q.Get("decompressBomb") == "true" {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		// The path can pin the city, and Prefer and Accept-Language change
+		// the response too, so they are all part of the key.
+		key := req.URL.Path + "?" + req.URL.RawQuery
+		if prefer := req.Header.Values("Prefer"); len(prefer) > 0 {
+			key += "\x00" + strings.Join(prefer, ",")
+		}
+		if lang := req.Header.Get("Accept-Language"); lang != "" {
+			key += "\x00lang=" + negotiateLanguage(lang)
+		}
+		if entry, ok := c.get(key); ok {
+			m.cacheHits.Add(1)
+			slog.Info("Cache hit", "query", key)
+			for name, values := range entry.header {
+				w.Header()[name] = slices.Clone(values)
+			}
+			w.Header().Set("X-Cache", "HIT")
+			w.Header().Set("Content-Length", strconv.Itoa(len(entry.body)))
+			w.WriteHeader(entry.status)
+			w.Write(entry.body)
+			return
+		}
+
+		m.cacheMisses.Add(1)
+		slog.Info("Cache miss", "query", key)
+		w.Header().Set("X-Cache", "MISS")
+		rec := &bodyRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, req)
+		// Only complete successful responses are stored: a client that went
+		// away mid-request may have left nothing written, or half a body.
+		if rec.status < 200 || rec.status > 299 || req.Context().Err() != nil {
+			return
+		}
+		c.put(key, cachedResponse{
+			status: rec.status,
+			header: cacheableHeader(w.Header()),
+			body:   bytes.Clone(rec.body.Bytes()),
+		})
+	})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestCacheMiddleware tests hits, misses and expiry of the response cache.
+func TestCacheMiddleware(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := &ResponseCache{TTL: time.Minute, Now: func() time.Time { return now }}
+	m := &Metrics{}
+	calls := 0
+	handler := cacheMiddleware(c, m, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"n":1}`))
+	}))
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		return rr
+	}
+
+	first := get("/weather?seed=1")
+	second := get("/weather?seed=1")
+	if calls != 1 {
+		t.Errorf("Handler called %d times for identical queries, want 1", calls)
+	}
+	if first.Header().Get("X-Cache") != "MISS" || second.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("Unexpected X-Cache headers: %q then %q", first.Header().Get("X-Cache"), second.Header().Get("X-Cache"))
+	}
+	if second.Code != http.StatusAccepted || second.Body.String() != `{"n":1}` || second.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Cached response differs: %d %q %q", second.Code, second.Body.String(), second.Header().Get("Content-Type"))
+	}
+
+	get("/weather?seed=2")
+	if calls != 2 {
+		t.Errorf("Handler called %d times after a different query, want 2", calls)
+	}
+
+	now = now.Add(time.Minute)
+	get("/weather?seed=1")
+	if calls != 3 {
+		t.Errorf("Handler called %d times after the TTL expired, want 3", calls)
+	}
+	if m.cacheHits.Load() != 1 || m.cacheMisses.Load() != 3 {
+		t.Errorf("Unexpected cache metrics: %d hits, %d misses", m.cacheHits.Load(), m.cacheMisses.Load())
+	}
+}
+
+// TestCacheSkipsIncompleteResponses tests that responses abandoned before
+// the status was written, and error responses, are not stored.
+func TestCacheSkipsIncompleteResponses(t *testing.T) {
+	c := &ResponseCache{TTL: time.Minute}
+	calls := 0
+	handler := cacheMiddleware(c, &Metrics{}, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			// The client went away during the delay, so nothing is written.
+		case 2:
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+
+	for i, want := range []int{http.StatusOK, http.StatusInternalServerError, http.StatusOK, http.StatusOK} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/weather?seed=1", nil))
+		if rr.Code != want {
+			t.Errorf("Request %d returned wrong status code: got %v want %v", i+1, rr.Code, want)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("Handler called %d times, want 3", calls)
+	}
+}
+
+// TestCacheReplaysHeaders tests that a hit carries the headers of the miss it
+// was stored from, except those set per request.
+func TestCacheReplaysHeaders(t *testing.T) {
+	svc := newTestService(1, nil, &FixedStatusChooser{Status: http.StatusOK})
+	svc.Cache = &ResponseCache{TTL: time.Minute}
+	handler := svc.Handler()
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/weather?size=10&seed=3", nil)
+		req.Header.Set("Accept-Language", "fr")
+		req.Header.Set("Prefer", "return=minimal")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	miss, hit := get(), get()
+	if hit.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("Second request was not a cache hit: X-Cache %q", hit.Header().Get("X-Cache"))
+	}
+	if hit.Body.String() != miss.Body.String() {
+		t.Errorf("Cached body differs:\n%s\n%s", hit.Body, miss.Body)
+	}
+	for name := range miss.Header() {
+		if slices.Contains([]string{"X-Cache", "X-Request-Id"}, name) {
+			continue
+		}
+		if got, want := hit.Header().Values(name), miss.Header().Values(name); !slices.Equal(got, want) {
+			t.Errorf("Hit has %s %q, want %q", name, got, want)
+		}
+	}
+	if len(hit.Header()) != len(miss.Header()) {
+		t.Errorf("Hit has headers %v, want %v", hit.Header(), miss.Header())
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// chaosHeader is the request header that combines fault behaviours for a
+// single /weather request.
+const chaosHeader = "X-Chaos"
+
+// applyChaosHeader applies the faults in an X-Chaos header value, such as
+// "status=500;delay=2s;truncate", to opts. Directives are separated by
+// semicolons, names are case-insensitive and whitespace is ignored:
+//
+//	status=503        respond with this status code
+//	delay=2s          delay exactly this long (a Go duration or milliseconds)
+//	bodydelay=500ms   pause this long before each chunk of the body
+//	truncate          cut the body off halfway and close the connection
+//	shortbody         advertise a longer Content-Length than is sent
+//	badjson           write malformed JSON
+//	softerror         respond 200 with an error-shaped body
+//	omitfields        drop required fields from some readings
+//	anomalyrate=0.1   inject out-of-band temperatures at this rate
+//	duprate=0.1       repeat earlier readings at this rate
+//
+// Unlike Prefer, the header is meant for test clients, so unknown directives,
+// invalid values and options already set by the query or body are errors,
+// all collected into one *paramError.
+func applyChaosHeader(value string, opts *WeatherOptions) error {
+	var problems []error
+	for _, directive := range strings.Split(value, ";") {
+		name, arg, hasArg := strings.Cut(strings.TrimSpace(directive), "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		arg = strings.TrimSpace(arg)
+		if name == "" {
+			continue
+		}
+
+		var set bool // Whether the option was already set
+		switch name {
+		case "status":
+			status, err := strconv.Atoi(arg)
+			if err != nil {
+				problems = append(problems, errorOf(ErrInvalidParam, "invalid X-Chaos status %q, expected an integer", arg))
+				continue
+			}
+			set = opts.Status != nil
+			opts.Status = &status
+		case "delay":
+			ms, err := parseLatency(arg)
+			if err != nil {
+				problems = append(problems, errorOf(ErrInvalidParam, "invalid X-Chaos delay %q, expected a duration or milliseconds", arg))
+				continue
+			}
+			set = opts.DelayMs != nil || opts.MinDelay != nil || opts.MaxDelay != nil
+			opts.DelayMs = &ms
+		case "bodydelay":
+			if arg == "" {
+				problems = append(problems, errorOf(ErrInvalidParam, "X-Chaos bodydelay needs a duration or milliseconds"))
+				continue
+			}
+			set = opts.BodyDelay != ""
+			opts.BodyDelay = arg
+		case "anomalyrate", "duprate":
+			rate, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				problems = append(problems, errorOf(ErrInvalidParam, "invalid X-Chaos %s %q, expected a number", name, arg))
+				continue
+			}
+			target := &opts.AnomalyRate
+			if name == "duprate" {
+				target = &opts.DupRate
+			}
+			set = *target != nil
+			*target = &rate
+		case "truncate", "shortbody", "badjson", "softerror", "omitfields":
+			if hasArg {
+				problems = append(problems, errorOf(ErrInvalidParam, "X-Chaos %s takes no value", name))
+				continue
+			}
+			flag := map[string]*bool{
+				"truncate":   &opts.Truncate,
+				"shortbody":  &opts.ShortBody,
+				"badjson":    &opts.BadJSON,
+				"softerror":  &opts.SoftError,
+				"omitfields": &opts.OmitFields,
+			}[name]
+			set = *flag
+			*flag = true
+		default:
+			problems = append(problems, errorOf(ErrInvalidParam, "unknown X-Chaos directive %q", name))
+			continue
+		}
+		if set {
+			problems = append(problems, errorOf(ErrConflictingParams, "X-Chaos %s conflicts with the same option in the request", name))
+		}
+	}
+	if len(problems) > 0 {
+		return &paramError{problems: problems}
+	}
+	return nil
+}
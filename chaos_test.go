@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestWeatherHandlerChaosHeader tests that X-Chaos combines faults and
+// rejects invalid or conflicting directives.
+func TestWeatherHandlerChaosHeader(t *testing.T) {
+	testCases := []struct {
+		name   string
+		chaos  string
+		target string
+		status int
+		delay  time.Duration
+	}{
+		{"StatusAndDelay", "status=500; delay=2s", "/weather", http.StatusInternalServerError, 2 * time.Second},
+		{"PlainMilliseconds", "DELAY=250;status=418", "/weather", http.StatusTeapot, 250 * time.Millisecond},
+		{"SoftError", "softerror;delay=0", "/weather", http.StatusOK, 0},
+		{"WinsOverPrefer", "status=503;delay=0", "/weather", http.StatusServiceUnavailable, 0},
+		{"UnknownDirective", "status=500;explode", "/weather", http.StatusBadRequest, 0},
+		{"InvalidValue", "delay=soon", "/weather", http.StatusBadRequest, 0},
+		{"FlagWithValue", "truncate=yes", "/weather", http.StatusBadRequest, 0},
+		{"ConflictsWithQuery", "status=500", "/weather?status=200", http.StatusBadRequest, 0},
+		{"InvalidCombination", "truncate;badjson", "/weather", http.StatusBadRequest, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recording := &recordingSleeper{}
+			req := httptest.NewRequest("GET", tc.target, nil)
+			req.Header.Set("X-Chaos", tc.chaos)
+			req.Header.Set("Prefer", "status=200")
+			rr := httptest.NewRecorder()
+			weatherHandler(recording, chooser, rr, req)
+
+			if rr.Code != tc.status {
+				t.Errorf("Handler returned wrong status code: got %v want %v (%s)", rr.Code, tc.status, rr.Body)
+			}
+			if recording.total != tc.delay {
+				t.Errorf("Handler slept for wrong duration: got %v want %v", recording.total, tc.delay)
+			}
+		})
+	}
+}
+
+// TestApplyChaosHeaderProblems tests that every problem in the header is
+// reported with its kind.
+func TestApplyChaosHeaderProblems(t *testing.T) {
+	status := 200
+	opts := WeatherOptions{Status: &status}
+	err := applyChaosHeader("status=500;anomalyrate=lots;boom", &opts)
+	if !errors.Is(err, ErrConflictingParams) || !errors.Is(err, ErrInvalidParam) {
+		t.Fatalf("Got error %v, want conflicting and invalid parameters", err)
+	}
+	var pe *paramError
+	if !errors.As(err, &pe) || len(pe.problems) != 3 {
+		t.Errorf("Got error %v, want three problems", err)
+	}
+}
+
+// TestWeatherHandlerTruncate tests that truncate announces the whole body in
+// Content-Length but sends only part of it.
+func TestWeatherHandlerTruncate(t *testing.T) {
+	req := httptest.NewRequest("GET", "/weather?truncate=true&delayMs=0", nil)
+	rr := httptest.NewRecorder()
+	weatherHandler(sleeper, chooser, rr, req)
+
+	length, err := strconv.Atoi(rr.Header().Get("Content-Length"))
+	if err != nil {
+		t.Fatalf("Invalid Content-Length %q", rr.Header().Get("Content-Length"))
+	}
+	if got := rr.Body.Len(); got == 0 || got >= length {
+		t.Errorf("Handler wrote %d of %d bytes, want a truncated body", got, length)
+	}
+	if json.Valid(rr.Body.Bytes()) {
+		t.Errorf("Truncated body is valid JSON: %s", rr.Body)
+	}
+}
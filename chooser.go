@@ -0,0 +1,91 @@
+package main
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// statusClasses are the status classes random statuses are weighted by, in
+// the order of StatusWeights.
+var statusClasses = []string{"2xx", "4xx", "5xx"}
+
+// defaultStatusWeights picks 70% 2xx, 15% 4xx and 15% 5xx statuses.
+var defaultStatusWeights = []int{70, 15, 15}
+
+// parseStatusWeights parses a WEATHER_STATUS_WEIGHTS value such as
+// "2xx:80,4xx:10,5xx:10" into weights for statusClasses. Unlisted classes get
+// a weight of 0, so "2xx:1" only picks successes.
+func parseStatusWeights(value string) ([]int, error) {
+	weights := make([]int, len(statusClasses))
+	for _, pair := range strings.Split(value, ",") {
+		name, weightStr, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, errorOf(ErrBadConfig, "invalid status weight %q, expected class:weight", pair)
+		}
+		index := -1
+		for i, class := range statusClasses {
+			if strings.EqualFold(class, strings.TrimSpace(name)) {
+				index = i
+			}
+		}
+		if index < 0 {
+			return nil, errorOf(ErrBadConfig, "unknown status class %q, expected 2xx, 4xx or 5xx", name)
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(weightStr))
+		if err != nil || weight < 0 {
+			return nil, errorOf(ErrBadConfig, "invalid weight %q for status class %q", weightStr, name)
+		}
+		weights[index] = weight
+	}
+
+	total := 0
+	for _, weight := range weights {
+		total += weight
+	}
+	if total == 0 {
+		return nil, errorOf(ErrBadConfig, "status weights must not all be zero")
+	}
+	return weights, nil
+}
+
+// StatusChooser interface defines the contract for choosing a response status code.
+type StatusChooser interface {
+	ChooseStatus(r *rand.Rand) int
+}
+
+// requestedStatusChooser is implemented by status choosers that simulate
+// server state, such as an outage burst or an open circuit, which takes
+// precedence over a status the client asked for with status or Prefer.
+type requestedStatusChooser interface {
+	ChooseRequestedStatus(r *rand.Rand, status int) int
+}
+
+// chooseRequestedStatus returns the status to send when the client asked for
+// status: status itself, unless c simulates server state that overrides it.
+func chooseRequestedStatus(c StatusChooser, r *rand.Rand, status int) int {
+	if rc, ok := c.(requestedStatusChooser); ok {
+		return rc.ChooseRequestedStatus(r, status)
+	}
+	return status
+}
+
+// RandomStatusChooser implements StatusChooser using getResponseStatusCode.
+type RandomStatusChooser struct{}
+
+// ChooseStatus randomly selects a 2xx, 4xx, or 5xx status code from r, with
+// the configured status weights.
+func (c *RandomStatusChooser) ChooseStatus(r *rand.Rand) int {
+	return getResponseStatusCode(r)
+}
+
+// FixedStatusChooser implements StatusChooser by always returning Status.
+// This is useful for tests that need a deterministic response.
+type FixedStatusChooser struct {
+	Status int
+}
+
+// ChooseStatus returns the fixed status code.
+func (c *FixedStatusChooser) ChooseStatus(r *rand.Rand) int {
+	return c.Status
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// circuitState is the state of a simulated circuit breaker.
+type circuitState int
+
+const (
+	// circuitClosed passes statuses through and counts consecutive errors.
+	circuitClosed circuitState = iota
+	// circuitOpen rejects every request with 503 until the cooldown expires.
+	circuitOpen
+	// circuitHalfOpen lets requests through; the next outcome closes or reopens the circuit.
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitClosed:
+		return "closed"
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker is the state machine behind the circuit-breaker simulation.
+// After Threshold consecutive 5xx outcomes it opens for Cooldown, then
+// half-opens: a successful outcome closes it again, an error reopens it.
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+	// Now returns the current time; tests can replace it. Defaults to time.Now.
+	Now func() time.Time
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+}
+
+func (cb *CircuitBreaker) now() time.Time {
+	if cb.Now != nil {
+		return cb.Now()
+	}
+	return time.Now()
+}
+
+// State returns the current state, moving from open to half-open once the
+// cooldown has expired.
+func (cb *CircuitBreaker) State() circuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.refresh()
+	return cb.state
+}
+
+// refresh applies the open to half-open transition. cb.mu must be held.
+func (cb *CircuitBreaker) refresh() {
+	if cb.state == circuitOpen && cb.now().Sub(cb.openedAt) >= cb.Cooldown {
+		cb.transition(circuitHalfOpen)
+	}
+}
+
+// transition changes state and logs it. cb.mu must be held.
+func (cb *CircuitBreaker) transition(to circuitState) {
+	slog.Info("Circuit breaker state changed", "from", cb.state, "to", to)
+	cb.state = to
+}
+
+// Allow reports whether a request may proceed to the real status choice.
+func (cb *CircuitBreaker) Allow() bool {
+	return cb.State() != circuitOpen
+}
+
+// Record updates the state machine with the outcome of an allowed request.
+func (cb *CircuitBreaker) Record(status int) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.refresh()
+
+	if status < 500 {
+		cb.failures = 0
+		if cb.state == circuitHalfOpen {
+			cb.transition(circuitClosed)
+		}
+		return
+	}
+
+	cb.failures++
+	if cb.state == circuitHalfOpen || cb.failures >= cb.Threshold {
+		cb.failures = 0
+		cb.openedAt = cb.now()
+		cb.transition(circuitOpen)
+	}
+}
+
+// Reset closes the circuit and forgets the consecutive failures.
+func (cb *CircuitBreaker) Reset() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state != circuitClosed {
+		cb.transition(circuitClosed)
+	}
+	cb.failures = 0
+}
+
+// CircuitBreakerChooser implements StatusChooser by wrapping another chooser
+// with a CircuitBreaker. While the circuit is open it always returns 503.
+type CircuitBreakerChooser struct {
+	Inner   StatusChooser
+	Breaker *CircuitBreaker
+}
+
+// ChooseStatus returns 503 while the circuit is open, and otherwise the inner
+// chooser's status, recording it in the breaker.
+func (c *CircuitBreakerChooser) ChooseStatus(r *rand.Rand) int {
+	if !c.Breaker.Allow() {
+		return http.StatusServiceUnavailable
+	}
+	status := c.Inner.ChooseStatus(r)
+	c.Breaker.Record(status)
+	return status
+}
+
+// ChooseRequestedStatus returns 503 while the circuit is open, even when the
+// client asked for another status. Otherwise the requested status is recorded
+// like a chosen one, so forced errors can open the circuit.
+func (c *CircuitBreakerChooser) ChooseRequestedStatus(r *rand.Rand, status int) int {
+	if !c.Breaker.Allow() {
+		return http.StatusServiceUnavailable
+	}
+	status = chooseRequestedStatus(c.Inner, r, status)
+	c.Breaker.Record(status)
+	return status
+}
+
+// Reset resets the breaker and the inner chooser.
+func (c *CircuitBreakerChooser) Reset() {
+	c.Breaker.Reset()
+	resetChooser(c.Inner)
+}
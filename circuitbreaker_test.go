@@ -0,0 +1,101 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// sequenceChooser implements StatusChooser by returning statuses in order.
+type sequenceChooser struct {
+	statuses []int
+}
+
+func (c *sequenceChooser) ChooseStatus(_ *rand.Rand) int {
+	status := c.statuses[0]
+	c.statuses = c.statuses[1:]
+	return status
+}
+
+// TestCircuitBreakerStateMachine tests the closed, open and half-open transitions.
+func TestCircuitBreakerStateMachine(t *testing.T) {
+	now := time.Unix(0, 0)
+	breaker := &CircuitBreaker{Threshold: 3, Cooldown: 10 * time.Second, Now: func() time.Time { return now }}
+	inner := &sequenceChooser{statuses: []int{500, 502, 200, 500, 503, 504, 500, 200}}
+	c := &CircuitBreakerChooser{Inner: inner, Breaker: breaker}
+
+	steps := []struct {
+		advance time.Duration
+		status  int
+		state   circuitState
+	}{
+		{0, 500, circuitClosed},
+		{0, 502, circuitClosed},
+		{0, 200, circuitClosed}, // A success resets the consecutive error count
+		{0, 500, circuitClosed},
+		{0, 503, circuitClosed},
+		{0, 504, circuitOpen}, // Third consecutive error opens the circuit
+		{5 * time.Second, http.StatusServiceUnavailable, circuitOpen},
+		{5 * time.Second, 500, circuitOpen}, // Half-open trial fails and reopens
+		{10 * time.Second, 200, circuitClosed},
+	}
+
+	for i, step := range steps {
+		now = now.Add(step.advance)
+		if status := c.ChooseStatus(nil); status != step.status {
+			t.Errorf("Step %d returned wrong status: got %d want %d", i, status, step.status)
+		}
+		if state := breaker.State(); state != step.state {
+			t.Errorf("Step %d left breaker in wrong state: got %v want %v", i, state, step.state)
+		}
+	}
+}
+
+// TestRequestedStatusPrecedence tests that statuses requested with status or
+// Prefer are recorded by the circuit breaker and overridden by an open circuit
+// or an outage burst, even behind WEATHER_FAIL_EVERY.
+func TestRequestedStatusPrecedence(t *testing.T) {
+	breaker := &CircuitBreaker{Threshold: 2, Cooldown: time.Hour}
+	outage := &OutageSimulator{}
+	svc := newTestService(1, nil, nil)
+	svc.Chooser = &CircuitBreakerChooser{
+		Inner:   &FailEveryChooser{Inner: &BurstChooser{Inner: &FixedStatusChooser{Status: http.StatusOK}, Outage: outage}, Every: 1000},
+		Breaker: breaker,
+	}
+	handler := svc.Handler()
+	status := func(query, prefer string) int {
+		req := httptest.NewRequest("GET", "/weather?"+query, nil)
+		if prefer != "" {
+			req.Header.Set("Prefer", prefer)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// An outage overrides the requested status.
+	outage.Every, outage.Duration = time.Hour, time.Hour
+	if got := status("status=200", ""); got != http.StatusServiceUnavailable {
+		t.Errorf("Requested 200 during an outage: got %d want %d", got, http.StatusServiceUnavailable)
+	}
+	outage.Every, outage.Duration = 0, 0
+	breaker.Reset()
+
+	// Forced errors open the circuit, which then overrides requested statuses.
+	for i := range 2 {
+		if got := status("status=500", ""); got != http.StatusInternalServerError {
+			t.Errorf("Forced error %d: got %d want %d", i+1, got, http.StatusInternalServerError)
+		}
+	}
+	if breaker.State() != circuitOpen {
+		t.Fatalf("Forced errors left the breaker %v, want open", breaker.State())
+	}
+	if got := status("status=200", ""); got != http.StatusServiceUnavailable {
+		t.Errorf("Requested 200 with the circuit open: got %d want %d", got, http.StatusServiceUnavailable)
+	}
+	if got := status("", "status=200"); got != http.StatusServiceUnavailable {
+		t.Errorf("Preferred 200 with the circuit open: got %d want %d", got, http.StatusServiceUnavailable)
+	}
+}
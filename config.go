@@ -0,0 +1,504 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config holds the server settings, loaded once from the environment at
+// startup and passed to the components that need them. The log level is read
+// separately by setupLogging, before anything else is logged.
+type Config struct {
+	Port   int
+	Author string
+	// TLSCert and TLSKey are the key pair to serve HTTPS with. TLSSelfSigned
+	// serves HTTPS with a generated certificate instead. Plain HTTP otherwise.
+	TLSCert       string
+	TLSKey        string
+	TLSSelfSigned bool
+	// H2C also serves HTTP/2 over cleartext connections.
+	H2C bool
+	// Seed seeds the shared random source; nil seeds it from the clock.
+	Seed *int64
+	// FixedDelay replaces the random /weather delay when non-negative.
+	FixedDelay time.Duration
+	// SizeDelayBase and SizeDelayPerItem, when SizeDelayPerItem is positive,
+	// replace the random /weather delay with base + size*perItem.
+	SizeDelayBase    time.Duration
+	SizeDelayPerItem time.Duration
+	// ErrorDelay, when positive, replaces the delay of 5xx /weather responses.
+	ErrorDelay time.Duration
+	// ConditionWeights weights each entry in conditions; nil is uniform.
+	ConditionWeights []int
+	// StatusWeights weights the 2xx, 4xx and 5xx classes of random statuses.
+	StatusWeights []int
+	// DefaultMaxDelayMs bounds the random /weather delay without maxDelay;
+	// MaxDelayMs is the largest delay a client may request.
+	DefaultMaxDelayMs int
+	MaxDelayMs        int
+	// DefaultSize is the number of readings without size; MinSize and
+	// MaxSize bound the size a client may request.
+	DefaultSize int
+	MinSize     int
+	MaxSize     int
+	// Sleeper names the Sleeper strategy; see newSleeper.
+	Sleeper     string
+	SleepJitter float64
+
+	RequestLogSize int
+	HistorySize    int
+	HistoryFile    string
+	AccessLog      string
+	// TracesEndpoint is the OTLP/HTTP URL spans are exported to; empty
+	// disables tracing. ServiceName defaults to go-weather.
+	TracesEndpoint string
+	ServiceName    string
+
+	ShutdownTimeout time.Duration
+	FlushTimeout    time.Duration
+	StartupDelay    time.Duration
+	// Maintenance starts the server in maintenance mode.
+	Maintenance           bool
+	MaintenanceRetryAfter time.Duration
+	WarmupDuration        time.Duration
+	WarmupRequests        int
+	WarmupDelay           time.Duration
+
+	BurstEvery       time.Duration
+	BurstProbability float64
+	BurstDuration    time.Duration
+	// FailEvery forces a 500 on every FailEvery-th /weather request; 0
+	// disables it.
+	FailEvery        int
+	CircuitBreaker   bool
+	CircuitThreshold int
+	CircuitCooldown  time.Duration
+	CityOutages      []CityOutage
+	// SeasonWeights are the condition weights of each season option.
+	SeasonWeights map[string][]int
+
+	CacheTTL           time.Duration
+	IdempotencyTTL     time.Duration
+	DropConnectionRate float64
+	MaxConcurrency     int
+	MaxBodyBytes       int64
+	ShortBodyBytes     int
+	GzipLevel          int
+
+	// Deprecation adds Deprecation and Sunset headers to /weather responses.
+	Deprecation Deprecation
+
+	InjectedHeaders []injectedHeader
+	// StationsPerCity is how many sensor stations each city has.
+	StationsPerCity int
+	// DecompressBomb allows the decompressBomb /weather option.
+	DecompressBomb bool
+	// GrowthBytes, in debug mode, grows each /weather response by this many
+	// bytes over the previous one.
+	GrowthBytes    int
+	ServerHeader   string
+	Attribution    string
+	ClockSkew      time.Duration
+	SkewTimestamps bool
+
+	BasePath       string
+	HealthAtRoot   bool
+	DisabledRoutes []string
+	// Debug enables debug-only features such as injected headers and /debug/reset.
+	Debug bool
+}
+
+// LoadConfig reads the Config from WEATHER_* environment variables. Invalid
+// scalar values are logged and replaced by their defaults, as before; values
+// that can't be parsed at all, such as a malformed WEATHER_CITY_OUTAGES, and
+// settings rejected by Validate are returned as an ErrBadConfig error.
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		Port:                  envInt("WEATHER_PORT", 8080),
+		TLSCert:               os.Getenv("WEATHER_TLS_CERT"),
+		TLSKey:                os.Getenv("WEATHER_TLS_KEY"),
+		TLSSelfSigned:         envBool("WEATHER_TLS_SELF_SIGNED", false),
+		H2C:                   envBool("WEATHER_H2C", false),
+		Sleeper:               os.Getenv("WEATHER_SLEEPER"),
+		SleepJitter:           envFloat("WEATHER_SLEEP_JITTER", 0.2),
+		Author:                os.Getenv("AUTHOR"),
+		FixedDelay:            -1,
+		StatusWeights:         defaultStatusWeights,
+		DefaultMaxDelayMs:     envInt("WEATHER_DEFAULT_MAX_DELAY_MS", defaultMaxDelayMs),
+		MaxDelayMs:            envInt("WEATHER_MAX_DELAY_MS", maxDelayMs),
+		DefaultSize:           envInt("WEATHER_DEFAULT_SIZE", defaultSize),
+		MinSize:               envInt("WEATHER_MIN_SIZE", minSize),
+		MaxSize:               envInt("WEATHER_MAX_SIZE", maxSize),
+		RequestLogSize:        envInt("WEATHER_REQUEST_LOG_SIZE", 100),
+		HistorySize:           envInt("WEATHER_HISTORY_SIZE", 1000),
+		HistoryFile:           os.Getenv("WEATHER_HISTORY_FILE"),
+		AccessLog:             os.Getenv("WEATHER_ACCESS_LOG"),
+		TracesEndpoint:        tracesEndpoint(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"), os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")),
+		ServiceName:           os.Getenv("OTEL_SERVICE_NAME"),
+		ShutdownTimeout:       envDuration("WEATHER_SHUTDOWN_TIMEOUT", 10*time.Second),
+		FlushTimeout:          envDuration("WEATHER_FLUSH_TIMEOUT", 5*time.Second),
+		StartupDelay:          envDuration("WEATHER_STARTUP_DELAY", 0),
+		Maintenance:           envBool("WEATHER_MAINTENANCE", false),
+		MaintenanceRetryAfter: envDuration("WEATHER_MAINTENANCE_RETRY_AFTER", time.Minute),
+		WarmupDuration:        envDuration("WEATHER_WARMUP_DURATION", 0),
+		WarmupRequests:        envInt("WEATHER_WARMUP_REQUESTS", 0),
+		WarmupDelay:           envDuration("WEATHER_WARMUP_DELAY", 2*time.Second),
+		BurstEvery:            envDuration("WEATHER_BURST_EVERY", 0),
+		BurstProbability:      envFloat("WEATHER_BURST_PROBABILITY", 0),
+		BurstDuration:         envDuration("WEATHER_BURST_DURATION", 5*time.Second),
+		FailEvery:             envInt("WEATHER_FAIL_EVERY", 0),
+		DecompressBomb:        envBool("WEATHER_DECOMPRESS_BOMB", false),
+		StationsPerCity:       envInt("WEATHER_STATIONS_PER_CITY", 1),
+		CircuitBreaker:        envBool("WEATHER_CIRCUIT_BREAKER", false),
+		CircuitThreshold:      envInt("WEATHER_CIRCUIT_THRESHOLD", 5),
+		CircuitCooldown:       envDuration("WEATHER_CIRCUIT_COOLDOWN", 10*time.Second),
+		CacheTTL:              envDuration("WEATHER_CACHE_TTL", 0),
+		DropConnectionRate:    envFloat("WEATHER_DROP_CONNECTION_RATE", 0),
+		IdempotencyTTL:        envDuration("WEATHER_IDEMPOTENCY_TTL", 24*time.Hour),
+		MaxConcurrency:        envInt("WEATHER_MAX_CONCURRENCY", 0),
+		MaxBodyBytes:          int64(envInt("WEATHER_MAX_BODY_BYTES", 1<<20)),
+		ShortBodyBytes:        envInt("WEATHER_SHORT_BODY_BYTES", defaultShortBodyBytes),
+		GzipLevel:             envInt("WEATHER_GZIP_LEVEL", 0),
+		SkewTimestamps:        envBool("WEATHER_CLOCK_SKEW_TIMESTAMPS", false),
+		BasePath:              os.Getenv("WEATHER_BASE_PATH"),
+		HealthAtRoot:          envBool("WEATHER_HEALTH_AT_ROOT", false),
+		DisabledRoutes:        parseRouteList(os.Getenv("WEATHER_DISABLED_ROUTES")),
+		Debug:                 envBool("WEATHER_DEBUG", false),
+	}
+	var problems []error
+
+	if value := os.Getenv("WEATHER_SEED"); value != "" {
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			problems = append(problems, errorOf(ErrBadConfig, "invalid WEATHER_SEED %q, expected an integer", value))
+		}
+		cfg.Seed = &seed
+	}
+
+	if ms := envInt("WEATHER_FIXED_DELAY_MS", -1); ms >= 0 {
+		cfg.FixedDelay = time.Duration(ms) * time.Millisecond
+	}
+	cfg.SizeDelayBase = time.Duration(envInt("WEATHER_DELAY_BASE_MS", 0)) * time.Millisecond
+	cfg.SizeDelayPerItem = time.Duration(envFloat("WEATHER_DELAY_PER_ITEM_MS", 0) * float64(time.Millisecond))
+	cfg.ErrorDelay = time.Duration(envInt("WEATHER_ERROR_DELAY_MS", 0)) * time.Millisecond
+
+	// Invalid weights keep the uniform distribution rather than failing startup.
+	if value := os.Getenv("WEATHER_CONDITION_WEIGHTS"); value != "" {
+		weights, err := parseConditionWeights(value)
+		if err != nil {
+			slog.Warn("Invalid WEATHER_CONDITION_WEIGHTS, using uniform weights", "error", err)
+		}
+		cfg.ConditionWeights = weights
+	}
+
+	if value := os.Getenv("WEATHER_STATUS_WEIGHTS"); value != "" {
+		weights, err := parseStatusWeights(value)
+		if err != nil {
+			problems = append(problems, errorOf(ErrBadConfig, "invalid WEATHER_STATUS_WEIGHTS: %v", err))
+		}
+		cfg.StatusWeights = weights
+	}
+
+	if value := os.Getenv("WEATHER_CITY_OUTAGES"); value != "" {
+		outages, err := parseCityOutages(value)
+		if err != nil {
+			problems = append(problems, errorOf(ErrBadConfig, "invalid WEATHER_CITY_OUTAGES: %v", err))
+		}
+		cfg.CityOutages = outages
+	}
+
+	seasonWeights, err := parseSeasonWeights(os.Getenv("WEATHER_SEASON_WEIGHTS"))
+	if err != nil {
+		problems = append(problems, errorOf(ErrBadConfig, "invalid WEATHER_SEASON_WEIGHTS: %v", err))
+	}
+	cfg.SeasonWeights = seasonWeights
+
+	cfg.Deprecation.Deprecated = envBool("WEATHER_DEPRECATED", false)
+	if value := os.Getenv("WEATHER_SUNSET"); value != "" {
+		sunset, err := parseSunset(value)
+		if err != nil {
+			problems = append(problems, err)
+		}
+		cfg.Deprecation.Sunset = sunset
+	}
+
+	// Growing responses are pathological, so they need WEATHER_DEBUG too.
+	if growth := envInt("WEATHER_GROWTH_BYTES", 0); growth != 0 {
+		if !cfg.Debug {
+			slog.Warn("Ignoring WEATHER_GROWTH_BYTES because WEATHER_DEBUG is not enabled")
+		} else {
+			cfg.GrowthBytes = growth
+		}
+	}
+
+	// Injected headers are a debugging aid, so they need WEATHER_DEBUG too.
+	if value := os.Getenv("WEATHER_INJECT_HEADERS"); value != "" {
+		if !cfg.Debug {
+			slog.Warn("Ignoring WEATHER_INJECT_HEADERS because WEATHER_DEBUG is not enabled")
+		} else if headers, err := parseInjectedHeaders(value); err != nil {
+			problems = append(problems, errorOf(ErrBadConfig, "invalid WEATHER_INJECT_HEADERS: %v", err))
+		} else {
+			cfg.InjectedHeaders = headers
+		}
+	}
+
+	// Identify the service in a Server header unless it is customized or set empty.
+	serverHeader, ok := os.LookupEnv("WEATHER_SERVER_HEADER")
+	if !ok {
+		serverHeader = "go-weather/" + buildVersion()
+	}
+	cfg.ServerHeader = serverHeader
+	attribution, ok := os.LookupEnv("WEATHER_ATTRIBUTION")
+	if !ok {
+		attribution = defaultAttribution
+	}
+	cfg.Attribution = attribution
+
+	// envDuration rejects negative values, which are valid skews.
+	if value := os.Getenv("WEATHER_CLOCK_SKEW"); value != "" {
+		skew, err := time.ParseDuration(value)
+		if err != nil {
+			problems = append(problems, errorOf(ErrBadConfig, "invalid WEATHER_CLOCK_SKEW: %v", err))
+		}
+		cfg.ClockSkew = skew
+	}
+
+	if err := cfg.Validate(); err != nil {
+		problems = append(problems, err)
+	}
+	return cfg, errors.Join(problems...)
+}
+
+// Validate checks that the settings are usable together, returning every
+// problem found as an ErrBadConfig error.
+func (cfg Config) Validate() error {
+	var problems []error
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_PORT %d is out of range 1 to 65535", cfg.Port))
+	}
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_TLS_CERT and WEATHER_TLS_KEY must be set together"))
+	}
+	if cfg.TLSCert != "" && cfg.TLSSelfSigned {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_TLS_SELF_SIGNED conflicts with WEATHER_TLS_CERT"))
+	}
+	if cfg.FixedDelay > maxDelayMs*time.Millisecond {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_FIXED_DELAY_MS must be at most %d", maxDelayMs))
+	}
+	if cfg.MaxDelayMs < 0 || cfg.MaxDelayMs > maxDelayMs {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_MAX_DELAY_MS must be 0 to %d", maxDelayMs))
+	}
+	if cfg.DefaultMaxDelayMs < 0 || cfg.DefaultMaxDelayMs > cfg.MaxDelayMs {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_DEFAULT_MAX_DELAY_MS must be 0 to WEATHER_MAX_DELAY_MS (%d)", cfg.MaxDelayMs))
+	}
+	if cfg.MinSize < 1 || cfg.MinSize > cfg.MaxSize || cfg.MaxSize > sizeCeiling {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_MIN_SIZE and WEATHER_MAX_SIZE must satisfy 1 <= min <= max <= %d", sizeCeiling))
+	} else if cfg.DefaultSize < cfg.MinSize || cfg.DefaultSize > cfg.MaxSize {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_DEFAULT_SIZE must be %d to %d", cfg.MinSize, cfg.MaxSize))
+	}
+	if cfg.ErrorDelay < 0 || cfg.ErrorDelay > maxDelayMs*time.Millisecond {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_ERROR_DELAY_MS must be 0 to %d", maxDelayMs))
+	}
+	if _, err := newSleeper(cfg.Sleeper, cfg.SleepJitter); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.SizeDelayBase < 0 || cfg.SizeDelayPerItem < 0 {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_DELAY_BASE_MS and WEATHER_DELAY_PER_ITEM_MS must not be negative"))
+	}
+	if cfg.RequestLogSize < 1 || cfg.HistorySize < 1 {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_REQUEST_LOG_SIZE and WEATHER_HISTORY_SIZE must be at least 1"))
+	}
+	if cfg.BurstProbability < 0 || cfg.BurstProbability > 1 {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_BURST_PROBABILITY %v is out of range 0 to 1", cfg.BurstProbability))
+	}
+	if cfg.DropConnectionRate < 0 || cfg.DropConnectionRate > 1 {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_DROP_CONNECTION_RATE %v is out of range 0 to 1", cfg.DropConnectionRate))
+	}
+	if cfg.GrowthBytes < 0 || cfg.GrowthBytes > maxGrowthBytes {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_GROWTH_BYTES must be 0 to %d", maxGrowthBytes))
+	}
+	if cfg.WarmupRequests < 0 {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_WARMUP_REQUESTS must not be negative"))
+	}
+	if cfg.FailEvery < 0 {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_FAIL_EVERY must not be negative"))
+	}
+	if cfg.StationsPerCity < 1 || cfg.StationsPerCity > maxStationsPerCity {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_STATIONS_PER_CITY must be 1 to %d", maxStationsPerCity))
+	}
+	if cfg.CircuitThreshold < 1 {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_CIRCUIT_THRESHOLD must be at least 1"))
+	}
+	if err := checkGzipLevel(cfg.GzipLevel); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.MaxConcurrency < 0 || cfg.MaxBodyBytes < 0 {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_MAX_CONCURRENCY and WEATHER_MAX_BODY_BYTES must not be negative"))
+	}
+	if cfg.TracesEndpoint != "" {
+		if u, err := url.Parse(cfg.TracesEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, errorOf(ErrBadConfig, "OTEL_EXPORTER_OTLP_ENDPOINT %q is not an http or https URL", cfg.TracesEndpoint))
+		}
+	}
+	if cfg.ShortBodyBytes < 1 || cfg.ShortBodyBytes > maxShortBodyBytes {
+		problems = append(problems, errorOf(ErrBadConfig, "WEATHER_SHORT_BODY_BYTES must be 1 to %d", maxShortBodyBytes))
+	}
+	return errors.Join(problems...)
+}
+
+// newService builds the WeatherService described by cfg. It fails only when
+// cfg wasn't validated or the access log can't be opened.
+func newService(cfg Config) (*WeatherService, error) {
+	sleeper, err := newSleeper(cfg.Sleeper, cfg.SleepJitter)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Sleeper != "" {
+		slog.Info("Using sleeper", "sleeper", cfg.Sleeper, "jitter", cfg.SleepJitter)
+	}
+
+	var chooser StatusChooser = &RandomStatusChooser{}
+
+	// Optionally simulate correlated outage bursts where every request fails.
+	outage := &OutageSimulator{
+		Every:       cfg.BurstEvery,
+		Probability: cfg.BurstProbability,
+		Duration:    cfg.BurstDuration,
+		Start:       time.Now(),
+	}
+	if outage.Every > 0 || outage.Probability > 0 {
+		slog.Info("Outage burst mode enabled", "every", outage.Every, "probability", outage.Probability, "duration", outage.Duration)
+		chooser = &BurstChooser{Inner: chooser, Outage: outage}
+	}
+
+	// Optionally force a 500 on every Nth request.
+	if cfg.FailEvery > 0 {
+		slog.Info("Forced failure cadence enabled", "every", cfg.FailEvery)
+		chooser = &FailEveryChooser{Inner: chooser, Every: int64(cfg.FailEvery)}
+	}
+
+	// Optionally simulate an upstream circuit breaker around the chosen statuses.
+	if cfg.CircuitBreaker {
+		breaker := &CircuitBreaker{Threshold: cfg.CircuitThreshold, Cooldown: cfg.CircuitCooldown}
+		slog.Info("Circuit breaker simulation enabled", "threshold", breaker.Threshold, "cooldown", breaker.Cooldown)
+		chooser = &CircuitBreakerChooser{Inner: chooser, Breaker: breaker}
+	}
+
+	// Optionally write combined-format access logs.
+	accessLog, err := openAccessLog(cfg.AccessLog)
+	if err != nil {
+		return nil, err
+	}
+
+	// Optionally cache identical /weather queries for a TTL.
+	var cache *ResponseCache
+	if cfg.CacheTTL > 0 {
+		slog.Info("Response cache enabled", "ttl", cfg.CacheTTL)
+		cache = &ResponseCache{TTL: cfg.CacheTTL}
+	}
+
+	if cfg.DecompressBomb {
+		slog.Warn("Decompression bombs enabled: /weather?decompressBomb=true sends small bodies that decompress large")
+	}
+	var growth *Growth
+	if cfg.GrowthBytes > 0 {
+		slog.Warn("Response growth enabled: every /weather response is padded more than the last", "bytesPerRequest", cfg.GrowthBytes)
+		growth = &Growth{BytesPerRequest: cfg.GrowthBytes}
+	}
+
+	// Optionally export a span per /weather request to an OTLP collector.
+	var tracer *Tracer
+	if cfg.TracesEndpoint != "" {
+		slog.Info("Tracing enabled", "endpoint", cfg.TracesEndpoint)
+		var err error
+		if tracer, err = NewTracer(cfg.TracesEndpoint, cfg.ServiceName); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(cfg.InjectedHeaders) > 0 {
+		slog.Warn("Injecting extra response headers", "headers", len(cfg.InjectedHeaders))
+	}
+	if cfg.DropConnectionRate > 0 {
+		slog.Warn("Connection drops enabled: some /weather requests get no response", "rate", cfg.DropConnectionRate)
+	}
+	if cfg.Debug {
+		slog.Warn("Debug mode enabled: POST /debug/reset clears all in-memory state and POST /debug/maintenance switches maintenance mode")
+	}
+	if cfg.ClockSkew != 0 {
+		slog.Warn("Clock skew enabled: Date headers are offset from real time", "skew", cfg.ClockSkew, "timestamps", cfg.SkewTimestamps)
+	}
+
+	// Optionally take cities offline on a daily schedule.
+	var availability *AvailabilitySchedule
+	if len(cfg.CityOutages) > 0 {
+		availability = &AvailabilitySchedule{Outages: cfg.CityOutages}
+		slog.Info("City availability schedule enabled", "outages", len(cfg.CityOutages))
+	}
+
+	// Optionally simulate cold-start latency that decays after startup.
+	var warmup *Warmup
+	if cfg.WarmupDuration > 0 || cfg.WarmupRequests > 0 {
+		warmup = &Warmup{Start: time.Now(), Duration: cfg.WarmupDuration, Requests: int64(cfg.WarmupRequests), Delay: cfg.WarmupDelay}
+		slog.Info("Warmup enabled", "duration", warmup.Duration, "requests", warmup.Requests, "delay", warmup.Delay)
+	}
+
+	// Maintenance mode can also be switched at runtime with /debug/maintenance.
+	maintenance := &Maintenance{RetryAfter: cfg.MaintenanceRetryAfter}
+	maintenance.SetEnabled(cfg.Maintenance)
+
+	// Replay POST responses for repeated Idempotency-Keys within a TTL.
+	var idempotency *ResponseCache
+	if cfg.IdempotencyTTL > 0 {
+		idempotency = &ResponseCache{TTL: cfg.IdempotencyTTL}
+	}
+
+	// Components register their checks here; /health aggregates them.
+	readiness := newDelayedReadiness(cfg.StartupDelay)
+	health := &HealthChecker{}
+	registerHealthChecks(health, cfg, readiness, tracer)
+
+	return &WeatherService{
+		Sleeper: sleeper,
+		Chooser: chooser,
+		// Keep the most recent request logs in memory for /debug/requests.
+		RequestLog: NewRequestLog(cfg.RequestLogSize),
+		// Track in-flight requests for /metrics and shutdown logging.
+		Metrics: &Metrics{},
+		// Count response statuses for /debug/stats.
+		Stats:              &StatusStats{},
+		Health:             health,
+		Readiness:          readiness,
+		Maintenance:        maintenance,
+		MaxConcurrency:     cfg.MaxConcurrency,
+		DropConnectionRate: cfg.DropConnectionRate,
+		Deprecation:        cfg.Deprecation,
+		MaxBodyBytes:       cfg.MaxBodyBytes,
+		ShortBodyBytes:     cfg.ShortBodyBytes,
+		GzipLevel:          cfg.GzipLevel,
+		InjectedHeaders:    cfg.InjectedHeaders,
+		ServerHeader:       cfg.ServerHeader,
+		Attribution:        cfg.Attribution,
+		ClockSkew:          cfg.ClockSkew,
+		SkewTimestamps:     cfg.SkewTimestamps,
+		Warmup:             warmup,
+		Availability:       availability,
+		History:            NewHistory(cfg.HistorySize),
+		Cache:              cache,
+		Idempotency:        idempotency,
+		AccessLog:          accessLog,
+		BasePath:           cfg.BasePath,
+		HealthAtRoot:       cfg.HealthAtRoot,
+		DisabledRoutes:     cfg.DisabledRoutes,
+		Debug:              cfg.Debug,
+		Tracer:             tracer,
+		Growth:             growth,
+		DecompressBomb:     cfg.DecompressBomb,
+		StationsPerCity:    cfg.StationsPerCity,
+		SeasonWeights:      cfg.SeasonWeights,
+	}, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestLoadConfigDefaults tests the settings used when nothing is configured.
+func TestLoadConfigDefaults(t *testing.T) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+	if cfg.Port != 8080 {
+		t.Errorf("Wrong default port: got %v want %v", cfg.Port, 8080)
+	}
+	if cfg.Seed != nil || cfg.FixedDelay >= 0 || cfg.ConditionWeights != nil {
+		t.Errorf("Expected random generation by default, got seed %v, delay %v, weights %v", cfg.Seed, cfg.FixedDelay, cfg.ConditionWeights)
+	}
+	if cfg.IdempotencyTTL != 24*time.Hour || cfg.MaxBodyBytes != 1<<20 {
+		t.Errorf("Wrong defaults: idempotency TTL %v, max body %v", cfg.IdempotencyTTL, cfg.MaxBodyBytes)
+	}
+	if cfg.GzipLevel != 0 {
+		t.Errorf("Expected compression off by default, got level %v", cfg.GzipLevel)
+	}
+}
+
+// TestLoadConfigFromEnv tests that environment variables are read into the Config.
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Setenv("WEATHER_PORT", "9090")
+	t.Setenv("WEATHER_SEED", "42")
+	t.Setenv("WEATHER_FIXED_DELAY_MS", "250")
+	t.Setenv("WEATHER_CONDITION_WEIGHTS", "Sunny:5")
+	t.Setenv("WEATHER_CLOCK_SKEW", "-2m")
+	t.Setenv("WEATHER_DISABLED_ROUTES", "/metrics")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+	if cfg.Port != 9090 {
+		t.Errorf("Wrong port: got %v want %v", cfg.Port, 9090)
+	}
+	if cfg.Seed == nil || *cfg.Seed != 42 {
+		t.Errorf("Wrong seed: got %v want %v", cfg.Seed, 42)
+	}
+	if cfg.FixedDelay != 250*time.Millisecond {
+		t.Errorf("Wrong fixed delay: got %v want %v", cfg.FixedDelay, 250*time.Millisecond)
+	}
+	if len(cfg.ConditionWeights) != len(conditions) || cfg.ConditionWeights[0] != 5 {
+		t.Errorf("Wrong condition weights: got %v", cfg.ConditionWeights)
+	}
+	if cfg.ClockSkew != -2*time.Minute {
+		t.Errorf("Wrong clock skew: got %v want %v", cfg.ClockSkew, -2*time.Minute)
+	}
+	if len(cfg.DisabledRoutes) != 1 || cfg.DisabledRoutes[0] != "/metrics" {
+		t.Errorf("Wrong disabled routes: got %v", cfg.DisabledRoutes)
+	}
+}
+
+// TestLoadConfigInvalid tests that unusable settings are rejected with ErrBadConfig.
+func TestLoadConfigInvalid(t *testing.T) {
+	testCases := []struct {
+		name  string
+		env   string
+		value string
+	}{
+		{"Port", "WEATHER_PORT", "70000"},
+		{"Seed", "WEATHER_SEED", "abc"},
+		{"FixedDelay", "WEATHER_FIXED_DELAY_MS", "120000"},
+		{"DelayPerItem", "WEATHER_DELAY_PER_ITEM_MS", "-5"},
+		{"BurstProbability", "WEATHER_BURST_PROBABILITY", "1.5"},
+		{"GzipLevel", "WEATHER_GZIP_LEVEL", "12"},
+		{"CircuitThreshold", "WEATHER_CIRCUIT_THRESHOLD", "0"},
+		{"MaxConcurrency", "WEATHER_MAX_CONCURRENCY", "-1"},
+		{"CityOutages", "WEATHER_CITY_OUTAGES", "Atlantis@00:00-06:00"},
+		{"ClockSkew", "WEATHER_CLOCK_SKEW", "soon"},
+		{"TLSCertWithoutKey", "WEATHER_TLS_CERT", "cert.pem"},
+		{"ErrorDelay", "WEATHER_ERROR_DELAY_MS", "90000"},
+		{"Sunset", "WEATHER_SUNSET", "soon"},
+		{"ShortBodyBytes", "WEATHER_SHORT_BODY_BYTES", "0"},
+		{"TracesEndpoint", "OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4318"},
+		{"SeasonWeights", "WEATHER_SEASON_WEIGHTS", "monsoon=Rainy:5"},
+		{"FailEvery", "WEATHER_FAIL_EVERY", "-5"},
+		{"WarmupRequests", "WEATHER_WARMUP_REQUESTS", "-1"},
+		{"StationsPerCity", "WEATHER_STATIONS_PER_CITY", "0"},
+		{"StatusWeights", "WEATHER_STATUS_WEIGHTS", "3xx:5"},
+		{"StatusWeightsZero", "WEATHER_STATUS_WEIGHTS", "2xx:0"},
+		{"MaxSize", "WEATHER_MAX_SIZE", "5000"},
+		{"DefaultSize", "WEATHER_DEFAULT_SIZE", "5"},
+		{"MaxDelay", "WEATHER_MAX_DELAY_MS", "90000"},
+		{"DefaultMaxDelay", "WEATHER_DEFAULT_MAX_DELAY_MS", "70000"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(tc.env, tc.value)
+			if _, err := LoadConfig(); !errors.Is(err, ErrBadConfig) {
+				t.Errorf("Expected an ErrBadConfig error for %s=%s, got %v", tc.env, tc.value, err)
+			}
+		})
+	}
+}
+
+// TestNewService tests that the service is wired from the Config.
+func TestNewService(t *testing.T) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+	cfg.CacheTTL = time.Minute
+	cfg.MaxConcurrency = 3
+	cfg.BasePath = "/api"
+
+	svc, err := newService(cfg)
+	if err != nil {
+		t.Fatalf("newService returned an error: %v", err)
+	}
+	if svc.Cache == nil || svc.Cache.TTL != time.Minute {
+		t.Errorf("Expected a response cache with TTL %v", time.Minute)
+	}
+	if svc.MaxConcurrency != 3 || svc.BasePath != "/api" {
+		t.Errorf("Wrong service settings: max concurrency %v, base path %q", svc.MaxConcurrency, svc.BasePath)
+	}
+	if svc.Warmup != nil || svc.Availability != nil {
+		t.Errorf("Expected warmup and availability to be disabled")
+	}
+}
+
+// TestNewServiceHealthChecks tests that newService registers the readiness
+// and history file checks with /health.
+func TestNewServiceHealthChecks(t *testing.T) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+
+	cfg.HistoryFile = filepath.Join(t.TempDir(), "history.ndjson")
+	svc, err := newService(cfg)
+	if err != nil {
+		t.Fatalf("newService returned an error: %v", err)
+	}
+	report := svc.Health.Run()
+	if report.Status != healthStatusHealthy || report.Checks["readiness"].Status != healthStatusHealthy ||
+		report.Checks["history_file"].Status != healthStatusHealthy {
+		t.Errorf("Expected healthy readiness and history file checks, got %+v", report)
+	}
+
+	// A history file in a missing directory can't be written at shutdown.
+	cfg.HistoryFile = filepath.Join(t.TempDir(), "missing", "history.ndjson")
+	cfg.StartupDelay = time.Hour
+	if svc, err = newService(cfg); err != nil {
+		t.Fatalf("newService returned an error: %v", err)
+	}
+	report = svc.Health.Run()
+	if report.Status != healthStatusDegraded || report.Checks["readiness"].Status != healthStatusUnhealthy ||
+		report.Checks["history_file"].Status != healthStatusUnhealthy {
+		t.Errorf("Expected failing readiness and history file checks to degrade /health, got %+v", report)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// maxCPUMs is the longest CPU burn a client may request.
+const maxCPUMs = 5000
+
+// burnCPU busy-loops for d of wall-clock time, like a compute-bound backend,
+// keeping one core busy instead of sleeping. It stops early with ctx's error
+// once ctx is done.
+func burnCPU(ctx context.Context, d time.Duration) error {
+	deadline := time.Now().Add(d)
+	x := uint64(1)
+	for time.Now().Before(deadline) {
+		for i := 0; i < 10000; i++ {
+			x = x*6364136223846793005 + 1442695040888963407
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	// Keep the compiler from optimizing the loop away, without sharing a
+	// sink between concurrent burns.
+	runtime.KeepAlive(x)
+	return nil
+}
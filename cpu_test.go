@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestWeatherHandlerCPUBurn tests that cpuMs busy-loops for the requested
+// time instead of sleeping.
+func TestWeatherHandlerCPUBurn(t *testing.T) {
+	recording := &recordingSleeper{}
+	rr := httptest.NewRecorder()
+	start := time.Now()
+	weatherHandler(recording, &FixedStatusChooser{Status: http.StatusOK}, rr, httptest.NewRequest("GET", "/weather?cpuMs=30", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Handler returned after %v, want at least 30ms", elapsed)
+	}
+	if recording.total != 0 {
+		t.Errorf("Handler slept for %v, want no sleep", recording.total)
+	}
+}
+
+// TestWeatherHandlerCPUBurnInvalid tests that out-of-range and conflicting
+// cpuMs values are rejected.
+func TestWeatherHandlerCPUBurnInvalid(t *testing.T) {
+	for _, target := range []string{
+		"/weather?cpuMs=-1",
+		"/weather?cpuMs=5001",
+		"/weather?cpuMs=10&delayMs=10",
+		"/weather?cpuMs=10&keepAlive=true",
+	} {
+		rr := httptest.NewRecorder()
+		weatherHandler(sleeper, chooser, rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s returned wrong status code: got %v want %v", target, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+// TestBurnCPUCanceled tests that a burn stops once the context is done.
+func TestBurnCPUCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if err := burnCPU(ctx, time.Minute); err != context.Canceled {
+		t.Errorf("burnCPU returned %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("burnCPU took %v after cancellation", elapsed)
+	}
+}
+
+// TestWeatherHandlerCPUBurnParallel tests that concurrent burns don't share
+// state; run it with -race.
+func TestWeatherHandlerCPUBurnParallel(t *testing.T) {
+	handler := newTestService(1, nil, &FixedStatusChooser{Status: http.StatusOK}).Handler()
+	for i := range 4 {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Parallel()
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/weather?cpuMs=20", nil))
+			if rr.Code != http.StatusOK {
+				t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Deprecation describes the deprecation headers sent on /weather responses,
+// for testing how clients surface deprecation warnings.
+type Deprecation struct {
+	// Deprecated sends Deprecation: true.
+	Deprecated bool
+	// Sunset, when not zero, is sent in a Sunset header (RFC 8594).
+	Sunset time.Time
+}
+
+// parseSunset parses a WEATHER_SUNSET value, either an RFC 3339 timestamp or
+// a date such as 2030-01-01, which is taken as midnight UTC.
+func parseSunset(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, errorOf(ErrBadConfig, "invalid WEATHER_SUNSET %q, expected a date such as 2030-01-01 or an RFC 3339 timestamp", value)
+	}
+	return t, nil
+}
+
+// deprecationMiddleware attaches the deprecation headers d describes to every
+// response and logs that it did.
+func deprecationMiddleware(d Deprecation, next http.Handler) http.Handler {
+	sunset := ""
+	if !d.Sunset.IsZero() {
+		sunset = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if d.Deprecated {
+			w.Header().Set("Deprecation", "true")
+		}
+		if sunset != "" {
+			w.Header().Set("Sunset", sunset)
+		}
+		slog.Info("Attached deprecation headers", "path", req.URL.Path, "deprecated", d.Deprecated, "sunset", sunset)
+		next.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestDeprecationHeaders tests that /weather responses carry the configured
+// deprecation headers and other endpoints don't.
+func TestDeprecationHeaders(t *testing.T) {
+	svc := newTestService(1, nil, nil)
+	svc.Deprecation = Deprecation{Deprecated: true, Sunset: time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	handler := svc.Handler()
+
+	for _, target := range []string{"/weather", "/v1/weather/Tokyo"} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		if got := rr.Header().Get("Deprecation"); got != "true" {
+			t.Errorf("%s returned wrong Deprecation header: got %q want %q", target, got, "true")
+		}
+		if got, want := rr.Header().Get("Sunset"), "Tue, 01 Jan 2030 00:00:00 GMT"; got != want {
+			t.Errorf("%s returned wrong Sunset header: got %q want %q", target, got, want)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+	if rr.Header().Get("Deprecation") != "" || rr.Header().Get("Sunset") != "" {
+		t.Errorf("/health returned deprecation headers: %v", rr.Header())
+	}
+}
+
+// TestParseSunset tests the accepted WEATHER_SUNSET formats.
+func TestParseSunset(t *testing.T) {
+	want := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for _, value := range []string{"2030-01-01", "2030-01-01T00:00:00Z", "2030-01-01T01:00:00+01:00"} {
+		got, err := parseSunset(value)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseSunset(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	if _, err := parseSunset("next year"); err == nil {
+		t.Errorf("Expected an error for an invalid sunset, but got none.")
+	}
+}
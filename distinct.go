@@ -0,0 +1,25 @@
+package main
+
+import "math/rand"
+
+// spreadCities reassigns the cities of readings so exactly distinct cities
+// from candidates appear, or fewer when there are fewer readings or
+// candidates. The cities are drawn without replacement: each is used once, in
+// a shuffled order, before any repeats, so the readings are spread as evenly
+// as possible among them.
+func spreadCities(readings []WeatherReading, r *rand.Rand, candidates []string, distinct int) {
+	if len(candidates) == 0 {
+		return
+	}
+	chosen := r.Perm(len(candidates))[:min(distinct, len(candidates))]
+	var deck []int
+	for i := range readings {
+		if len(deck) == 0 {
+			deck = append(deck, chosen...)
+			r.Shuffle(len(deck), func(i, j int) { deck[i], deck[j] = deck[j], deck[i] })
+		}
+		readings[i].City = candidates[deck[0]]
+		readings[i].ID = readingID(readings[i])
+		deck = deck[1:]
+	}
+}
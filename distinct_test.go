@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestSpreadCities tests that the readings use exactly the requested number of
+// cities, as evenly as possible.
+func TestSpreadCities(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, tc := range []struct{ size, distinct, want int }{
+		{10, len(cities), len(cities)},
+		{20, 3, 3},
+		{5, len(cities), 5},
+		{10, 1, 1},
+	} {
+		readings := appendDummyWeatherReadings(nil, rng, tc.size, testEpoch)
+		spreadCities(readings, rng, cities, tc.distinct)
+
+		counts := map[string]int{}
+		for _, reading := range readings {
+			counts[reading.City]++
+			if reading.ID != readingID(reading) {
+				t.Errorf("Reading %s has a stale ID", reading.City)
+			}
+		}
+		if len(counts) != tc.want {
+			t.Errorf("size=%d distinct=%d: got %d cities, want %d", tc.size, tc.distinct, len(counts), tc.want)
+		}
+		fewest, most := tc.size, 0
+		for _, n := range counts {
+			fewest, most = min(fewest, n), max(most, n)
+		}
+		if most-fewest > 1 {
+			t.Errorf("size=%d distinct=%d: cities are uneven: %v", tc.size, tc.distinct, counts)
+		}
+	}
+}
+
+// TestWeatherHandlerDistinctCities tests the distinctCities, minCities and
+// maxCities options.
+func TestWeatherHandlerDistinctCities(t *testing.T) {
+	ok := &FixedStatusChooser{Status: http.StatusOK}
+	for _, tc := range []struct {
+		query    string
+		min, max int
+	}{
+		{"distinctCities=true&size=10", len(cities), len(cities)},
+		{"minCities=2&maxCities=2&size=50", 2, 2},
+		{"distinctCities=true&minCities=3&maxCities=5&size=30", 3, 5},
+		{"maxCities=1", 1, 1},
+	} {
+		rr := httptest.NewRecorder()
+		weatherHandler(sleeper, ok, rr, httptest.NewRequest("GET", "/weather?"+tc.query, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s returned wrong status code: got %v want %v", tc.query, rr.Code, http.StatusOK)
+		}
+		var responseData DataResponse
+		if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+			t.Fatalf("Could not decode response: %v", err)
+		}
+		distinct := map[string]bool{}
+		for _, reading := range responseData.Readings {
+			distinct[reading.City] = true
+		}
+		if len(distinct) < tc.min || len(distinct) > tc.max {
+			t.Errorf("%s: got %d distinct cities, want %d to %d", tc.query, len(distinct), tc.min, tc.max)
+		}
+	}
+}
+
+// TestWeatherHandlerDistinctCitiesOffline tests that distinctCities only
+// spreads readings over online cities, evenly, and that every reading's ID
+// and station match its final city.
+func TestWeatherHandlerDistinctCitiesOffline(t *testing.T) {
+	svc := newTestService(1, nil, nil)
+	svc.Availability = &AvailabilitySchedule{Outages: []CityOutage{{City: "Lagos", From: 0, To: 24 * time.Hour}}}
+	svc.StationsPerCity = 1
+
+	rr := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/weather?distinctCities=true&size=50", nil))
+	var responseData DataResponse
+	if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	counts := map[string]int{}
+	for _, reading := range responseData.Readings {
+		counts[reading.City]++
+		if reading.ID != readingID(reading) || reading.StationID != stationID(reading.City, 0) {
+			t.Errorf("Reading for %s has ID %s and station %s from another city", reading.City, reading.ID, reading.StationID)
+		}
+	}
+	if counts["Lagos"] > 0 || len(counts) != len(cities)-1 {
+		t.Errorf("Got readings for %v, want every online city", counts)
+	}
+	fewest, most := len(responseData.Readings), 0
+	for _, n := range counts {
+		fewest, most = min(fewest, n), max(most, n)
+	}
+	if most-fewest > 1 {
+		t.Errorf("Online cities are uneven: %v", counts)
+	}
+}
+
+// TestWeatherHandlerDistinctCitiesInvalid tests that impossible counts and
+// a fixed city are rejected.
+func TestWeatherHandlerDistinctCitiesInvalid(t *testing.T) {
+	ok := &FixedStatusChooser{Status: http.StatusOK}
+	for _, query := range []string{
+		"minCities=0",
+		"maxCities=100",
+		"minCities=5&maxCities=2",
+		"distinctCities=true&city=Tokyo",
+	} {
+		rr := httptest.NewRecorder()
+		weatherHandler(sleeper, ok, rr, httptest.NewRequest("GET", "/weather?"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s returned wrong status code: got %v want %v", query, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+)
+
+// dropConnectionMiddleware simulates connection-phase failures: with
+// probability rate, chosen with rng, the request's connection is hijacked and
+// closed before any response is written, so the client sees an EOF or, at
+// random, a connection reset (by closing with SO_LINGER 0). Connections that
+// can't be hijacked, such as HTTP/2 streams, are aborted with
+// http.ErrAbortHandler instead, which resets the stream.
+func dropConnectionMiddleware(rate float64, rng func() *rand.Rand, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r := rng()
+		if r.Float64() >= rate {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			slog.Warn("Fault injection: aborting request without a response", "path", req.URL.Path, "error", err)
+			panic(http.ErrAbortHandler)
+		}
+		reset := r.Intn(2) == 0
+		if tcp, ok := conn.(*net.TCPConn); ok && reset {
+			tcp.SetLinger(0)
+		}
+		slog.Warn("Fault injection: dropping connection without a response", "path", req.URL.Path, "reset", reset)
+		conn.Close()
+	})
+}
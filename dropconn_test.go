@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestDropConnection tests that dropped /weather requests get no response at
+// all, while other endpoints are unaffected.
+func TestDropConnection(t *testing.T) {
+	svc := newTestService(1, nil, nil)
+	svc.DropConnectionRate = 1
+	server := httptest.NewServer(svc.Handler())
+	defer server.Close()
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	for i := 0; i < 5; i++ {
+		resp, err := client.Get(server.URL + "/weather")
+		if err == nil {
+			resp.Body.Close()
+			t.Fatalf("Expected the connection to be dropped, got status %v", resp.StatusCode)
+		}
+	}
+
+	resp, err := client.Get(server.URL + "/health")
+	if err != nil {
+		t.Fatalf("Health check failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Health returned wrong status code: got %v want %v", resp.StatusCode, http.StatusOK)
+	}
+}
+
+// TestDropConnectionWithoutHijack tests that a request whose connection can't
+// be hijacked is aborted instead.
+func TestDropConnectionWithoutHijack(t *testing.T) {
+	handler := dropConnectionMiddleware(1, newTestService(1, nil, nil).rand, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("Dropped request reached the handler")
+	}))
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("Expected a panic with http.ErrAbortHandler, got %v", recovered)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/weather", nil))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	// maxEchoedHeaders is the most distinct headers echoHeaders reports.
+	maxEchoedHeaders = 100
+	// maxEchoedValueBytes is the longest header value echoHeaders reports;
+	// longer values are cut short and marked with an ellipsis.
+	maxEchoedValueBytes = 1024
+)
+
+// redactedHeaders are never echoed verbatim, since the response may be logged
+// or cached by the same proxies being debugged.
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"X-Api-Key":           true,
+	"X-Auth-Token":        true,
+	"X-Csrf-Token":        true,
+}
+
+// echoedHeaders returns the request's headers, including Host, with
+// credentials redacted, at most maxEchoedHeaders names (the first in
+// alphabetical order, so the same request always echoes the same headers)
+// and values capped at maxEchoedValueBytes.
+func echoedHeaders(req *http.Request) map[string][]string {
+	echoed := map[string][]string{"Host": {req.Host}}
+	for _, name := range sortedKeys(req.Header) {
+		values := req.Header[name]
+		if len(echoed) >= maxEchoedHeaders {
+			break
+		}
+		if redactedHeaders[name] {
+			echoed[name] = []string{"[REDACTED]"}
+			continue
+		}
+		capped := make([]string, len(values))
+		for i, value := range values {
+			if len(value) > maxEchoedValueBytes {
+				value = strings.ToValidUTF8(value[:maxEchoedValueBytes], "") + "…"
+			}
+			capped[i] = value
+		}
+		echoed[name] = capped
+	}
+	return echoed
+}
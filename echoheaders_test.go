@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestWeatherHandlerEchoHeaders tests that echoHeaders reports the request
+// headers with credentials redacted and long values capped, only in debug mode.
+func TestWeatherHandlerEchoHeaders(t *testing.T) {
+	echo := func(debug bool) map[string][]string {
+		svc := newTestService(1, nil, nil)
+		svc.Debug = debug
+		req := httptest.NewRequest("GET", "/weather?echoHeaders=true", nil)
+		req.Header.Add("X-Forwarded-For", "10.0.0.1")
+		req.Header.Add("X-Forwarded-For", "10.0.0.2")
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("X-Long", strings.Repeat("a", 5000))
+		rr := httptest.NewRecorder()
+		svc.Handler().ServeHTTP(rr, req)
+
+		var responseData DataResponse
+		if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+			t.Fatalf("Could not decode response: %v", err)
+		}
+		return responseData.RequestHeaders
+	}
+
+	headers := echo(true)
+	if got := headers["X-Forwarded-For"]; len(got) != 2 || got[0] != "10.0.0.1" || got[1] != "10.0.0.2" {
+		t.Errorf("Echoed wrong X-Forwarded-For: %q", got)
+	}
+	if got := headers["Host"]; len(got) != 1 || got[0] != "example.com" {
+		t.Errorf("Echoed wrong Host: %q", got)
+	}
+	if got := headers["Authorization"]; len(got) != 1 || got[0] != "[REDACTED]" {
+		t.Errorf("Authorization was not redacted: %q", got)
+	}
+	if got := headers["X-Long"]; len(got) != 1 || len(got[0]) > maxEchoedValueBytes+len("…") {
+		t.Errorf("Long header was not capped: %d bytes", len(got[0]))
+	}
+
+	if headers := echo(false); headers != nil {
+		t.Errorf("Headers were echoed without debug mode: %v", headers)
+	}
+}
+
+// TestEchoedHeadersLimit tests that at most maxEchoedHeaders names are echoed.
+func TestEchoedHeadersLimit(t *testing.T) {
+	req := httptest.NewRequest("GET", "/weather", nil)
+	for i := range 2 * maxEchoedHeaders {
+		req.Header.Set("X-Header-"+strconv.Itoa(i), "value")
+	}
+	echoed := echoedHeaders(req)
+	if n := len(echoed); n != maxEchoedHeaders {
+		t.Errorf("Echoed %d headers, want %d", n, maxEchoedHeaders)
+	}
+	// Host and the alphabetically first names are kept, whatever the map order.
+	for _, name := range sortedKeys(req.Header)[:maxEchoedHeaders-1] {
+		if _, ok := echoed[name]; !ok {
+			t.Errorf("%s wasn't echoed, want the first headers in alphabetical order", name)
+		}
+	}
+}
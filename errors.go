@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+)
+
+// Sentinel errors describing why options or configuration were rejected.
+// Helpers wrap them with context; callers test for them with errors.Is.
+var (
+	ErrInvalidParam      = errors.New("invalid parameter")
+	ErrInvalidSize       = errors.New("invalid size")
+	ErrUnknownCity       = errors.New("unknown city")
+	ErrConflictingParams = errors.New("conflicting parameters")
+	ErrBadConfig         = errors.New("bad configuration")
+)
+
+// kindError is an error with a self-contained message that unwraps to the
+// sentinel describing its kind, so existing messages stay readable while
+// errors.Is still works.
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string { return e.msg }
+func (e *kindError) Unwrap() error { return e.kind }
+
+// maxQuotedInput is how many bytes of a client's value error messages quote.
+const maxQuotedInput = 64
+
+// clipInput shortens s to at most maxQuotedInput bytes, on a character
+// boundary, marking the cut with "...", so an error message can't echo back
+// an arbitrarily large value.
+func clipInput(s string) string {
+	if len(s) <= maxQuotedInput {
+		return s
+	}
+	n := maxQuotedInput
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "..."
+}
+
+// errorOf returns an error of the given kind with a formatted message. String
+// arguments, usually values from the client, are clipped with clipInput.
+func errorOf(kind error, format string, args ...any) error {
+	for i, arg := range args {
+		if s, ok := arg.(string); ok {
+			args[i] = clipInput(s)
+		}
+	}
+	return &kindError{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
+
+// statusForError maps an error to the HTTP status a handler responds with:
+// 413 for bodies over the size limit, 400 for invalid or conflicting options
+// and 500 for anything unexpected.
+func statusForError(err error) int {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrInvalidParam), errors.Is(err, ErrInvalidSize),
+		errors.Is(err, ErrUnknownCity), errors.Is(err, ErrConflictingParams):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
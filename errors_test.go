@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// TestResolveWeatherOptionsErrorKinds tests that every problem in a request
+// can be matched with errors.Is and maps to 400.
+func TestResolveWeatherOptionsErrorKinds(t *testing.T) {
+	q, _ := url.ParseQuery("units=kelvin&city=Atlantis&delayMs=100&minDelay=10")
+	opts, err := parseWeatherQuery(q)
+	if err != nil {
+		t.Fatalf("Could not parse query: %v", err)
+	}
+	_, err = resolveWeatherOptions(opts)
+
+	for _, kind := range []error{ErrInvalidParam, ErrUnknownCity, ErrConflictingParams} {
+		if !errors.Is(err, kind) {
+			t.Errorf("Error %q does not match %v", err, kind)
+		}
+	}
+	if errors.Is(err, ErrInvalidSize) {
+		t.Errorf("Error %q unexpectedly matches %v", err, ErrInvalidSize)
+	}
+	if status := statusForError(err); status != http.StatusBadRequest {
+		t.Errorf("Wrong status for option errors: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+// TestErrorKinds tests the sentinel errors returned by the helpers and their
+// mapping to HTTP statuses.
+func TestErrorKinds(t *testing.T) {
+	_, seedErr := parseWeatherQuery(url.Values{"seed": {"abc"}})
+	_, outageErr := parseCityOutages("Atlantis@00:00-06:00")
+	_, weightsErr := parseConditionWeights("Sunny:-1")
+	_, levelErr := parseLogLevel("loud")
+
+	testCases := []struct {
+		name   string
+		err    error
+		kind   error
+		status int
+	}{
+		{"Seed", seedErr, ErrInvalidParam, http.StatusBadRequest},
+		{"Size", checkSize(5), ErrInvalidSize, http.StatusBadRequest},
+		{"Count", runGenerate([]string{"--count", "0"}, nil), ErrInvalidSize, http.StatusBadRequest},
+		{"Outages", outageErr, ErrBadConfig, http.StatusInternalServerError},
+		{"Weights", weightsErr, ErrBadConfig, http.StatusInternalServerError},
+		{"LogLevel", levelErr, ErrBadConfig, http.StatusInternalServerError},
+		{"TooLarge", &http.MaxBytesError{Limit: 10}, nil, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.kind != nil && !errors.Is(tc.err, tc.kind) {
+				t.Errorf("Error %v does not match %v", tc.err, tc.kind)
+			}
+			if status := statusForError(tc.err); status != tc.status {
+				t.Errorf("Wrong status for %v: got %v want %v", tc.err, status, tc.status)
+			}
+		})
+	}
+
+	if checkSize(50) != nil {
+		t.Errorf("checkSize rejected a valid size")
+	}
+}
+
+// TestErrorOfClipsInput tests that long client values are quoted only in part.
+func TestErrorOfClipsInput(t *testing.T) {
+	city := strings.Repeat("é", 1000)
+	err := errorOf(ErrUnknownCity, "unknown 'city' parameter %q", city)
+	if len(err.Error()) > 2*maxQuotedInput {
+		t.Errorf("Error message is %d bytes, want it clipped", len(err.Error()))
+	}
+	if !strings.Contains(err.Error(), `..."`) || !utf8.ValidString(err.Error()) {
+		t.Errorf("Error message is not clipped cleanly: %s", err)
+	}
+	if got := clipInput("Tokyo"); got != "Tokyo" {
+		t.Errorf("clipInput changed a short value: %q", got)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// writeStaticJSON writes v as JSON with a strong ETag derived from the body,
+// responding 304 Not Modified without a body when the request's
+// If-None-Match already names it. It suits responses that rarely change.
+func writeStaticJSON(w http.ResponseWriter, req *http.Request, v any) {
+	body, etag, err := encodeWithETag(v)
+	if err != nil {
+		slog.Error("Could not encode JSON response", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	if etagMatches(req.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// encodeWithETag encodes v as a JSON body and returns it with a strong ETag
+// derived from its contents.
+func encodeWithETag(v any) ([]byte, string, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, "", err
+	}
+	body = append(body, '\n')
+	sum := sha256.Sum256(body)
+	return body, `"` + hex.EncodeToString(sum[:8]) + `"`, nil
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag,
+// using the weak comparison RFC 9110 requires for If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// etagMatchesStrong reports whether an If-Match header value matches etag,
+// using the strong comparison RFC 9110 requires for If-Match: weak tags never
+// match.
+func etagMatchesStrong(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchMiddleware simulates optimistic concurrency on POST requests: when
+// If-Match is present and doesn't name the current ETag of the resource, the
+// request is rejected with 412 Precondition Failed before reaching next.
+func ifMatchMiddleware(currentETag func() string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ifMatch := req.Header.Get("If-Match")
+		if req.Method != http.MethodPost || ifMatch == "" {
+			next.ServeHTTP(w, req)
+			return
+		}
+		etag := currentETag()
+		if !etagMatchesStrong(ifMatch, etag) {
+			slog.Info("Precondition failed", "if_match", ifMatch, "etag", etag)
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Content-Type", "application/json")
+			writeJSON(w, http.StatusPreconditionFailed, DataResponse{
+				Message: fmt.Sprintf("Precondition failed: If-Match %s does not match the current ETag %s", ifMatch, etag),
+			})
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
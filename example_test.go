@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+)
+
+// Example_testService shows that a test service serves the same readings
+// for the same seed.
+func Example_testService() {
+	server := httptest.NewServer(newTestService(42, nil, nil).Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/weather?size=10")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer resp.Body.Close()
+
+	var data DataResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		fmt.Println(err)
+		return
+	}
+	first := data.Readings[0]
+	fmt.Println(resp.StatusCode, len(data.Readings))
+	fmt.Println(first.City, first.Timestamp.Format("2006-01-02T15:04Z07:00"), first.Humidity, first.Condition)
+	// Output:
+	// 200 10
+	// Tokyo 2024-01-01T20:00Z 85 Snowy
+}
+
+// Example_testServiceForcedStatus shows forcing every response to 503.
+func Example_testServiceForcedStatus() {
+	svc := newTestService(1, nil, &FixedStatusChooser{Status: http.StatusServiceUnavailable})
+
+	rr := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/weather", nil))
+	fmt.Println(rr.Code)
+	// Output: 503
+}
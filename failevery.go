@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+)
+
+// FailEveryChooser implements StatusChooser by forcing a 500 on every
+// Every-th call and delegating the others to Inner, for a predictable
+// failure cadence that retry tests can assert against.
+type FailEveryChooser struct {
+	Inner StatusChooser
+	Every int64
+
+	count atomic.Int64
+}
+
+// ChooseStatus returns 500 for every Every-th call, counting from the first
+// call or the last Reset, and the inner chooser's status otherwise.
+func (c *FailEveryChooser) ChooseStatus(r *rand.Rand) int {
+	if n := c.count.Add(1); n%c.Every == 0 {
+		slog.Info("Forcing a failure", "request", n, "every", c.Every)
+		return http.StatusInternalServerError
+	}
+	return c.Inner.ChooseStatus(r)
+}
+
+// ChooseRequestedStatus passes status to the inner chooser. Requested
+// statuses don't count towards Every.
+func (c *FailEveryChooser) ChooseRequestedStatus(r *rand.Rand, status int) int {
+	return chooseRequestedStatus(c.Inner, r, status)
+}
+
+// Reset restarts the count and resets the inner chooser.
+func (c *FailEveryChooser) Reset() {
+	c.count.Store(0)
+	resetChooser(c.Inner)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestFailEveryChooser tests that every third /weather request fails with
+// 500 and that /debug/reset restarts the count.
+func TestFailEveryChooser(t *testing.T) {
+	svc := newTestService(1, nil, &FailEveryChooser{Inner: &FixedStatusChooser{Status: http.StatusOK}, Every: 3})
+	svc.Debug = true
+	handler := svc.Handler()
+
+	serve := func(method, target string) int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, target, nil))
+		return rr.Code
+	}
+
+	want := []int{200, 200, 500, 200, 200, 500, 200}
+	for i, status := range want {
+		if got := serve("GET", "/weather"); got != status {
+			t.Errorf("Request %d returned wrong status code: got %v want %v", i+1, got, status)
+		}
+	}
+
+	// After a reset the third request fails again, not the second.
+	if got := serve("POST", "/debug/reset"); got != http.StatusOK {
+		t.Fatalf("Reset returned wrong status code: got %v want %v", got, http.StatusOK)
+	}
+	for i, status := range want[:3] {
+		if got := serve("GET", "/weather"); got != status {
+			t.Errorf("Request %d after reset returned wrong status code: got %v want %v", i+1, got, status)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxForecastHours is the longest forecast a client may request (one week).
+const maxForecastHours = 168
+
+// generateForecast generates one reading per hour for the next hours hours in
+// city. Temperature follows a daily sine curve around a random base with a
+// small random walk on top, so consecutive readings vary smoothly.
+func generateForecast(city string, hours int, start time.Time) []WeatherReading {
+	base := float64(r.Intn(20)+10) + r.Float64() // 10.0 to 30.0 Celsius
+	amplitude := 3 + r.Float64()*5               // 3 to 8 degrees either side
+	drift := 0.0
+	humidity := r.Intn(80) + 20
+	condition := pickCondition(r)
+
+	readings := make([]WeatherReading, hours)
+	for i := 0; i < hours; i++ {
+		ts := start.Add(time.Duration(i+1) * time.Hour)
+
+		// Peak around 15:00, lowest around 03:00.
+		phase := 2 * math.Pi * float64(ts.Hour()-9) / 24
+		drift += r.Float64() - 0.5
+		humidity = min(max(humidity+r.Intn(7)-3, 20), 99)
+		if r.Intn(5) == 0 { // 20% chance the condition changes each hour
+			condition = pickCondition(r)
+		}
+
+		readings[i] = WeatherReading{
+			City:        city,
+			Timestamp:   ts,
+			Temperature: base + amplitude*math.Sin(phase) + drift,
+			Humidity:    humidity,
+			Condition:   condition,
+		}
+		readings[i].ID = readingID(readings[i])
+	}
+	return readings
+}
+
+// forecastHandler handles requests to the /weather/forecast endpoint.
+func forecastHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	city, ok := lookupCity(req.URL.Query().Get("city"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, DataResponse{
+			Message: fmt.Sprintf("Unknown or missing 'city' parameter: %q.", req.URL.Query().Get("city")),
+		})
+		return
+	}
+
+	hoursStr := req.URL.Query().Get("hours")
+	hours := 24 // Default forecast length
+	if hoursStr != "" {
+		var err error
+		hours, err = strconv.Atoi(hoursStr)
+		if err != nil || hours < 1 || hours > maxForecastHours {
+			writeJSON(w, http.StatusBadRequest, DataResponse{
+				Message: fmt.Sprintf("Invalid 'hours' parameter %q, expected 1 to %d.", hoursStr, maxForecastHours),
+			})
+			return
+		}
+	}
+
+	readings := generateForecast(city, hours, time.Now().Truncate(time.Hour))
+	slog.Info("Responding with forecast", "city", city, "hours", hours)
+	writeJSON(w, http.StatusOK, DataResponse{
+		Readings:    readings,
+		Message:     fmt.Sprintf("Successfully retrieved a %d hour forecast for %s.", hours, city),
+		GeneratedAt: time.Now().UTC(),
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestForecastHandler tests the /weather/forecast endpoint.
+func TestForecastHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/weather/forecast?city=tokyo&hours=48", nil)
+	rr := httptest.NewRecorder()
+	forecastHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Forecast handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var responseData DataResponse
+	if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	if len(responseData.Readings) != 48 {
+		t.Fatalf("Forecast handler returned unexpected number of readings: got %d want %d", len(responseData.Readings), 48)
+	}
+
+	for i, reading := range responseData.Readings {
+		if reading.City != "Tokyo" {
+			t.Errorf("Reading %d has wrong city: got %v want %v", i, reading.City, "Tokyo")
+		}
+		if i == 0 {
+			continue
+		}
+		prev := responseData.Readings[i-1]
+		if step := reading.Timestamp.Sub(prev.Timestamp); step != time.Hour {
+			t.Errorf("Reading %d is not one hour after the previous one: got %v", i, step)
+		}
+		// A sine of at most 8 degrees amplitude moves under 2.1 degrees per hour, plus 0.5 of drift.
+		if diff := math.Abs(reading.Temperature - prev.Temperature); diff > 3 {
+			t.Errorf("Reading %d temperature jumped by %.2f degrees", i, diff)
+		}
+	}
+}
+
+// TestForecastHandlerInvalidParams tests the /weather/forecast endpoint with invalid parameters.
+func TestForecastHandlerInvalidParams(t *testing.T) {
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"MissingCity", "hours=24"},
+		{"UnknownCity", "city=Atlantis"},
+		{"HoursTooSmall", "city=Tokyo&hours=0"},
+		{"HoursTooLarge", "city=Tokyo&hours=1000"},
+		{"NonNumericHours", "city=Tokyo&hours=abc"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/weather/forecast?"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			forecastHandler(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Forecast handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// runGenerate implements the generate subcommand, which writes a DataResponse
+// as JSON to out without starting the server.
+func runGenerate(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	count := fs.Int("count", 10, "number of readings to generate")
+	seed := fs.Int64("seed", 0, "random seed (default: current time)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *count < 1 {
+		return errorOf(ErrInvalidSize, "generate: --count must be at least 1")
+	}
+
+	seedSet := false
+	fs.Visit(func(f *flag.Flag) { seedSet = seedSet || f.Name == "seed" })
+	if !seedSet {
+		*seed = time.Now().UnixNano()
+	}
+
+	return encodeFixture(out, generateResponse(*seed, *count))
+}
+
+// runFixtures implements the fixtures subcommand, which writes count
+// DataResponse files named weather-seed-N.json to a directory, generated with
+// consecutive seeds starting at --seed. The directory is created if needed,
+// and existing files are only overwritten with --force. A summary is written
+// to out.
+func runFixtures(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("fixtures", flag.ContinueOnError)
+	count := flags.Int("count", 10, "number of fixture files to write")
+	size := flags.Int("size", 10, "number of readings in each file")
+	dir := flags.String("out", "fixtures", "directory to write the files to")
+	seed := flags.Int64("seed", 1, "seed of the first file; each next file uses the next seed")
+	force := flags.Bool("force", false, "overwrite existing files")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *count < 1 || *size < 1 {
+		return errorOf(ErrInvalidSize, "fixtures: --count and --size must be at least 1")
+	}
+
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return fmt.Errorf("fixtures: %w", err)
+	}
+	// Check every name first, so a refusal leaves no partial corpus behind.
+	if !*force {
+		for i := range *count {
+			path := fixturePath(*dir, *seed+int64(i))
+			if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("fixtures: %s already exists, use --force to overwrite it", path)
+			}
+		}
+	}
+
+	for i := range *count {
+		if err := writeFixture(fixturePath(*dir, *seed+int64(i)), *seed+int64(i), *size, *force); err != nil {
+			return fmt.Errorf("fixtures: %w", err)
+		}
+	}
+	_, err := fmt.Fprintf(out, "Wrote %d fixtures with seeds %d to %d to %s\n", *count, *seed, *seed+int64(*count)-1, *dir)
+	return err
+}
+
+// fixturePath returns the name of the fixture file generated with seed.
+func fixturePath(dir string, seed int64) string {
+	return filepath.Join(dir, fmt.Sprintf("weather-seed-%d.json", seed))
+}
+
+// writeFixture writes the response generated with seed to path. Without
+// force it fails rather than replace a file created in the meantime.
+func writeFixture(path string, seed int64, size int, force bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !force {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := encodeFixture(f, generateResponse(seed, size)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// generateResponse generates a DataResponse of count readings from seed, as
+// the generate and fixtures subcommands write it.
+func generateResponse(seed int64, count int) DataResponse {
+	readings := generateDummyWeatherReadings(rand.New(rand.NewSource(seed)), count)
+	return DataResponse{
+		Readings:    readings,
+		Message:     fmt.Sprintf("Successfully retrieved %d weather readings.", len(readings)),
+		GeneratedAt: time.Now().UTC(),
+	}
+}
+
+// encodeFixture writes data to out as indented JSON.
+func encodeFixture(out io.Writer, data DataResponse) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
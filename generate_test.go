@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRunGenerate tests the generate subcommand.
+func TestRunGenerate(t *testing.T) {
+	var out bytes.Buffer
+	if err := runGenerate([]string{"--count", "50", "--seed", "7"}, &out); err != nil {
+		t.Fatalf("generate returned an error: %v", err)
+	}
+
+	var responseData DataResponse
+	if err := json.Unmarshal(out.Bytes(), &responseData); err != nil {
+		t.Fatalf("Could not decode generate output: %v", err)
+	}
+	if len(responseData.Readings) != 50 {
+		t.Errorf("generate returned unexpected number of readings: got %d want %d", len(responseData.Readings), 50)
+	}
+
+	if err := runGenerate([]string{"--count", "0"}, &out); err == nil {
+		t.Errorf("Expected an error for --count 0, but got none.")
+	}
+}
+
+// TestRunFixtures tests that the fixtures subcommand writes one distinct,
+// predictably named file per seed and refuses to overwrite without --force.
+func TestRunFixtures(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "corpus")
+	args := []string{"--count", "3", "--size", "20", "--seed", "5", "--out", dir}
+	var out bytes.Buffer
+	if err := runFixtures(args, &out); err != nil {
+		t.Fatalf("fixtures returned an error: %v", err)
+	}
+
+	var first []WeatherReading
+	for seed := 5; seed <= 7; seed++ {
+		data, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("weather-seed-%d.json", seed)))
+		if err != nil {
+			t.Fatalf("Could not read fixture for seed %d: %v", seed, err)
+		}
+		var responseData DataResponse
+		if err := json.Unmarshal(data, &responseData); err != nil {
+			t.Fatalf("Could not decode fixture for seed %d: %v", seed, err)
+		}
+		if len(responseData.Readings) != 20 {
+			t.Errorf("Fixture for seed %d has %d readings, want 20", seed, len(responseData.Readings))
+		}
+		if first != nil && responseData.Readings[0].Temperature == first[0].Temperature {
+			t.Errorf("Fixture for seed %d repeats the first fixture", seed)
+		}
+		first = responseData.Readings
+	}
+
+	// Overlapping seeds are refused without writing anything.
+	err := runFixtures([]string{"--count", "2", "--seed", "4", "--out", dir}, &out)
+	if err == nil {
+		t.Fatalf("Expected an error for existing files, but got none.")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "weather-seed-4.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("A fixture was written despite the refusal: %v", err)
+	}
+	if err := runFixtures([]string{"--count", "2", "--seed", "4", "--out", dir, "--force"}, &out); err != nil {
+		t.Errorf("fixtures with --force returned an error: %v", err)
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+// groupByCity is the groupBy option value that groups readings by city.
+const groupByCity = "city"
+
+// groupReadingsByCity groups readings by the city of the reading at the same
+// index in original, keeping each city's readings in their original order, so
+// readings that omit their city are still grouped. encoding/json writes map
+// keys in sorted order, so the cities always appear alphabetically.
+func groupReadingsByCity[R any](readings []R, original []WeatherReading) map[string][]R {
+	grouped := make(map[string][]R)
+	for i, reading := range readings {
+		city := original[i].City
+		grouped[city] = append(grouped[city], reading)
+	}
+	return grouped
+}
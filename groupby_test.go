@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestWeatherHandlerGroupByCity tests that groupBy=city returns the readings
+// grouped by city, with the cities in sorted order.
+func TestWeatherHandlerGroupByCity(t *testing.T) {
+	req := httptest.NewRequest("GET", "/weather?groupBy=city&size=50&seed=3", nil)
+	rr := httptest.NewRecorder()
+	weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusOK}, rr, req)
+	body := rr.Body.String()
+
+	var grouped struct {
+		Readings map[string][]WeatherReading `json:"readings"`
+	}
+	if err := json.Unmarshal([]byte(body), &grouped); err != nil {
+		t.Fatalf("Could not decode grouped response: %v", err)
+	}
+	total := 0
+	for city, readings := range grouped.Readings {
+		for _, reading := range readings {
+			if reading.City != city {
+				t.Errorf("Reading for %s is grouped under %s", reading.City, city)
+			}
+		}
+		total += len(readings)
+	}
+	if total != 50 {
+		t.Errorf("Grouped response has wrong number of readings: got %v want %v", total, 50)
+	}
+
+	// Cities appear in the body in alphabetical order.
+	cities := make([]string, 0, len(grouped.Readings))
+	for city := range grouped.Readings {
+		cities = append(cities, city)
+	}
+	slices.Sort(cities)
+	for i := 1; i < len(cities); i++ {
+		if strings.Index(body, `"`+cities[i-1]+`":`) > strings.Index(body, `"`+cities[i]+`":`) {
+			t.Errorf("Cities are not in sorted order: %s appears after %s", cities[i-1], cities[i])
+		}
+	}
+}
+
+// TestWeatherHandlerGroupByCityError tests that error responses keep the usual shape.
+func TestWeatherHandlerGroupByCityError(t *testing.T) {
+	req := httptest.NewRequest("GET", "/weather?groupBy=city", nil)
+	rr := httptest.NewRecorder()
+	weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusServiceUnavailable}, rr, req)
+
+	var responseData DataResponse
+	if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+		t.Fatalf("Could not decode error response: %v", err)
+	}
+	if responseData.Message == "" || responseData.Readings != nil {
+		t.Errorf("Error response has wrong shape: %+v", responseData)
+	}
+}
+
+// TestWeatherHandlerCombinedShapes tests that groupBy, omitFields and
+// humidityPrecision=float apply together, grouping readings that lack their
+// city under the right one and keeping the rest of the envelope.
+func TestWeatherHandlerCombinedShapes(t *testing.T) {
+	req := httptest.NewRequest("GET", "/weather?groupBy=city&omitFields=true&humidityPrecision=float&attribution=true&size=100&seed=3", nil)
+	rr := httptest.NewRecorder()
+	weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusOK}, rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var responseData struct {
+		Readings    map[string][]map[string]json.RawMessage `json:"readings"`
+		Source      string                                  `json:"source"`
+		GeneratedAt string                                  `json:"generated_at"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	if responseData.Source != dataSource || responseData.GeneratedAt == "" {
+		t.Errorf("Response lost its envelope: source %q, generated_at %q", responseData.Source, responseData.GeneratedAt)
+	}
+	total, incomplete, fractional := 0, 0, 0
+	for city, readings := range responseData.Readings {
+		for _, reading := range readings {
+			total++
+			if raw, ok := reading["city"]; !ok {
+				incomplete++
+			} else if string(raw) != `"`+city+`"` {
+				t.Errorf("Reading for %s is grouped under %s", raw, city)
+			}
+			if strings.Contains(string(reading["humidity"]), ".") {
+				fractional++
+			}
+		}
+	}
+	if total != 100 || incomplete == 0 || fractional == 0 {
+		t.Errorf("Got %d readings, %d without a city and %d with fractional humidity; want 100 and some of each", total, incomplete, fractional)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// maxGrowthBytes caps the padding Growth adds, so a long run can't exhaust
+// memory.
+const maxGrowthBytes = 16 << 20
+
+// Growth pads successive /weather responses with more and more bytes,
+// simulating a leak, to validate alerting on response-size creep. A nil
+// Growth adds nothing.
+type Growth struct {
+	// BytesPerRequest is how much each response grows over the previous one.
+	BytesPerRequest int
+
+	requests atomic.Int64
+}
+
+// Padding returns the padding for the next response: BytesPerRequest bytes
+// for every earlier response since start or the last Reset, up to
+// maxGrowthBytes.
+func (g *Growth) Padding() string {
+	if g == nil {
+		return ""
+	}
+	n := min((g.requests.Add(1)-1)*int64(g.BytesPerRequest), maxGrowthBytes)
+	if n == 0 {
+		return ""
+	}
+	slog.Info("Fault injection: padding the response to simulate a leak", "bytes", n)
+	return strings.Repeat("x", int(n))
+}
+
+// Reset shrinks responses back to their normal size.
+func (g *Growth) Reset() {
+	if g != nil {
+		g.requests.Store(0)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestGrowth tests that successive /weather responses grow by the configured
+// rate and shrink back after /debug/reset.
+func TestGrowth(t *testing.T) {
+	svc := newTestService(1, nil, &FixedStatusChooser{Status: http.StatusInternalServerError})
+	svc.Debug = true
+	svc.Growth = &Growth{BytesPerRequest: 100}
+	handler := svc.Handler()
+
+	padding := func() int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/weather", nil))
+		var responseData DataResponse
+		if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+			t.Fatalf("Could not decode response: %v", err)
+		}
+		return len(responseData.Padding)
+	}
+
+	for i, want := range []int{0, 100, 200, 300} {
+		if got := padding(); got != want {
+			t.Errorf("Response %d has %d bytes of padding, want %d", i+1, got, want)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/debug/reset", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Reset returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if got := padding(); got != 0 {
+		t.Errorf("Response after reset has %d bytes of padding, want 0", got)
+	}
+}
+
+// TestGrowthCapped tests that the padding stops growing at maxGrowthBytes.
+func TestGrowthCapped(t *testing.T) {
+	g := &Growth{BytesPerRequest: maxGrowthBytes / 2}
+	for _, want := range []int{0, maxGrowthBytes / 2, maxGrowthBytes, maxGrowthBytes} {
+		if got := len(g.Padding()); got != want {
+			t.Errorf("Padding is %d bytes, want %d", got, want)
+		}
+	}
+}
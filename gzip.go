@@ -0,0 +1,141 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// checkGzipLevel returns an ErrBadConfig error unless level is 1 to 9,
+// gzip.DefaultCompression, or 0 to disable compression.
+func checkGzipLevel(level int) error {
+	if level < gzip.DefaultCompression || level > gzip.BestCompression {
+		return errorOf(ErrBadConfig, "WEATHER_GZIP_LEVEL %d is out of range 1 to 9 (or 0 to disable compression)", level)
+	}
+	return nil
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip, either by
+// name or with *, and doesn't rule it out with q=0.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.TrimSpace(coding)
+		if !strings.EqualFold(coding, "gzip") && coding != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter compresses the body written through it. Whether to
+// compress is decided when the status is written, so responses without a
+// body or that are already encoded pass through untouched.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	pool    *sync.Pool
+	gz      *gzip.Writer
+	decided bool
+}
+
+// decide sets up compression for a response with the given status.
+func (g *gzipResponseWriter) decide(code int) {
+	g.decided = true
+	h := g.Header()
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified || h.Get("Content-Encoding") != "" {
+		return
+	}
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length") // The length written by handlers is uncompressed
+	g.gz = g.pool.Get().(*gzip.Writer)
+	g.gz.Reset(g.ResponseWriter)
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	if !g.decided {
+		g.decide(code)
+	}
+	g.ResponseWriter.WriteHeader(code)
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !g.decided {
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.gz != nil {
+		return g.gz.Write(b)
+	}
+	return g.ResponseWriter.Write(b)
+}
+
+// Flush flushes the compressed data written so far, so streaming endpoints
+// still reach the client incrementally.
+func (g *gzipResponseWriter) Flush() {
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer so http.ResponseController can reach it.
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// close finishes the compressed stream and returns the writer to the pool.
+func (g *gzipResponseWriter) close() {
+	if g.gz == nil {
+		return
+	}
+	g.gz.Close()
+	g.pool.Put(g.gz)
+	g.gz = nil
+}
+
+// skipCompression sends the response written to w uncompressed, when w is or
+// wraps a gzipResponseWriter that hasn't written the status yet. Handlers
+// call it for responses whose framing is the point, such as a Content-Length
+// that doesn't match the body.
+func skipCompression(w http.ResponseWriter) {
+	for {
+		switch rw := w.(type) {
+		case *gzipResponseWriter:
+			rw.decided = true
+			return
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return
+		}
+	}
+}
+
+// gzipMiddleware compresses responses at the given level for clients that
+// send Accept-Encoding: gzip. Writers are pooled, since allocating one per
+// response would dominate the cost at low levels.
+func gzipMiddleware(level int, next http.Handler) http.Handler {
+	pool := &sync.Pool{New: func() any {
+		gz, _ := gzip.NewWriterLevel(nil, level) // The level is validated at startup
+		return gz
+	}}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if req.Method == http.MethodHead || !acceptsGzip(req.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w, pool: pool}
+		defer gw.close()
+		next.ServeHTTP(gw, req)
+	})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestAcceptsGzip tests Accept-Encoding negotiation.
+func TestAcceptsGzip(t *testing.T) {
+	testCases := map[string]bool{
+		"gzip":                true,
+		"deflate, GZIP;q=0.5": true,
+		"*":                   true,
+		"gzip;q=0":            false,
+		"br, deflate":         false,
+		"":                    false,
+	}
+	for header, want := range testCases {
+		if got := acceptsGzip(header); got != want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+// TestGzipMiddleware tests that responses are compressed only for clients
+// that accept gzip and only when they have a body.
+func TestGzipMiddleware(t *testing.T) {
+	svc := newTestService(1, nil, nil)
+	svc.GzipLevel = gzip.BestSpeed
+	handler := svc.Handler()
+
+	req := httptest.NewRequest("GET", "/weather?size=50", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Handler returned wrong Content-Encoding: got %q want %q", got, "gzip")
+	}
+	if got := rr.Header().Get("Content-Length"); got != "" {
+		t.Errorf("Compressed response kept the uncompressed Content-Length %s", got)
+	}
+	body, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("Response is not valid gzip: %v", err)
+	}
+	var responseData DataResponse
+	if err := json.NewDecoder(body).Decode(&responseData); err != nil {
+		t.Fatalf("Could not decode decompressed response: %v", err)
+	}
+	if len(responseData.Readings) != 50 {
+		t.Errorf("Handler returned wrong number of readings: got %v want %v", len(responseData.Readings), 50)
+	}
+
+	// Without Accept-Encoding the response is left alone.
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/weather", nil))
+	if got := rr.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Response was encoded without Accept-Encoding: %q", got)
+	}
+	if got := rr.Header().Get("Vary"); got == "" {
+		t.Errorf("Response has no Vary header")
+	}
+
+	// Responses without a body are never encoded.
+	req = httptest.NewRequest("GET", "/weather?status=204", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if got := rr.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("204 response was encoded: Content-Encoding %q", got)
+	}
+}
+
+// TestGzipDisabled tests that a level of 0 disables compression.
+func TestGzipDisabled(t *testing.T) {
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	newTestService(1, nil, nil).Handler().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected an uncompressed 200, got %v with Content-Encoding %q", rr.Code, rr.Header().Get("Content-Encoding"))
+	}
+}
+
+// TestGzipSkipsFramingFaults tests that faults in the body framing are sent
+// uncompressed, so the client sees the Content-Length they depend on.
+func TestGzipSkipsFramingFaults(t *testing.T) {
+	svc := newTestService(1, nil, &FixedStatusChooser{Status: http.StatusOK})
+	svc.GzipLevel = gzip.BestSpeed
+	handler := svc.Handler()
+
+	for _, query := range []string{"truncate=true", "bodyDelay=1ms"} {
+		req := httptest.NewRequest("GET", "/weather?"+query, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if got := rr.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("%s: response was encoded: Content-Encoding %q", query, got)
+		}
+		if got := rr.Header().Get("Content-Length"); got == "" {
+			t.Errorf("%s: response has no Content-Length", query)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// injectedHeader is an extra response header added for proxy testing.
+type injectedHeader struct {
+	Name, Value string
+}
+
+// parseInjectedHeaders parses a value such as
+// "Content-Type: text/plain|X-Debug: 1|X-Debug: 2" into headers. Names may
+// repeat; every entry is added, so repeats produce duplicate headers.
+func parseInjectedHeaders(value string) ([]injectedHeader, error) {
+	var headers []injectedHeader
+	for _, entry := range strings.Split(value, "|") {
+		name, headerValue, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t\r\n") || strings.ContainsAny(headerValue, "\r\n") {
+			return nil, errorOf(ErrBadConfig, "invalid header %q, expected Name: value", entry)
+		}
+		headers = append(headers, injectedHeader{Name: name, Value: strings.TrimSpace(headerValue)})
+	}
+	return headers, nil
+}
+
+// headerInjector wraps an http.ResponseWriter and adds extra headers just
+// before the status line is written. Adding them any earlier would let the
+// handler's own Header().Set calls replace them instead of duplicating them.
+type headerInjector struct {
+	http.ResponseWriter
+	headers     []injectedHeader
+	wroteHeader bool
+}
+
+// WriteHeader adds the injected headers alongside the handler's own.
+func (hi *headerInjector) WriteHeader(code int) {
+	if !hi.wroteHeader {
+		hi.wroteHeader = true
+		for _, h := range hi.headers {
+			hi.Header().Add(h.Name, h.Value)
+		}
+	}
+	hi.ResponseWriter.WriteHeader(code)
+}
+
+// Write injects the headers with an implicit 200 if no status was written yet.
+func (hi *headerInjector) Write(b []byte) (int, error) {
+	if !hi.wroteHeader {
+		hi.WriteHeader(http.StatusOK)
+	}
+	return hi.ResponseWriter.Write(b)
+}
+
+// Flush forwards to the underlying writer if it supports flushing.
+func (hi *headerInjector) Flush() {
+	if !hi.wroteHeader {
+		hi.WriteHeader(http.StatusOK)
+	}
+	if f, ok := hi.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer so http.ResponseController can reach it.
+func (hi *headerInjector) Unwrap() http.ResponseWriter {
+	return hi.ResponseWriter
+}
+
+// headerInjectionMiddleware adds headers to every response, duplicating any
+// the handler sets itself.
+func headerInjectionMiddleware(headers []injectedHeader, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		next.ServeHTTP(&headerInjector{ResponseWriter: w, headers: headers}, req)
+	})
+}
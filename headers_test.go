@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestParseInjectedHeaders tests parsing the WEATHER_INJECT_HEADERS format.
+func TestParseInjectedHeaders(t *testing.T) {
+	headers, err := parseInjectedHeaders("Content-Type: text/plain; charset=utf-8|X-Debug: 1|X-Debug:2")
+	if err != nil {
+		t.Fatalf("Could not parse headers: %v", err)
+	}
+	expected := []injectedHeader{
+		{Name: "Content-Type", Value: "text/plain; charset=utf-8"},
+		{Name: "X-Debug", Value: "1"},
+		{Name: "X-Debug", Value: "2"},
+	}
+	if len(headers) != len(expected) {
+		t.Fatalf("Parsed wrong number of headers: got %+v want %+v", headers, expected)
+	}
+	for i := range expected {
+		if headers[i] != expected[i] {
+			t.Errorf("Header %d parsed wrongly: got %+v want %+v", i, headers[i], expected[i])
+		}
+	}
+
+	for _, value := range []string{"X-Debug", ": 1", "X Debug: 1"} {
+		if _, err := parseInjectedHeaders(value); err == nil {
+			t.Errorf("Expected an error parsing %q", value)
+		}
+	}
+}
+
+// TestHeaderInjectionMiddleware tests that injected headers duplicate the
+// ones the handler sets rather than being replaced by them.
+func TestHeaderInjectionMiddleware(t *testing.T) {
+	headers := []injectedHeader{{"Content-Type", "text/plain"}, {"X-Debug", "1"}, {"X-Debug", "2"}}
+	handler := headerInjectionMiddleware(headers, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusOK}, w, req)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/weather", nil))
+
+	if got := rr.Header().Values("Content-Type"); len(got) != 2 || got[0] != "application/json" || got[1] != "text/plain" {
+		t.Errorf("Handler returned wrong Content-Type values: got %v want [application/json text/plain]", got)
+	}
+	if got := rr.Header().Values("X-Debug"); len(got) != 2 {
+		t.Errorf("Handler returned wrong X-Debug values: got %v want [1 2]", got)
+	}
+}
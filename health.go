@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// Health statuses reported by /health.
+const (
+	healthStatusHealthy   = "healthy"
+	healthStatusDegraded  = "degraded"
+	healthStatusUnhealthy = "unhealthy"
+)
+
+// HealthCheck is a named check run on every /health request. A failing
+// critical check makes the service unhealthy; a failing non-critical check
+// only degrades it.
+type HealthCheck struct {
+	Name     string
+	Critical bool
+	Check    func() error
+}
+
+// HealthChecker is a registry of health checks that components register with.
+type HealthChecker struct {
+	mu     sync.RWMutex
+	checks []HealthCheck
+}
+
+// CheckResult is the outcome of a single health check.
+type CheckResult struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+}
+
+// HealthReport is the aggregated response of /health.
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+	// Runtime is only reported with ?verbose=true.
+	Runtime *RuntimeStats `json:"runtime,omitempty"`
+}
+
+// RuntimeStats summarizes runtime.MemStats and the goroutine count, to spot
+// leaks under load.
+type RuntimeStats struct {
+	Goroutines     int    `json:"goroutines"`
+	HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+	HeapInuseBytes uint64 `json:"heap_inuse_bytes"`
+	HeapObjects    uint64 `json:"heap_objects"`
+	SysBytes       uint64 `json:"sys_bytes"`
+	NumGC          uint32 `json:"num_gc"`
+	PauseTotalNs   uint64 `json:"gc_pause_total_ns"`
+}
+
+// readRuntimeStats reads the current RuntimeStats. It briefly stops the world
+// to read the memory statistics, so it is only done on request.
+func readRuntimeStats() *RuntimeStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return &RuntimeStats{
+		Goroutines:     runtime.NumGoroutine(),
+		HeapAllocBytes: m.HeapAlloc,
+		HeapInuseBytes: m.HeapInuse,
+		HeapObjects:    m.HeapObjects,
+		SysBytes:       m.Sys,
+		NumGC:          m.NumGC,
+		PauseTotalNs:   m.PauseTotalNs,
+	}
+}
+
+// Register adds a health check to the registry.
+func (h *HealthChecker) Register(name string, critical bool, check func() error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, HealthCheck{Name: name, Critical: critical, Check: check})
+}
+
+// registerHealthChecks registers the checks of the components cfg enables:
+// readiness, whether the history file can be written at shutdown and whether
+// the last span export succeeded. None of them is critical, since the
+// simulator keeps serving /weather while they fail.
+func registerHealthChecks(h *HealthChecker, cfg Config, readiness *Readiness, tracer *Tracer) {
+	h.Register("readiness", false, func() error {
+		if !readiness.Ready() {
+			return errors.New("still starting up")
+		}
+		return nil
+	})
+	if cfg.HistoryFile != "" {
+		h.Register("history_file", false, func() error {
+			return checkWritable(cfg.HistoryFile)
+		})
+	}
+	if tracer != nil {
+		h.Register("trace_exporter", false, tracer.ExportError)
+	}
+}
+
+// checkWritable reports whether the file at path can be created or written,
+// without changing it.
+func checkWritable(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err == nil {
+		return f.Close()
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	f, err = os.CreateTemp(filepath.Dir(path), ".health-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// Run executes every registered check and aggregates the results.
+func (h *HealthChecker) Run() HealthReport {
+	h.mu.RLock()
+	checks := append([]HealthCheck(nil), h.checks...)
+	h.mu.RUnlock()
+
+	report := HealthReport{Status: healthStatusHealthy}
+	if len(checks) > 0 {
+		report.Checks = make(map[string]CheckResult, len(checks))
+	}
+	for _, check := range checks {
+		result := CheckResult{Status: healthStatusHealthy, Critical: check.Critical}
+		if err := check.Check(); err != nil {
+			result.Status = healthStatusUnhealthy
+			result.Error = err.Error()
+			if check.Critical {
+				report.Status = healthStatusUnhealthy
+			} else if report.Status == healthStatusHealthy {
+				report.Status = healthStatusDegraded
+			}
+		}
+		report.Checks[check.Name] = result
+	}
+	return report
+}
+
+// healthHandler handles requests to the /health endpoint. It returns 503 if
+// any critical check fails and 200 otherwise, with per-check detail as JSON,
+// and runtime statistics with ?verbose=true.
+func healthHandler(h *HealthChecker, w http.ResponseWriter, req *http.Request) {
+	report := h.Run()
+	if req.URL.Query().Get("verbose") == "true" {
+		report.Runtime = readRuntimeStats()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	status := http.StatusOK
+	if report.Status == healthStatusUnhealthy {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+// History keeps the most recent readings served by /weather, for export.
+type History struct {
+	buf *ringBuffer[WeatherReading]
+}
+
+// NewHistory creates a History that keeps at most size readings.
+func NewHistory(size int) *History {
+	return &History{buf: newRingBuffer[WeatherReading](size)}
+}
+
+// Add records readings, evicting the oldest ones if the history is full.
+func (h *History) Add(readings ...WeatherReading) {
+	h.buf.Add(readings...)
+}
+
+// Reset discards every recorded reading.
+func (h *History) Reset() {
+	h.buf.Reset()
+}
+
+// Readings returns a copy of the recorded readings, oldest first.
+func (h *History) Readings() []WeatherReading {
+	return h.buf.Items()
+}
+
+// ndjsonFlushEvery is how many lines writeNDJSON writes between flushes.
+const ndjsonFlushEvery = 100
+
+// writeNDJSON streams items as newline-delimited JSON, one item per line.
+// When w is an http.Flusher it is flushed periodically so large exports reach
+// the client incrementally.
+func writeNDJSON[T any](w io.Writer, items []T) error {
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w) // Encode terminates each value with a newline
+	for i, item := range items {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+		if flusher != nil && (i+1)%ndjsonFlushEvery == 0 {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
+
+// historyNDJSONHandler serves the recorded readings as NDJSON.
+func historyNDJSONHandler(h *History, w http.ResponseWriter, req *http.Request) {
+	readings := h.Readings()
+	slog.Info("Exporting history", "readings", len(readings))
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	if err := writeNDJSON(w, readings); err != nil {
+		slog.Warn("Could not write NDJSON line", "error", err)
+	}
+}
+
+// WriteFile writes the recorded readings to path as NDJSON, replacing any
+// existing file, and returns how many readings were written.
+func (h *History) WriteFile(path string) (int, error) {
+	readings := h.Readings()
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	if err := writeNDJSON(f, readings); err != nil {
+		f.Close()
+		return 0, err
+	}
+	return len(readings), f.Close()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRingBufferKeepsNewest tests that the ring buffer evicts the oldest items.
+func TestRingBufferKeepsNewest(t *testing.T) {
+	b := newRingBuffer[int](3)
+	b.Add(1, 2)
+	b.Add(3, 4, 5)
+
+	items := b.Items()
+	if len(items) != 3 || items[0] != 3 || items[2] != 5 {
+		t.Errorf("Ring buffer returned unexpected items: got %v want [3 4 5]", items)
+	}
+
+	b.Reset()
+	if items := b.Items(); len(items) != 0 {
+		t.Errorf("Ring buffer is not empty after Reset: %v", items)
+	}
+}
+
+// TestHistoryNDJSONExport tests that served readings are exported as NDJSON.
+func TestHistoryNDJSONExport(t *testing.T) {
+	svc := &WeatherService{
+		Sleeper:    &NoOpSleeper{},
+		Chooser:    &FixedStatusChooser{Status: http.StatusOK},
+		RequestLog: NewRequestLog(10),
+		Metrics:    &Metrics{},
+		Health:     &HealthChecker{},
+		History:    NewHistory(15),
+	}
+	router := svc.Router()
+
+	// Two responses of 10 readings overflow a history of 15.
+	for i := 0; i < 2; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/weather?city=Lagos", nil))
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/weather/history.ndjson", nil))
+
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/x-ndjson" {
+		t.Errorf("History export returned wrong content type: got %v want %v", contentType, "application/x-ndjson")
+	}
+
+	lines := 0
+	scanner := bufio.NewScanner(rr.Body)
+	for scanner.Scan() {
+		var reading WeatherReading
+		if err := json.Unmarshal(scanner.Bytes(), &reading); err != nil {
+			t.Fatalf("Could not decode NDJSON line %d: %v", lines, err)
+		}
+		if reading.City != "Lagos" {
+			t.Errorf("History line %d has wrong city: got %v want %v", lines, reading.City, "Lagos")
+		}
+		lines++
+	}
+	if lines != 15 {
+		t.Errorf("History export returned unexpected number of lines: got %d want %d", lines, 15)
+	}
+}
+
+// TestHistoryWriteFile tests that the history is flushed to disk as NDJSON.
+func TestHistoryWriteFile(t *testing.T) {
+	h := NewHistory(10)
+	h.Add(generateDummyWeatherReadings(r, 4)...)
+
+	path := filepath.Join(t.TempDir(), "history.ndjson")
+	n, err := h.WriteFile(path)
+	if err != nil {
+		t.Fatalf("Could not write history: %v", err)
+	}
+	if n != 4 {
+		t.Errorf("WriteFile reported wrong number of readings: got %d want %d", n, 4)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Could not read history file: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 4 {
+		t.Errorf("History file has wrong number of lines: got %d want %d", lines, 4)
+	}
+}
+
+// TestRunShutdownFlushesTimeout tests that a stuck flush is abandoned at the
+// deadline along with the flushes after it.
+func TestRunShutdownFlushesTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	var ran []string
+	flushes := []shutdownFlush{
+		{Name: "first", Flush: func() (int, error) { ran = append(ran, "first"); return 1, nil }},
+		{Name: "stuck", Flush: func() (int, error) { <-release; return 0, nil }},
+		{Name: "last", Flush: func() (int, error) { ran = append(ran, "last"); return 1, nil }},
+	}
+
+	start := time.Now()
+	runShutdownFlushes(flushes, 50*time.Millisecond)
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Flushes took %v despite the timeout", elapsed)
+	}
+	if len(ran) != 1 || ran[0] != "first" {
+		t.Errorf("Unexpected flushes ran: got %v want [first]", ran)
+	}
+}
+
+// TestHistoryKeepsCelsius tests that the history records readings in Celsius
+// and without icons when the response is converted to Fahrenheit.
+func TestHistoryKeepsCelsius(t *testing.T) {
+	svc := newTestService(1, nil, &FixedStatusChooser{Status: http.StatusOK})
+	svc.History = NewHistory(100)
+	rr := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/weather?size=20&units=fahrenheit&icons=true", nil))
+
+	var responseData DataResponse
+	if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	history := svc.History.Readings()
+	if len(history) != len(responseData.Readings) {
+		t.Fatalf("History has %d readings, want %d", len(history), len(responseData.Readings))
+	}
+	for i, reading := range responseData.Readings {
+		if got := celsiusToFahrenheit(history[i].Temperature); got != reading.Temperature {
+			t.Errorf("History reading %d is %v°C (%v°F), response has %v°F", i, history[i].Temperature, got, reading.Temperature)
+		}
+		if history[i].Icon != "" {
+			t.Errorf("History reading %d has icon %q, want none", i, history[i].Icon)
+		}
+	}
+}
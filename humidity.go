@@ -0,0 +1,21 @@
+package main
+
+import "math/rand"
+
+// humidityPrecision option values. Humidity is a whole percentage unless
+// humidityFloat is requested.
+const (
+	humidityInt   = "int"
+	humidityFloat = "float"
+)
+
+// withFloatHumidity gives each reading's humidity a random tenth of a
+// percent, e.g. 64 becomes 64.3. Readings stay within 20.0-99.9%.
+func withFloatHumidity(views []readingView, readings []WeatherReading, r *rand.Rand) {
+	for i := range views {
+		// Dividing whole tenths keeps the value exact, e.g. 64.3 and not
+		// 64.30000000000001.
+		humidity := float64(readings[i].Humidity*10+r.Intn(10)) / 10
+		views[i].Humidity = &humidity
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// idempotencyMiddleware replays the original response to a POST request when
+// the same Idempotency-Key is sent again to the same path within the store's
+// TTL, instead of generating fresh data. Replays carry Idempotent-Replayed:
+// true. Requests without the header, and other methods, pass straight through.
+func idempotencyMiddleware(store *ResponseCache, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		idempotencyKey := req.Header.Get("Idempotency-Key")
+		if req.Method != http.MethodPost || idempotencyKey == "" {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		key := req.URL.Path + "\x00" + idempotencyKey
+		if entry, ok := store.get(key); ok {
+			slog.Info("Replaying idempotent response", "path", req.URL.Path, "key", idempotencyKey)
+			for name, values := range entry.header {
+				w.Header()[name] = slices.Clone(values)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.Header().Set("Content-Length", strconv.Itoa(len(entry.body)))
+			w.WriteHeader(entry.status)
+			w.Write(entry.body)
+			return
+		}
+
+		rec := &bodyRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, req)
+		// A request abandoned before the status was written has nothing to
+		// replay, and a partial body must not be replayed either.
+		if rec.status == 0 || req.Context().Err() != nil {
+			return
+		}
+		store.put(key, cachedResponse{
+			status: rec.status,
+			header: cacheableHeader(w.Header()),
+			body:   bytes.Clone(rec.body.Bytes()),
+		})
+	})
+}
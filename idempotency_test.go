@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestIdempotencyMiddleware tests that a repeated Idempotency-Key replays the
+// original response while new keys and GET requests reach the handler.
+func TestIdempotencyMiddleware(t *testing.T) {
+	store := &ResponseCache{TTL: time.Minute}
+	calls := 0
+	handler := idempotencyMiddleware(store, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"n":` + strconv.Itoa(calls) + `}`))
+	}))
+
+	send := func(method, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/weather", nil)
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	first := send("POST", "abc")
+	replay := send("POST", "abc")
+	if calls != 1 {
+		t.Errorf("Handler called %d times for a repeated key, want 1", calls)
+	}
+	if replay.Code != http.StatusCreated || replay.Body.String() != first.Body.String() {
+		t.Errorf("Replayed response differs: got %d %q want %d %q", replay.Code, replay.Body.String(), first.Code, first.Body.String())
+	}
+	if replay.Header().Get("Idempotent-Replayed") != "true" || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("Unexpected Idempotent-Replayed headers: %q then %q", first.Header().Get("Idempotent-Replayed"), replay.Header().Get("Idempotent-Replayed"))
+	}
+
+	send("POST", "def")
+	send("POST", "")
+	send("GET", "abc")
+	if calls != 4 {
+		t.Errorf("Handler called %d times for new keys, no key and GET, want 4", calls)
+	}
+}
+
+// TestIdempotencyAbandonedRequest tests that a request abandoned before
+// anything was written is not replayed.
+func TestIdempotencyAbandonedRequest(t *testing.T) {
+	store := &ResponseCache{TTL: time.Minute}
+	calls := 0
+	handler := idempotencyMiddleware(store, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls > 1 {
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+
+	for range 2 {
+		req := httptest.NewRequest("POST", "/weather", nil)
+		req.Header.Set("Idempotency-Key", "abc")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if calls != 2 {
+		t.Errorf("Handler called %d times, want 2", calls)
+	}
+}
+
+// TestIdempotencyStoreFull tests that a full store evicts its oldest key
+// rather than refusing new ones.
+func TestIdempotencyStoreFull(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := &ResponseCache{TTL: 24 * time.Hour, Now: func() time.Time { return now }}
+	for i := range maxCacheEntries {
+		store.put(strconv.Itoa(i), cachedResponse{status: http.StatusOK})
+		now = now.Add(time.Second)
+	}
+	store.put("new", cachedResponse{status: http.StatusOK})
+
+	if _, ok := store.get("new"); !ok {
+		t.Error("New key was not stored")
+	}
+	if _, ok := store.get("0"); ok {
+		t.Error("Oldest key was not evicted")
+	}
+	if _, ok := store.get("1"); !ok {
+		t.Error("Second oldest key was evicted")
+	}
+}
+
+// TestIdempotencyTrailingSlash tests that a retry differing only by a
+// trailing slash replays the original response.
+func TestIdempotencyTrailingSlash(t *testing.T) {
+	svc := newTestService(1, nil, &FixedStatusChooser{Status: http.StatusOK})
+	svc.Idempotency = &ResponseCache{TTL: time.Minute}
+	handler := svc.Handler()
+
+	send := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", target, strings.NewReader(`{"size":10}`))
+		req.Header.Set("Idempotency-Key", "abc")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	first := send("/weather")
+	retry := send("/weather/")
+	if retry.Header().Get("Idempotent-Replayed") != "true" || retry.Body.String() != first.Body.String() {
+		t.Errorf("Retry with a trailing slash was not replayed: Idempotent-Replayed %q, body %q want %q",
+			retry.Header().Get("Idempotent-Replayed"), retry.Body.String(), first.Body.String())
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RouteInfo describes a registered route in the root index.
+type RouteInfo struct {
+	Method string `json:"method,omitempty"` // Empty when any method is accepted
+	Path   string `json:"path"`
+}
+
+// IndexResponse is the response of GET /, listing every registered route.
+type IndexResponse struct {
+	Service   string      `json:"service"`
+	Version   string      `json:"version"`
+	Endpoints []RouteInfo `json:"endpoints"`
+}
+
+// routeMux is a ServeMux that remembers the patterns registered on it, so
+// the root index is generated from the actual routes.
+type routeMux struct {
+	*http.ServeMux
+	routes []RouteInfo
+}
+
+// Handle registers h for pattern and records the route.
+func (m *routeMux) Handle(pattern string, h http.Handler) {
+	m.ServeMux.Handle(pattern, h)
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		method, path = "", pattern
+	}
+	m.routes = append(m.routes, RouteInfo{Method: method, Path: path})
+}
+
+// indexHandler serves the root index of routes as JSON.
+func indexHandler(routes []RouteInfo, w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, http.StatusOK, IndexResponse{Service: "go-weather", Version: buildVersion(), Endpoints: routes})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestServer starts the full server handler with no delays and a fixed status code.
+func newTestServer(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+	svc := &WeatherService{
+		Sleeper:    &NoOpSleeper{},
+		Chooser:    &FixedStatusChooser{Status: status},
+		RequestLog: NewRequestLog(100),
+		Metrics:    &Metrics{},
+		Health:     &HealthChecker{},
+	}
+	server := httptest.NewServer(svc.Handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestIntegrationWeatherSuccess tests /weather end to end with a forced 200.
+func TestIntegrationWeatherSuccess(t *testing.T) {
+	server := newTestServer(t, http.StatusOK)
+
+	for _, path := range []string{"/weather?size=25", "/v1/weather?size=25"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s returned wrong status code: got %v want %v", path, resp.StatusCode, http.StatusOK)
+		}
+		if resp.Header.Get("X-Request-ID") == "" {
+			t.Errorf("GET %s is missing the X-Request-ID header set by the middleware.", path)
+		}
+
+		var responseData DataResponse
+		if err := json.NewDecoder(resp.Body).Decode(&responseData); err != nil {
+			t.Fatalf("Could not decode response for %s: %v", path, err)
+		}
+		if len(responseData.Readings) != 25 {
+			t.Errorf("GET %s returned unexpected number of readings: got %d want %d", path, len(responseData.Readings), 25)
+		}
+	}
+}
+
+// TestIntegrationWeatherError tests /weather end to end with a forced 503.
+func TestIntegrationWeatherError(t *testing.T) {
+	server := newTestServer(t, http.StatusServiceUnavailable)
+
+	resp, err := http.Get(server.URL + "/weather")
+	if err != nil {
+		t.Fatalf("GET /weather failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("GET /weather returned wrong status code: got %v want %v", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+
+	var responseData DataResponse
+	if err := json.NewDecoder(resp.Body).Decode(&responseData); err != nil {
+		t.Fatalf("Could not decode error response: %v", err)
+	}
+	if len(responseData.Readings) > 0 || responseData.Message == "" {
+		t.Errorf("Error response has unexpected shape: %+v", responseData)
+	}
+}
+
+// TestIntegrationRequestLog tests that requests through the server appear on /debug/requests.
+func TestIntegrationRequestLog(t *testing.T) {
+	server := newTestServer(t, http.StatusOK)
+
+	resp, err := http.Get(server.URL + "/health")
+	if err != nil {
+		t.Fatalf("GET /health failed: %v", err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(server.URL + "/debug/requests")
+	if err != nil {
+		t.Fatalf("GET /debug/requests failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var entries []RequestLogEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		t.Fatalf("Could not decode request log: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Path != "/health" {
+		t.Errorf("Request log has unexpected entries: %+v", entries)
+	}
+}
+
+// TestIntegrationContentLength tests that normal responses carry a Content-Length
+// while keep-alive responses stay chunked.
+func TestIntegrationContentLength(t *testing.T) {
+	server := newTestServer(t, http.StatusOK)
+
+	resp, err := http.Get(server.URL + "/weather")
+	if err != nil {
+		t.Fatalf("GET /weather failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.ContentLength <= 0 || len(resp.TransferEncoding) > 0 {
+		t.Errorf("Expected an explicit Content-Length, got %d with transfer encoding %v", resp.ContentLength, resp.TransferEncoding)
+	}
+
+	resp, err = http.Get(server.URL + "/weather?keepAlive=true&minDelay=1000&maxDelay=1000")
+	if err != nil {
+		t.Fatalf("GET /weather?keepAlive=true failed: %v", err)
+	}
+	resp.Body.Close()
+	if len(resp.TransferEncoding) == 0 || resp.TransferEncoding[0] != "chunked" {
+		t.Errorf("Expected a chunked keep-alive response, got transfer encoding %v", resp.TransferEncoding)
+	}
+}
+
+// TestIntegrationH2C tests that with WEATHER_H2C a prior-knowledge HTTP/2
+// client is served over cleartext by the unchanged handlers, while HTTP/1
+// clients still work.
+func TestIntegrationH2C(t *testing.T) {
+	server := httptest.NewUnstartedServer(newTestService(1, nil, nil).Handler())
+	server.Config.Protocols = serverProtocols(Config{H2C: true})
+	server.Start()
+	t.Cleanup(server.Close)
+
+	h2c := new(http.Protocols)
+	h2c.SetUnencryptedHTTP2(true)
+	clients := map[int]*http.Client{
+		1: http.DefaultClient,
+		2: {Transport: &http.Transport{Protocols: h2c}},
+	}
+	for major, client := range clients {
+		resp, err := client.Get(server.URL + "/weather?size=20")
+		if err != nil {
+			t.Fatalf("HTTP/%d request failed: %v", major, err)
+		}
+		var responseData DataResponse
+		err = json.NewDecoder(resp.Body).Decode(&responseData)
+		resp.Body.Close()
+		if resp.ProtoMajor != major {
+			t.Errorf("Request was served over HTTP/%d, want HTTP/%d", resp.ProtoMajor, major)
+		}
+		if err != nil || len(responseData.Readings) != 20 {
+			t.Errorf("HTTP/%d returned %d readings (%v), want 20", major, len(responseData.Readings), err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// defaultLanguage is the language conditions are generated in.
+const defaultLanguage = "en"
+
+// conditionTranslations maps each supported language other than English to
+// its translation of every condition.
+var conditionTranslations = map[string]map[string]string{
+	"de": {
+		"Sunny":         "Sonnig",
+		"Partly Cloudy": "Teilweise bewölkt",
+		"Cloudy":        "Bewölkt",
+		"Rainy":         "Regnerisch",
+		"Stormy":        "Stürmisch",
+		"Foggy":         "Neblig",
+		"Snowy":         "Verschneit",
+	},
+	"es": {
+		"Sunny":         "Soleado",
+		"Partly Cloudy": "Parcialmente nublado",
+		"Cloudy":        "Nublado",
+		"Rainy":         "Lluvioso",
+		"Stormy":        "Tormentoso",
+		"Foggy":         "Neblinoso",
+		"Snowy":         "Nevado",
+	},
+	"fr": {
+		"Sunny":         "Ensoleillé",
+		"Partly Cloudy": "Partiellement nuageux",
+		"Cloudy":        "Nuageux",
+		"Rainy":         "Pluvieux",
+		"Stormy":        "Orageux",
+		"Foggy":         "Brumeux",
+		"Snowy":         "Neigeux",
+	},
+	"ja": {
+		"Sunny":         "晴れ",
+		"Partly Cloudy": "晴れ時々曇り",
+		"Cloudy":        "曇り",
+		"Rainy":         "雨",
+		"Stormy":        "嵐",
+		"Foggy":         "霧",
+		"Snowy":         "雪",
+	},
+}
+
+// negotiateLanguage returns the supported language an Accept-Language header
+// prefers most, matching on the primary subtag so "fr-CA" selects "fr". It
+// falls back to English when the header is empty, malformed or names no
+// supported language.
+func negotiateLanguage(header string) string {
+	best, bestQ := defaultLanguage, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		primary, _, _ := strings.Cut(tag, "-")
+		if primary == "*" {
+			primary = defaultLanguage
+		}
+		if _, ok := conditionTranslations[primary]; !ok && primary != defaultLanguage {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		// The first of equally preferred languages wins.
+		if q > bestQ {
+			best, bestQ = primary, q
+		}
+	}
+	return best
+}
+
+// translateCondition returns condition in lang, or unchanged when lang is
+// English or unsupported.
+func translateCondition(lang, condition string) string {
+	if translated, ok := conditionTranslations[lang][condition]; ok {
+		return translated
+	}
+	return condition
+}
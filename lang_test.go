@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// TestNegotiateLanguage tests Accept-Language negotiation and its fallbacks.
+func TestNegotiateLanguage(t *testing.T) {
+	testCases := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"fr", "fr"},
+		{"fr-CA, en;q=0.8", "fr"},
+		{"en-US,en;q=0.9,de;q=0.8", "en"},
+		{"nl, de;q=0.5", "de"},
+		{"ja;q=0.2, es;q=0.7", "es"},
+		{"fr;q=0, es;q=0.1", "es"},
+		{"nl, pt", "en"},
+		{"*", "en"},
+		{"de;q=abc", "en"},
+	}
+	for _, tc := range testCases {
+		if got := negotiateLanguage(tc.header); got != tc.want {
+			t.Errorf("negotiateLanguage(%q) = %q, want %q", tc.header, got, tc.want)
+		}
+	}
+}
+
+// TestWeatherHandlerAcceptLanguage tests that conditions are translated into
+// the negotiated language while the history keeps them in English.
+func TestWeatherHandlerAcceptLanguage(t *testing.T) {
+	svc := newTestService(2, nil, nil)
+	svc.History = NewHistory(100)
+
+	req := httptest.NewRequest("GET", "/weather?size=50", nil)
+	req.Header.Set("Accept-Language", "fr-FR, en;q=0.5")
+	rr := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("Content-Language"); got != "fr" {
+		t.Errorf("Handler returned wrong Content-Language: got %q want %q", got, "fr")
+	}
+	var responseData DataResponse
+	if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	for i, reading := range responseData.Readings {
+		english := svc.History.Readings()[i].Condition
+		if !slices.Contains(conditions, english) {
+			t.Errorf("History kept condition %q, want an English condition", english)
+		}
+		if want := conditionTranslations["fr"][english]; reading.Condition != want {
+			t.Errorf("Reading has condition %q, want %q", reading.Condition, want)
+		}
+	}
+
+	// Every supported language translates every condition.
+	for lang, translations := range conditionTranslations {
+		for _, condition := range conditions {
+			if translations[condition] == "" {
+				t.Errorf("Missing %s translation of %q", lang, condition)
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// parseLogLevel converts a WEATHER_LOG_LEVEL value into a slog.Level.
+// An empty value means info, which keeps the per-request log lines.
+func parseLogLevel(value string) (slog.Level, error) {
+	switch strings.ToLower(value) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error", "quiet":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, errorOf(ErrBadConfig, "unknown log level %q", value)
+	}
+}
+
+// setupLogging installs a default slog logger filtered by WEATHER_LOG_LEVEL.
+func setupLogging() {
+	level, err := parseLogLevel(os.Getenv("WEATHER_LOG_LEVEL"))
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+	if err != nil {
+		slog.Warn("Invalid WEATHER_LOG_LEVEL, defaulting to info", "error", err)
+	}
+}
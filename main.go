@@ -1,146 +1,939 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"log/slog"
 	"math/rand"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // WeatherReading represents a single dummy weather data record.
 type WeatherReading struct {
+	ID          string    `json:"id,omitempty"` // Stable ID derived from the reading, see readingID
 	City        string    `json:"city"`
+	StationID   string    `json:"station_id,omitempty"` // Sensor station within the city, see pickStation
 	Timestamp   time.Time `json:"timestamp"`
 	Temperature float64   `json:"temperature"` // Celsius
 	Humidity    int       `json:"humidity"`    // Percentage
 	Condition   string    `json:"condition"`
+	Anomaly     bool      `json:"anomaly,omitempty"` // Temperature was injected out of band
+	Icon        string    `json:"icon,omitempty"`    // OpenWeatherMap icon code, when requested
 }
 
 // DataResponse holds the array of weather readings.
 type DataResponse struct {
 	Readings []WeatherReading `json:"readings"`
+	Units    string           `json:"units,omitempty"`   // Temperature units of the readings
 	Message  string           `json:"message,omitempty"` // Added for error messages
+	// GeneratedAt is when the server produced the response, in UTC, after any
+	// injected delay. It is omitted from responses rejected before generation.
+	GeneratedAt time.Time `json:"generated_at,omitzero"`
+	// RequestHeaders echoes the request headers when echoHeaders is set.
+	RequestHeaders map[string][]string `json:"request_headers,omitempty"`
+	// Source and Attribution credit the data provider when attribution is set.
+	Source      string `json:"source,omitempty"`
+	Attribution string `json:"attribution,omitempty"`
+	// Padding grows with every response when WEATHER_GROWTH_BYTES is set.
+	Padding string `json:"padding,omitempty"`
+}
+
+const (
+	// dataSource is the Source of every response: the readings are made up.
+	dataSource = "simulated"
+	// defaultAttribution is the Attribution unless WEATHER_ATTRIBUTION is set.
+	defaultAttribution = "Weather data simulated by go-weather"
+)
+
+const (
+	// defaultMaxDelayMs is the upper bound of the random delay when maxDelay
+	// isn't given, unless WEATHER_DEFAULT_MAX_DELAY_MS is set.
+	defaultMaxDelayMs = 5000
+	// maxDelayMs is the largest delay a client may request, unless
+	// WEATHER_MAX_DELAY_MS lowers it, and the cap of every configured delay.
+	maxDelayMs = 60000
+)
+
+const (
+	// defaultSize is the number of readings when size isn't given, unless
+	// WEATHER_DEFAULT_SIZE is set.
+	defaultSize = 10
+	// minSize and maxSize bound size unless WEATHER_MIN_SIZE and
+	// WEATHER_MAX_SIZE are set.
+	minSize = 10
+	maxSize = 100
+	// sizeCeiling is the largest WEATHER_MAX_SIZE.
+	sizeCeiling = 1000
+)
+
+// keepAliveInterval is how often a keep-alive byte is flushed during the delay.
+const keepAliveInterval = time.Second
+
+const (
+	// defaultShortBodyBytes is how many bytes shortBody responses are missing
+	// unless WEATHER_SHORT_BODY_BYTES is set.
+	defaultShortBodyBytes = 1024
+	// maxShortBodyBytes is the largest WEATHER_SHORT_BODY_BYTES.
+	maxShortBodyBytes = 1 << 20
+	// shortBodyHold is the longest shortBody responses keep the client waiting.
+	shortBodyHold = maxDelayMs * time.Millisecond
+)
+
+// streamArray is the stream option value that streams the readings as a bare
+// JSON array, element by element.
+const streamArray = "array"
+
+// bodyDelayChunks is how many chunks bodyDelay splits the body into, pausing
+// before each one.
+const bodyDelayChunks = 4
+
+// allowedContentTypes lists the values accepted by the contentType override.
+// The body is always JSON; only the header changes.
+var allowedContentTypes = map[string]bool{
+	"application/json":         true,
+	"text/plain":               true,
+	"text/html":                true,
+	"application/xml":          true,
+	"application/octet-stream": true,
+}
+
+// cities lists the cities readings are generated for.
+var cities = []string{"New York", "London", "Paris", "Tokyo", "Sydney", "Lagos", "Dubai", "Rio"}
+
+// conditions lists the weather conditions a reading can have.
+var conditions = []string{"Sunny", "Partly Cloudy", "Cloudy", "Rainy", "Stormy", "Foggy", "Snowy"}
+
+// conditionIcons maps each condition to its OpenWeatherMap daytime icon code.
+var conditionIcons = map[string]string{
+	"Sunny":         "01d",
+	"Partly Cloudy": "02d",
+	"Cloudy":        "04d",
+	"Rainy":         "10d",
+	"Stormy":        "11d",
+	"Foggy":         "50d",
+	"Snowy":         "13d",
+}
+
+// lookupCity returns the canonical name of a known city, matching case-insensitively.
+func lookupCity(name string) (string, bool) {
+	for _, city := range cities {
+		if strings.EqualFold(city, name) {
+			return city, true
+		}
+	}
+	return "", false
 }
 
 // Global random source for generating values and status codes.
+// It is safe for concurrent use; request-local sources are not.
 var r *rand.Rand
 
 func init() {
-	// Initialize a new Rand source with the current time for better randomness.
-	s := rand.NewSource(time.Now().UnixNano())
-	r = rand.New(s)
+	// The shared source is randomly seeded until configured otherwise.
+	r = rand.New(&sharedSource{})
 }
 
-// generateDummyWeatherReadings generates a slice of dummy WeatherReading objects.
-func generateDummyWeatherReadings(count int) []WeatherReading {
-	readings := make([]WeatherReading, count)
-	cities := []string{"New York", "London", "Paris", "Tokyo", "Sydney", "Lagos", "Dubai", "Rio"}
-	conditions := []string{"Sunny", "Partly Cloudy", "Cloudy", "Rainy", "Stormy", "Foggy", "Snowy"}
+// generateDummyWeatherReadings generates a slice of dummy WeatherReading objects
+// using the given random source.
+func generateDummyWeatherReadings(r *rand.Rand, count int) []WeatherReading {
+	return appendDummyWeatherReadings(make([]WeatherReading, 0, count), r, count, time.Now())
+}
 
+// appendDummyWeatherReadings appends count dummy readings, with timestamps
+// within 12 hours of now, to dst and returns the extended slice, reusing dst's
+// capacity when possible.
+func appendDummyWeatherReadings(dst []WeatherReading, r *rand.Rand, count int, now time.Time) []WeatherReading {
+	return appendWeightedReadings(dst, r, count, now, nil)
+}
+
+// appendWeightedReadings is appendDummyWeatherReadings with conditions picked
+// by weights, such as a season's, or the configured weights when nil.
+func appendWeightedReadings(dst []WeatherReading, r *rand.Rand, count int, now time.Time, weights []int) []WeatherReading {
 	for i := 0; i < count; i++ {
-		readings[i] = WeatherReading{
+		reading := WeatherReading{
 			City:        cities[r.Intn(len(cities))],
-			Timestamp:   time.Now().Add(time.Duration(r.Intn(24)-12) * time.Hour), // Simulate readings +/- 12 hours
-			Temperature: float64(r.Intn(35)+5) + r.Float64(),                      // 5.0 to 40.0 Celsius
-			Humidity:    r.Intn(80) + 20,                                          // 20% to 99%
-			Condition:   conditions[r.Intn(len(conditions))],
+			Timestamp:   now.Add(time.Duration(r.Intn(24)-12) * time.Hour), // Simulate readings +/- 12 hours
+			Temperature: float64(r.Intn(35)+5) + r.Float64(),               // 5.0 to 40.0 Celsius
+			Humidity:    r.Intn(80) + 20,                                   // 20% to 99%
+			Condition:   pickConditionWeighted(r, weights),
 		}
+		reading.ID = readingID(reading)
+		dst = append(dst, reading)
 	}
-	return readings
+	return dst
 }
 
-// getResponseStatusCode randomly selects a 2xx, 4xx, or 5xx status code.
-func getResponseStatusCode() int {
-	statusCodes2xx := []int{http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent}
-	statusCodes4xx := []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusForbidden, http.StatusMethodNotAllowed}
-	statusCodes5xx := []int{http.StatusInternalServerError, http.StatusNotImplemented, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+// injectAnomalies replaces the temperature of each reading, with probability
+// rate, by an extreme value outside the normal 5-40°C range, and marks it.
+// Half of the anomalies are heat (60-70°C) and half cold (-40 to -50°C).
+func injectAnomalies(readings []WeatherReading, r *rand.Rand, rate float64) {
+	if rate <= 0 {
+		return
+	}
+	for i := range readings {
+		if r.Float64() >= rate {
+			continue
+		}
+		if r.Intn(2) == 0 {
+			readings[i].Temperature = 60 + r.Float64()*10
+		} else {
+			readings[i].Temperature = -40 - r.Float64()*10
+		}
+		readings[i].Anomaly = true
+	}
+}
 
-	// Randomly decide the type of response: 2xx, 4xx, or 5xx
-	// Adjust weights if needed, e.g., 70% 2xx, 15% 4xx, 15% 5xx
-	randomNumber := r.Intn(100) // 0-99
-	if randomNumber < 70 {      // 70% chance for 2xx
-		return statusCodes2xx[r.Intn(len(statusCodes2xx))]
-	} else if randomNumber < 85 { // 15% chance for 4xx (80-89)
-		return statusCodes4xx[r.Intn(len(statusCodes4xx))]
-	} else { // 15% chance for 5xx (90-99)
-		return statusCodes5xx[r.Intn(len(statusCodes5xx))]
+// injectDuplicates replaces each reading after the first, with probability
+// rate, by an exact copy of an earlier reading in the batch.
+func injectDuplicates(readings []WeatherReading, r *rand.Rand, rate float64) {
+	if rate <= 0 {
+		return
 	}
+	for i := 1; i < len(readings); i++ {
+		if r.Float64() < rate {
+			readings[i] = readings[r.Intn(i)]
+		}
+	}
+}
+
+// readingsPool recycles reading buffers between /weather requests. At size=100
+// this removes the 13.5 KB slice allocation per call (BenchmarkGenerateReadings:
+// 101 allocs/op, 18368 B/op; BenchmarkGenerateReadingsPooled: 100 allocs/op,
+// 4800 B/op, which are the reading ID strings), which matters for GC pressure
+// at high RPS. Calling time.Now once per batch rather than per reading also
+// cut generation time by roughly a third.
+var readingsPool = sync.Pool{
+	New: func() any { return new([]WeatherReading) },
+}
+
+// getResponseStatusCode randomly selects a 2xx, 4xx, or 5xx status code
+// using the given random source, weighting the classes by the configured
+// status weights (70% 2xx, 15% 4xx and 15% 5xx by default).
+func getResponseStatusCode(r *rand.Rand) int {
+	statusCodes := [][]int{
+		{http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent},
+		{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusForbidden, http.StatusMethodNotAllowed},
+		{http.StatusInternalServerError, http.StatusNotImplemented, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	}
+	weights := currentSettings().StatusWeights
+	if weights == nil {
+		weights = defaultStatusWeights
+	}
+	class := statusCodes[weightedIndex(r, weights)]
+	return class[r.Intn(len(class))]
 }
 
 // weatherHandler handles requests to the /weather endpoint.
-// It now takes a Sleeper interface for dependency injection.
-func weatherHandler(s Sleeper, w http.ResponseWriter, req *http.Request) {
-	// Set Content-Type header to application/json
-	w.Header().Set("Content-Type", "application/json")
+// It takes Sleeper and StatusChooser interfaces for dependency injection and
+// uses no other service state, such as the history.
+func weatherHandler(s Sleeper, c StatusChooser, w http.ResponseWriter, req *http.Request) {
+	svc := &WeatherService{Sleeper: s, Chooser: c}
+	svc.weather(w, req)
+}
 
-	// Get response size from query parameter, default to 10 if not provided or invalid.
-	sizeStr := req.URL.Query().Get("size")
-	size, err := strconv.Atoi(sizeStr)
-	if err != nil || size < 10 || size > 100 {
-		log.Printf("Invalid or missing 'size' parameter, defaulting to 10. Received: %s", sizeStr)
-		size = 10 // Default size
+// weather handles requests to the /weather endpoint.
+// GET reads options from the query string; POST reads them from a JSON body.
+func (svc *WeatherService) weather(w http.ResponseWriter, req *http.Request) {
+	var opts WeatherOptions
+	if req.Method == http.MethodPost {
+		if err := json.NewDecoder(req.Body).Decode(&opts); err != nil {
+			status, message := requestBodyError(err)
+			w.Header().Set("Content-Type", "application/json")
+			writeJSON(w, status, DataResponse{Message: message})
+			return
+		}
+	} else {
+		var err error
+		if opts, err = parseWeatherQuery(req.URL.Query()); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			writeJSON(w, statusForError(err), DataResponse{Message: fmt.Sprintf("Invalid request: %v", err)})
+			return
+		}
 	}
 
-	// Introduce a random delay between 0 and 5 seconds using the injected Sleeper.
-	delay := time.Duration(r.Intn(5001)) * time.Millisecond // 0 to 5000 milliseconds
-	log.Printf("Introducing a delay of %v for this request.", delay)
-	s.Sleep(delay) // Use the injected sleeper
+	// /weather/{city} pins the city from the path.
+	if name := req.PathValue("city"); name != "" {
+		city, ok := lookupCity(name)
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			writeJSON(w, http.StatusNotFound, DataResponse{Message: fmt.Sprintf("Unknown city %q", name)})
+			return
+		}
+		opts.City = city
+	}
 
-	// Get a random status code
-	statusCode := getResponseStatusCode()
-	log.Printf("Responding with status code: %d", statusCode)
+	// X-Chaos combines faults for this request only.
+	if value := req.Header.Get(chaosHeader); value != "" {
+		if err := applyChaosHeader(value, &opts); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			writeJSON(w, statusForError(err), DataResponse{Message: fmt.Sprintf("Invalid request: %v", err)})
+			return
+		}
+		slog.Info("Fault injection: applying X-Chaos header", "chaos", value)
+	}
 
-	w.WriteHeader(statusCode)
+	// The Prefer header can set the delay and status when the request doesn't.
+	if applied := applyPreferHeader(req.Header, &opts); len(applied) > 0 {
+		w.Header().Set("Preference-Applied", strings.Join(applied, ", "))
+	}
+	w.Header().Add("Vary", "Prefer")
+
+	p, err := resolveWeatherOptions(opts)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, statusForError(err), DataResponse{Message: fmt.Sprintf("Invalid request: %v", err)})
+		return
+	}
+	// GET requests can ask for a slice of the readings with a Range header.
+	if req.Method == http.MethodGet {
+		w.Header().Set("Accept-Ranges", rangeUnit)
+		if ir, ok := parseItemRange(req.Header.Get("Range")); ok {
+			p.itemRange = &ir
+		}
+	}
+	// Decompression bombs need to be enabled on the server too.
+	if p.bombBytes > 0 && !svc.DecompressBomb {
+		slog.Warn("Ignoring 'decompressBomb' because WEATHER_DECOMPRESS_BOMB is not enabled")
+		p.bombBytes = 0
+	}
+	// Echoing headers shows what reached the server, so it needs debug mode.
+	if opts.EchoHeaders {
+		if svc.Debug {
+			p.requestHeaders = echoedHeaders(req)
+		} else {
+			slog.Warn("Ignoring 'echoHeaders' because WEATHER_DEBUG is not enabled")
+		}
+	}
+	// Conditions are translated into the language the client prefers.
+	w.Header().Add("Vary", "Accept-Language")
+	if header := req.Header.Get("Accept-Language"); header != "" {
+		p.lang = negotiateLanguage(header)
+		w.Header().Set("Content-Language", p.lang)
+	}
+	if p.city != "" && svc.Availability.Offline(p.city) {
+		slog.Info("City is offline", "city", p.city)
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, http.StatusServiceUnavailable, DataResponse{Message: fmt.Sprintf("City %s is offline for scheduled maintenance", p.city)})
+		return
+	}
+	svc.serveWeather(req.Context(), w, p)
+}
+
+// serveWeather sleeps, chooses a status and writes the /weather response for
+// already-validated parameters.
+func (svc *WeatherService) serveWeather(ctx context.Context, w http.ResponseWriter, p weatherParams) {
+	// Set Content-Type header to application/json, unless an allowed override was requested.
+	w.Header().Set("Content-Type", p.contentType)
+	// These faults are in the framing of the body, which compression replaces.
+	if p.keepAlive || p.truncate || p.shortBody || p.bodyDelay > 0 {
+		skipCompression(w)
+	}
+
+	// Use a request-local random source when a seed is given, so this response
+	// is reproducible without disturbing the shared source.
+	rng := svc.rand()
+	if p.seed != nil {
+		rng = rand.New(rand.NewSource(*p.seed))
+	}
+
+	// Use the configured fixed delay if set, then a delay scaled with the
+	// requested size; otherwise introduce a random delay in [minDelay, maxDelay]
+	// milliseconds using the injected Sleeper.
+	current := currentSettings()
+	delay := current.FixedDelay
+	if delay < 0 {
+		if scaled, ok := current.sizeDelay(p.size); ok {
+			delay = scaled
+		} else {
+			delay = time.Duration(p.minDelay+rng.Intn(p.maxDelay-p.minDelay+1)) * time.Millisecond
+		}
+	}
+
+	// Get a status code from the injected chooser, unless one was requested.
+	// A requested status still goes through the chooser, so a simulated
+	// outage or open circuit overrides it.
+	statusCode := p.status
+	if p.softError {
+		statusCode = http.StatusOK
+	} else if statusCode == 0 {
+		statusCode = svc.Chooser.ChooseStatus(rng)
+	} else {
+		statusCode = chooseRequestedStatus(svc.Chooser, rng, statusCode)
+	}
+	slog.Info("Responding with status code", "status", statusCode)
+
+	// Server errors can take their own delay, like an overloaded upstream
+	// that times out instead of failing fast.
+	if statusCode >= 500 && current.ErrorDelay > 0 {
+		delay = current.ErrorDelay
+	}
+	if extra := svc.Warmup.ExtraDelay(); extra > 0 {
+		slog.Info("Adding warmup delay", "extra", extra)
+		delay += extra
+	}
+	if p.cpu > 0 {
+		// The burn replaces every configured delay.
+		delay = p.cpu
+		slog.Info("Burning CPU instead of sleeping for this request", "delay", delay)
+	} else {
+		slog.Info("Introducing a delay for this request", "delay", delay)
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int64("weather.delay_ms", delay.Milliseconds()),
+		attribute.Int("weather.status", statusCode),
+		attribute.Int("weather.size", p.size),
+	)
+
+	if p.cpu > 0 {
+		if err := burnCPU(ctx, delay); err != nil {
+			slog.Info("Client went away during the CPU burn", "error", err)
+			return
+		}
+	} else if p.keepAlive {
+		// The status line has to go out before the first keep-alive byte.
+		w.WriteHeader(statusCode)
+		sleepWithKeepAlive(svc.Sleeper, w, delay)
+	} else if err := sleepContext(ctx, svc.Sleeper, delay); err != nil {
+		// Only context-aware sleepers stop early, once nobody is waiting.
+		slog.Info("Client went away during the delay", "error", err)
+		return
+	}
+
+	// The response is produced now, after the delay, unless at pins the time
+	// so that the whole response is reproducible.
+	generatedAt := p.at
+	if generatedAt.IsZero() {
+		generatedAt = svc.now()
+		if svc.SkewTimestamps {
+			generatedAt = generatedAt.Add(svc.ClockSkew)
+		}
+	}
 
 	var responseData DataResponse
 
 	// Depending on the status code, provide appropriate response body
-	if statusCode >= 200 && statusCode < 300 {
-		readings := generateDummyWeatherReadings(size)
+	if p.softError {
+		// A 200 whose body reports a failure, like a misbehaving upstream.
 		responseData = DataResponse{
-			Readings: readings,
-			Message:  fmt.Sprintf("Successfully retrieved %d weather readings.", len(readings)),
+			Readings: []WeatherReading{},
+			Message:  "Error: could not retrieve weather readings. This is a dummy error returned with status 200 for testing.",
+		}
+		slog.Info("Fault injection: responding with a soft error", "status", statusCode)
+	} else if statusCode >= 200 && statusCode < 300 {
+		// Borrow a buffer from the pool; it is returned once the response is written.
+		buf := readingsPool.Get().(*[]WeatherReading)
+		defer func() {
+			*buf = (*buf)[:0]
+			readingsPool.Put(buf)
+		}()
+		readings := appendWeightedReadings((*buf)[:0], rng, p.size, generatedAt, svc.seasonWeights(p.season))
+		// A pinned city replaces the generated ones before anything, the ID
+		// included, is derived from them.
+		if p.city != "" {
+			for i := range readings {
+				readings[i].City = p.city
+				readings[i].ID = readingID(readings[i])
+			}
+		}
+		// Spread readings only over online cities, so moving the offline
+		// ones afterwards can't bring a city back twice.
+		if p.minCities > 0 {
+			spreadCities(readings, rng, svc.Availability.onlineCities(), p.minCities+rng.Intn(p.maxCities-p.minCities+1))
+		}
+		readings = svc.Availability.omitOffline(readings, rng)
+		// Stations belong to the final city of each reading.
+		for i := range readings {
+			readings[i].StationID = pickStation(rng, readings[i].City, svc.StationsPerCity)
+		}
+		*buf = readings
+		injectAnomalies(readings, rng, p.anomalyRate)
+		injectDuplicates(readings, rng, p.dupRate)
+		// Keep-alive responses have already sent their status line.
+		if p.itemRange != nil && statusCode == http.StatusOK && !p.keepAlive {
+			readings, statusCode = applyItemRange(w.Header(), *p.itemRange, readings)
+		}
+		if statusCode == http.StatusRequestedRangeNotSatisfiable {
+			responseData = DataResponse{Message: "Requested range not satisfiable."}
+		} else {
+			// The history copies the readings in Celsius and without icons,
+			// whatever the request asked for.
+			if svc.History != nil {
+				svc.History.Add(readings...)
+			}
+			for i := range readings {
+				if p.units == unitsFahrenheit {
+					readings[i].Temperature = celsiusToFahrenheit(readings[i].Temperature)
+				}
+				if p.icons {
+					readings[i].Icon = conditionIcons[readings[i].Condition]
+				}
+			}
+			// The history keeps English conditions. OpenWeatherMap output
+			// maps them to its own codes, so it stays in English too.
+			if p.compat == "" {
+				for i := range readings {
+					readings[i].Condition = translateCondition(p.lang, readings[i].Condition)
+				}
+			}
+			responseData = DataResponse{
+				Readings: readings,
+				Units:    p.units,
+				Message:  fmt.Sprintf("Successfully retrieved %d weather readings.", len(readings)),
+			}
+			slog.Info("Responding with weather readings", "status", statusCode, "readings", len(readings))
 		}
-		log.Printf("Responding with %d status code and %d weather readings.", statusCode, len(readings))
 	} else {
 		// For 4xx and 5xx errors, provide a generic error message.
 		errorMessage := fmt.Sprintf("An error occurred with status code %d. This is a dummy error for testing.", statusCode)
 		responseData = DataResponse{
 			Message: errorMessage,
 		}
-		log.Printf("Responding with %d status code and error message: %s", statusCode, errorMessage)
+		slog.Info("Responding with error message", "status", statusCode, "message", errorMessage)
+	}
+
+	responseData.GeneratedAt = generatedAt.UTC()
+	responseData.RequestHeaders = p.requestHeaders
+	responseData.Padding = svc.Growth.Padding()
+	if p.attribution {
+		responseData.Source = dataSource
+		responseData.Attribution = svc.Attribution
+	}
+	svc.Stats.Record(statusCode)
+
+	var body any = responseData
+	if p.compat == compatOWM {
+		body = owmResponse(statusCode, responseData)
+	} else if (p.groupBy != "" || p.omitFields || p.floatHumidity) && responseData.Readings != nil {
+		if p.omitFields {
+			slog.Info("Fault injection: omitting required reading fields", "status", statusCode)
+		}
+		body = reshapeReadings(responseData, p, rng)
+	}
+
+	if p.bombBytes > 0 {
+		slog.Warn("Fault injection: sending a decompression bomb", "status", statusCode, "decompressedBytes", p.bombBytes)
+		writeDecompressBomb(w, statusCode, body, p.bombBytes)
+		return
+	}
+
+	// Only the readings are streamed; errors keep their message object.
+	if p.stream == streamArray && responseData.Readings != nil && statusCode != http.StatusNoContent {
+		slog.Info("Streaming readings as a JSON array", "status", statusCode, "readings", len(responseData.Readings))
+		if err := writeJSONArray(w, statusCode, responseData.Readings); err != nil {
+			slog.Info("Could not stream the readings", "error", err)
+		}
+		return
 	}
 
-	// Encode and send the JSON response
-	json.NewEncoder(w).Encode(responseData)
+	// Keep-alive responses are already streaming, so encode straight to w.
+	if p.keepAlive {
+		if p.badJSON {
+			slog.Info("Fault injection: writing malformed JSON", "status", statusCode)
+			writeMalformedJSON(w, body)
+			return
+		}
+		json.NewEncoder(w).Encode(body)
+		return
+	}
+
+	if p.badJSON {
+		slog.Info("Fault injection: writing malformed JSON", "status", statusCode)
+		w.WriteHeader(statusCode)
+		writeMalformedJSON(w, body)
+		return
+	}
+
+	if p.truncate {
+		slog.Info("Fault injection: truncating the body", "status", statusCode)
+		writeTruncatedJSON(w, statusCode, body)
+		return
+	}
+
+	if p.shortBody {
+		extra := svc.ShortBodyBytes
+		if extra <= 0 {
+			extra = defaultShortBodyBytes
+		}
+		slog.Warn("Fault injection: advertising a Content-Length longer than the body", "status", statusCode, "missingBytes", extra)
+		writeShortJSON(ctx, svc.Sleeper, w, statusCode, body, extra)
+		return
+	}
+
+	if p.bodyDelay > 0 {
+		slog.Info("Fault injection: writing the body slowly", "status", statusCode, "bodyDelay", p.bodyDelay)
+		writeSlowJSON(ctx, svc.Sleeper, w, statusCode, body, p.bodyDelay)
+		return
+	}
+
+	// Encode and send the JSON response with an explicit Content-Length
+	writeJSON(w, statusCode, body)
+}
+
+// writeMalformedJSON writes v as JSON with a stray trailing comma before the
+// closing brace, which no conforming decoder accepts.
+func writeMalformedJSON(w http.ResponseWriter, v any) {
+	body, err := json.Marshal(v)
+	if err != nil || len(body) == 0 {
+		return
+	}
+	body = append(body[:len(body)-1], []byte(",}\n")...)
+	w.Write(body)
+}
+
+// writeTruncatedJSON writes the first half of v as JSON with the status code
+// and a Content-Length for the whole body. The server closes the connection
+// when a handler writes less than it declared, so the client sees the body
+// end early.
+func writeTruncatedJSON(w http.ResponseWriter, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	w.Write(body[:len(body)/2])
+}
+
+// writeShortJSON writes v as JSON with a Content-Length extra bytes longer
+// than the body, like a buggy server, so the client waits for bytes that
+// never arrive. It holds the connection open until the client gives up, or
+// for shortBodyHold, which ends the response short. It stops early when ctx
+// is done, if s is a ContextSleeper.
+func writeShortJSON(ctx context.Context, s Sleeper, w http.ResponseWriter, status int, v any, extra int) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("Could not encode JSON response", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n')
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)+extra))
+	w.WriteHeader(status)
+	w.Write(body)
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	if err := sleepContext(ctx, s, shortBodyHold); err != nil {
+		slog.Info("Client gave up waiting for the rest of a short body", "error", err)
+	}
+}
+
+// writeSlowJSON writes v as JSON like a slow backend: the status line and
+// headers, with the full Content-Length, are flushed at once, then the body
+// follows in bodyDelayChunks chunks with a pause of delay before each one. It
+// stops early when ctx is done, if s is a ContextSleeper.
+func writeSlowJSON(ctx context.Context, s Sleeper, w http.ResponseWriter, status int, v any, delay time.Duration) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("Could not encode JSON response", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n')
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	chunkSize := (len(body) + bodyDelayChunks - 1) / bodyDelayChunks
+	for len(body) > 0 {
+		if err := sleepContext(ctx, s, delay); err != nil {
+			slog.Info("Client went away during the body delay", "error", err)
+			return
+		}
+		chunk := body[:min(chunkSize, len(body))]
+		body = body[len(chunk):]
+		w.Write(chunk)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// sleepWithKeepAlive sleeps for d in keepAliveInterval steps, writing and flushing
+// a single whitespace byte after each step so intermediaries see traffic.
+// Leading whitespace is ignored by JSON parsers, so the final body stays valid.
+func sleepWithKeepAlive(s Sleeper, w http.ResponseWriter, d time.Duration) {
+	flusher, _ := w.(http.Flusher)
+	for d > 0 {
+		step := min(d, keepAliveInterval)
+		s.Sleep(step)
+		d -= step
+		w.Write([]byte(" "))
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// envInt reads an integer environment variable, falling back to def when it is
+// unset or invalid.
+func envInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("Invalid environment variable, using default", "name", name, "value", value, "default", def)
+		return def
+	}
+	return n
+}
+
+// envBool reads a boolean environment variable such as "true" or "1",
+// falling back to def when it is unset or invalid.
+func envBool(name string, def bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Warn("Invalid environment variable, using default", "name", name, "value", value, "default", def)
+		return def
+	}
+	return b
+}
+
+// envFloat reads a floating-point environment variable, falling back to def
+// when it is unset or invalid.
+func envFloat(name string, def float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Warn("Invalid environment variable, using default", "name", name, "value", value, "default", def)
+		return def
+	}
+	return f
 }
 
-func health(w http.ResponseWriter, r *http.Request) { w.Write([]byte("Healthy")) }
+// envDuration reads a duration environment variable such as "10s", falling
+// back to def when it is unset or invalid.
+func envDuration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		slog.Warn("Invalid environment variable, using default", "name", name, "value", value, "default", def)
+		return def
+	}
+	return d
+}
 
 func main() {
-	// Create an instance of RealSleeper for the main application.
-	sleeper := &DefaultSleeper{}
+	setupLogging()
+	if err := loadEnvFile(); err != nil {
+		log.Fatalf("Could not read WEATHER_ENV_FILE: %v", err)
+	}
+	cfg, err := LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	applyConfig(cfg)
 
-	// Define the handler for the /weather endpoint, injecting the realSleeper.
-	http.HandleFunc("/weather", func(w http.ResponseWriter, req *http.Request) {
-		weatherHandler(sleeper, w, req)
-	})
-	http.HandleFunc("/health", health)
+	// Dispatch on an optional subcommand; without one, run the server.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+		case "generate":
+			if err := runGenerate(os.Args[2:], os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			return
+		case "fixtures":
+			if err := runFixtures(os.Args[2:], os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			return
+		default:
+			fmt.Fprintf(os.Stderr, "unknown command %q, expected serve, generate or fixtures\n", os.Args[1])
+			os.Exit(2)
+		}
+	}
 
-	// Optionally read AUTHOR environment variable
-	var author = os.Getenv("AUTHOR")
+	serve(cfg)
+}
+
+// applyConfig sets the package-level generation settings from cfg, which the
+// generate subcommand shares with the server.
+func applyConfig(cfg Config) {
+	if cfg.Seed != nil {
+		r.Seed(*cfg.Seed)
+		slog.Info("Seeded the shared random source", "seed", *cfg.Seed)
+	}
+	if cfg.FixedDelay >= 0 {
+		slog.Info("Using a fixed delay for every request", "delay", cfg.FixedDelay)
+	}
+	settings.Store(cfg.runtimeSettings())
+}
+
+// serve runs the HTTP server described by cfg until it receives SIGINT or SIGTERM.
+func serve(cfg Config) {
+	svc, err := newService(cfg)
+	if err != nil {
+		log.Fatalf("Could not create the service: %v", err)
+	}
 
 	// Start the HTTP server
-	port := ":8080"
-	log.Printf("Starting Go REST API server on port %s", port)
+	port := ":" + strconv.Itoa(cfg.Port)
+	slog.Info("Starting Go REST API server", "port", port)
+
+	if cfg.Author != "" {
+		slog.Info("Author", "author", cfg.Author)
+	}
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		log.Fatalf("Could not set up TLS: %v", err)
+	}
+	server := &http.Server{
+		Addr:      port,
+		Handler:   svc.Handler(),
+		TLSConfig: tlsConfig,
+		Protocols: serverProtocols(cfg),
+	}
+
+	// Shut down gracefully on SIGINT/SIGTERM, letting in-flight requests finish.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Reload the reloadable settings on SIGHUP.
+	go watchReload(ctx, cfg)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		if tlsConfig != nil {
+			// The certificate is already in TLSConfig.
+			serverErr <- server.ListenAndServeTLS("", "")
+		} else {
+			serverErr <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-serverErr:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+
+	// Persist buffered records once the last requests have been recorded.
+	var flushes []shutdownFlush
+	if cfg.HistoryFile != "" && svc.History != nil {
+		flushes = append(flushes, shutdownFlush{Name: "history", Flush: func() (int, error) {
+			return svc.History.WriteFile(cfg.HistoryFile)
+		}})
+	}
+
+	if svc.Tracer != nil {
+		flushes = append(flushes, shutdownFlush{Name: "spans", Flush: svc.Tracer.Shutdown})
+	}
+
+	shutdown(server, svc.Metrics, cfg.ShutdownTimeout)
+	runShutdownFlushes(flushes, cfg.FlushTimeout)
+}
 
-	if author != "" {
-		log.Printf("Author: %s", author)
+// serverProtocols returns the protocols the server accepts: with H2C, HTTP/1
+// and unencrypted HTTP/2 with prior knowledge alongside HTTP/2 over TLS, so
+// h2c clients work without an x/net dependency; otherwise nil, for the
+// defaults.
+func serverProtocols(cfg Config) *http.Protocols {
+	if !cfg.H2C {
+		return nil
 	}
+	slog.Info("Serving HTTP/2 over cleartext (h2c)")
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return protocols
+}
+
+// shutdownFlush is a step that persists buffered records after the server has
+// drained, returning how many items it flushed.
+type shutdownFlush struct {
+	Name  string
+	Flush func() (int, error)
+}
 
-	log.Fatal(http.ListenAndServe(port, nil))
+// runShutdownFlushes runs each flush in order, giving all of them together at
+// most timeout. A flush still running at the deadline is abandoned, along with
+// the ones after it, so a stuck disk can't block exit forever.
+func runShutdownFlushes(flushes []shutdownFlush, timeout time.Duration) {
+	deadline := time.After(timeout)
+	for _, f := range flushes {
+		type result struct {
+			n   int
+			err error
+		}
+		done := make(chan result, 1)
+		go func() {
+			n, err := f.Flush()
+			done <- result{n, err}
+		}()
+
+		select {
+		case res := <-done:
+			if res.err != nil {
+				slog.Error("Flush failed", "buffer", f.Name, "error", res.err)
+				continue
+			}
+			slog.Info("Flushed buffered records", "buffer", f.Name, "items", res.n)
+		case <-deadline:
+			slog.Error("Flush timed out", "buffer", f.Name, "timeout", timeout)
+			return
+		}
+	}
+}
+
+// shutdown stops the server, waiting up to timeout for in-flight requests and
+// logging how many are still running while it drains.
+func shutdown(server *http.Server, metrics *Metrics, timeout time.Duration) {
+	slog.Info("Shutting down", "in_flight", metrics.InFlight(), "timeout", timeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				slog.Info("Draining connections", "in_flight", metrics.InFlight())
+			}
+		}
+	}()
+
+	err := server.Shutdown(ctx)
+	close(done)
+	if err != nil {
+		slog.Error("Shutdown did not complete", "error", err, "in_flight", metrics.InFlight())
+		return
+	}
+	slog.Info("Shutdown complete")
 }
@@ -2,19 +2,36 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 var sleeper = &NoOpSleeper{}
 
+var chooser = &RandomStatusChooser{}
+
+// recordingSleeper implements Sleeper and records the total requested sleep.
+type recordingSleeper struct {
+	total time.Duration
+}
+
+func (s *recordingSleeper) Sleep(d time.Duration) {
+	s.total += d
+}
+
 // TestWeatherHandlerSuccess tests the /weather endpoint for successful responses (2xx).
 func TestWeatherHandlerSuccess(t *testing.T) {
 	// Test with default size (10)
 	req := httptest.NewRequest("GET", "/weather", nil)
 	rr := httptest.NewRecorder()
-	weatherHandler(sleeper, rr, req)
+	weatherHandler(sleeper, chooser, rr, req)
 
 	// Check Content-Type header
 	if contentType := rr.Header().Get("Content-Type"); contentType != "application/json" {
@@ -55,7 +72,7 @@ func TestWeatherHandlerSuccess(t *testing.T) {
 	// Test with a specific valid size (e.g., 50)
 	req = httptest.NewRequest("GET", "/weather?size=50", nil)
 	rr = httptest.NewRecorder()
-	weatherHandler(sleeper, rr, req)
+	weatherHandler(sleeper, chooser, rr, req)
 
 	err = json.NewDecoder(rr.Body).Decode(&responseData)
 	if err != nil {
@@ -74,7 +91,7 @@ func TestWeatherHandlerSuccess(t *testing.T) {
 	// Test with max size (100)
 	req = httptest.NewRequest("GET", "/weather?size=100", nil)
 	rr = httptest.NewRecorder()
-	weatherHandler(sleeper, rr, req)
+	weatherHandler(sleeper, chooser, rr, req)
 
 	err = json.NewDecoder(rr.Body).Decode(&responseData)
 	if err != nil {
@@ -107,7 +124,7 @@ func TestWeatherHandlerInvalidSize(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			req := httptest.NewRequest("GET", "/weather?size="+tc.sizeParam, nil)
 			rr := httptest.NewRecorder()
-			weatherHandler(sleeper, rr, req)
+			weatherHandler(sleeper, chooser, rr, req)
 
 			// Parse the response body
 			var responseData DataResponse
@@ -145,7 +162,7 @@ func TestWeatherHandlerErrorResponseStructure(t *testing.T) {
 	for i := 0; i < maxAttempts; i++ {
 		req := httptest.NewRequest("GET", "/weather", nil)
 		rr := httptest.NewRecorder()
-		weatherHandler(sleeper, rr, req)
+		weatherHandler(sleeper, chooser, rr, req)
 
 		if rr.Code >= 400 { // Check for 4xx or 5xx status codes
 			errorHit = true
@@ -175,14 +192,670 @@ func TestWeatherHandlerErrorResponseStructure(t *testing.T) {
 func TestHealthEndpoint(t *testing.T) {
 	req := httptest.NewRequest("GET", "/health", nil)
 	rr := httptest.NewRecorder()
-	health(rr, req)
+	healthHandler(&HealthChecker{}, rr, req)
 
 	if rr.Code != http.StatusOK {
 		t.Errorf("Health endpoint returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
 	}
 
-	expected := "Healthy"
-	if rr.Body.String() != expected {
-		t.Errorf("Health endpoint returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	var report HealthReport
+	if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+		t.Fatalf("Could not decode health report: %v", err)
+	}
+	if report.Status != healthStatusHealthy {
+		t.Errorf("Health endpoint returned unexpected status: got %v want %v", report.Status, healthStatusHealthy)
+	}
+}
+
+// TestHealthEndpointChecks tests that failing checks degrade or fail /health.
+func TestHealthEndpointChecks(t *testing.T) {
+	h := &HealthChecker{}
+	h.Register("ok", true, func() error { return nil })
+	h.Register("optional", false, func() error { return errors.New("webhook unreachable") })
+
+	rr := httptest.NewRecorder()
+	healthHandler(h, rr, httptest.NewRequest("GET", "/health", nil))
+
+	var report HealthReport
+	if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+		t.Fatalf("Could not decode health report: %v", err)
+	}
+	if rr.Code != http.StatusOK || report.Status != healthStatusDegraded {
+		t.Errorf("Non-critical failure returned %d %v, want %d %v", rr.Code, report.Status, http.StatusOK, healthStatusDegraded)
+	}
+	if report.Checks["optional"].Error != "webhook unreachable" {
+		t.Errorf("Health report is missing check detail: %+v", report.Checks)
+	}
+
+	h.Register("disk", true, func() error { return errors.New("record dir not writable") })
+	rr = httptest.NewRecorder()
+	healthHandler(h, rr, httptest.NewRequest("GET", "/health", nil))
+
+	if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+		t.Fatalf("Could not decode health report: %v", err)
+	}
+	if rr.Code != http.StatusServiceUnavailable || report.Status != healthStatusUnhealthy {
+		t.Errorf("Critical failure returned %d %v, want %d %v", rr.Code, report.Status, http.StatusServiceUnavailable, healthStatusUnhealthy)
+	}
+}
+
+// TestHealthEndpointVerbose tests that runtime statistics are only reported
+// with verbose=true.
+func TestHealthEndpointVerbose(t *testing.T) {
+	for _, verbose := range []bool{false, true} {
+		rr := httptest.NewRecorder()
+		healthHandler(&HealthChecker{}, rr, httptest.NewRequest("GET", "/health?verbose="+strconv.FormatBool(verbose), nil))
+
+		var report HealthReport
+		if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+			t.Fatalf("Could not decode health report: %v", err)
+		}
+		if (report.Runtime != nil) != verbose {
+			t.Fatalf("verbose=%v returned runtime stats %+v", verbose, report.Runtime)
+		}
+		if verbose && (report.Runtime.Goroutines < 1 || report.Runtime.HeapAllocBytes == 0 || report.Runtime.SysBytes == 0) {
+			t.Errorf("Runtime stats are implausible: %+v", report.Runtime)
+		}
+	}
+}
+
+// TestWeatherHandlerKeepAlive tests that keep-alive padding still yields valid JSON.
+func TestWeatherHandlerKeepAlive(t *testing.T) {
+	req := httptest.NewRequest("GET", "/weather?keepAlive=true", nil)
+	rr := httptest.NewRecorder()
+	weatherHandler(sleeper, chooser, rr, req)
+
+	var responseData DataResponse
+	if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+		t.Fatalf("Could not decode keep-alive response: %v", err)
+	}
+	if responseData.Message == "" {
+		t.Errorf("Expected a message in keep-alive response, but got empty.")
+	}
+}
+
+// TestWeatherHandlerContentTypeOverride tests the contentType query parameter.
+func TestWeatherHandlerContentTypeOverride(t *testing.T) {
+	testCases := []struct {
+		name     string
+		param    string
+		expected string
+	}{
+		{"NoOverride", "", "application/json"},
+		{"AllowedOverride", "text/plain", "text/plain"},
+		{"DisallowedOverride", "text/plain%0D%0AX-Injected:%201", "application/json"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/weather?contentType="+tc.param, nil)
+			rr := httptest.NewRecorder()
+			weatherHandler(sleeper, chooser, rr, req)
+
+			if contentType := rr.Header().Get("Content-Type"); contentType != tc.expected {
+				t.Errorf("Handler returned wrong content type: got %v want %v", contentType, tc.expected)
+			}
+		})
+	}
+}
+
+// TestWeatherHandlerDelayBounds tests the minDelay and maxDelay query parameters.
+func TestWeatherHandlerDelayBounds(t *testing.T) {
+	recording := &recordingSleeper{}
+	req := httptest.NewRequest("GET", "/weather?minDelay=200&maxDelay=200", nil)
+	rr := httptest.NewRecorder()
+	weatherHandler(recording, chooser, rr, req)
+
+	if recording.total != 200*time.Millisecond {
+		t.Errorf("Handler slept for wrong duration: got %v want %v", recording.total, 200*time.Millisecond)
+	}
+
+	// minDelay greater than maxDelay is rejected.
+	req = httptest.NewRequest("GET", "/weather?minDelay=300&maxDelay=200", nil)
+	rr = httptest.NewRecorder()
+	weatherHandler(sleeper, chooser, rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code for minDelay > maxDelay: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+// TestWeatherHandlerSeed tests that the same seed produces the same response.
+func TestWeatherHandlerSeed(t *testing.T) {
+	var responses [2]DataResponse
+	var codes [2]int
+	for i := range responses {
+		req := httptest.NewRequest("GET", "/weather?seed=12345&size=20", nil)
+		rr := httptest.NewRecorder()
+		weatherHandler(sleeper, chooser, rr, req)
+
+		codes[i] = rr.Code
+		if err := json.NewDecoder(rr.Body).Decode(&responses[i]); err != nil {
+			t.Fatalf("Could not decode response: %v", err)
+		}
+	}
+
+	if codes[0] != codes[1] {
+		t.Errorf("Same seed returned different status codes: %d and %d", codes[0], codes[1])
+	}
+	if len(responses[0].Readings) != len(responses[1].Readings) {
+		t.Fatalf("Same seed returned different number of readings: %d and %d", len(responses[0].Readings), len(responses[1].Readings))
+	}
+	for i := range responses[0].Readings {
+		a, b := responses[0].Readings[i], responses[1].Readings[i]
+		if a.City != b.City || a.Temperature != b.Temperature || a.Humidity != b.Humidity || a.Condition != b.Condition {
+			t.Errorf("Same seed returned different reading %d: %+v and %+v", i, a, b)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/weather?seed=abc", nil)
+	rr := httptest.NewRecorder()
+	weatherHandler(sleeper, chooser, rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code for invalid seed: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+// TestWeatherHandlerBadJSON tests that badjson=true produces an undecodable body.
+func TestWeatherHandlerBadJSON(t *testing.T) {
+	req := httptest.NewRequest("GET", "/weather?badjson=true", nil)
+	rr := httptest.NewRecorder()
+	weatherHandler(sleeper, chooser, rr, req)
+
+	var responseData DataResponse
+	if err := json.NewDecoder(rr.Body).Decode(&responseData); err == nil {
+		t.Errorf("Expected malformed JSON, but the body decoded successfully.")
+	}
+}
+
+// TestWeatherHandlerFixedDelay tests that a fixed delay overrides the random range.
+func TestWeatherHandlerFixedDelay(t *testing.T) {
+	settings.Store(testSettings(func(s *runtimeSettings) { s.FixedDelay = 750 * time.Millisecond }))
+	defer settings.Store(defaultRuntimeSettings())
+
+	recording := &recordingSleeper{}
+	req := httptest.NewRequest("GET", "/weather?minDelay=10&maxDelay=20", nil)
+	rr := httptest.NewRecorder()
+	weatherHandler(recording, chooser, rr, req)
+
+	if recording.total != 750*time.Millisecond {
+		t.Errorf("Handler slept for wrong duration: got %v want %v", recording.total, 750*time.Millisecond)
+	}
+}
+
+// TestWeatherHandlerSizeScaledDelay tests that the delay can scale with the
+// requested size, capped at the maximum delay.
+func TestWeatherHandlerSizeScaledDelay(t *testing.T) {
+	settings.Store(testSettings(func(s *runtimeSettings) {
+		s.SizeDelayBase = 100 * time.Millisecond
+		s.SizeDelayPerItem = 20 * time.Millisecond
+	}))
+	defer settings.Store(defaultRuntimeSettings())
+
+	testCases := []struct {
+		target string
+		want   time.Duration
+	}{
+		{"/weather?size=10", 300 * time.Millisecond},
+		{"/weather?size=50&minDelay=10&maxDelay=20", 1100 * time.Millisecond},
+	}
+	for _, tc := range testCases {
+		recording := &recordingSleeper{}
+		weatherHandler(recording, chooser, httptest.NewRecorder(), httptest.NewRequest("GET", tc.target, nil))
+		if recording.total != tc.want {
+			t.Errorf("Handler slept for wrong duration for %s: got %v want %v", tc.target, recording.total, tc.want)
+		}
+	}
+
+	// The scaled delay is capped like any other delay.
+	settings.Store(testSettings(func(s *runtimeSettings) { s.SizeDelayPerItem = time.Second }))
+	recording := &recordingSleeper{}
+	weatherHandler(recording, chooser, httptest.NewRecorder(), httptest.NewRequest("GET", "/weather?size=100", nil))
+	if want := maxDelayMs * time.Millisecond; recording.total != want {
+		t.Errorf("Handler slept for wrong duration: got %v want %v", recording.total, want)
+	}
+}
+
+// TestWeatherHandlerErrorDelay tests that 5xx responses take the error delay
+// while other responses keep their usual delay.
+func TestWeatherHandlerErrorDelay(t *testing.T) {
+	settings.Store(testSettings(func(s *runtimeSettings) { s.ErrorDelay = 3 * time.Second }))
+	defer settings.Store(defaultRuntimeSettings())
+
+	testCases := []struct {
+		target string
+		want   time.Duration
+	}{
+		{"/weather?status=503&delayMs=100", 3 * time.Second},
+		{"/weather?status=500&minDelay=0&maxDelay=10", 3 * time.Second},
+		{"/weather?status=200&delayMs=100", 100 * time.Millisecond},
+		{"/weather?status=404&delayMs=100", 100 * time.Millisecond},
+		{"/weather?softError=true&delayMs=100", 100 * time.Millisecond},
+	}
+	for _, tc := range testCases {
+		recording := &recordingSleeper{}
+		weatherHandler(recording, chooser, httptest.NewRecorder(), httptest.NewRequest("GET", tc.target, nil))
+		if recording.total != tc.want {
+			t.Errorf("Handler slept for wrong duration for %s: got %v want %v", tc.target, recording.total, tc.want)
+		}
+	}
+}
+
+// TestWeatherHandlerPostMatchesGet tests that POST with a JSON body behaves like GET with query parameters.
+func TestWeatherHandlerPostMatchesGet(t *testing.T) {
+	ok := &FixedStatusChooser{Status: http.StatusOK}
+
+	get := httptest.NewRequest("GET", "/weather?size=50&units=fahrenheit&city=Tokyo&delayMs=100&seed=7", nil)
+	getRec := httptest.NewRecorder()
+	weatherHandler(sleeper, ok, getRec, get)
+
+	post := httptest.NewRequest("POST", "/weather", strings.NewReader(`{"size":50,"units":"fahrenheit","city":"Tokyo","delayMs":100,"seed":7}`))
+	postRec := httptest.NewRecorder()
+	weatherHandler(sleeper, ok, postRec, post)
+
+	var getData, postData DataResponse
+	if err := json.NewDecoder(getRec.Body).Decode(&getData); err != nil {
+		t.Fatalf("Could not decode GET response: %v", err)
+	}
+	if err := json.NewDecoder(postRec.Body).Decode(&postData); err != nil {
+		t.Fatalf("Could not decode POST response: %v", err)
+	}
+
+	if len(postData.Readings) != 50 || postData.Units != unitsFahrenheit {
+		t.Fatalf("POST returned unexpected response: %d readings in %q", len(postData.Readings), postData.Units)
+	}
+	for i, reading := range postData.Readings {
+		if reading.City != "Tokyo" {
+			t.Errorf("Reading %d has wrong city: got %v want %v", i, reading.City, "Tokyo")
+		}
+		if reading.Temperature != getData.Readings[i].Temperature {
+			t.Errorf("Reading %d differs between GET and POST: %v and %v", i, getData.Readings[i].Temperature, reading.Temperature)
+		}
+		// 5-40 Celsius is 41-104 Fahrenheit.
+		if reading.Temperature < 41 || reading.Temperature > 104 {
+			t.Errorf("Reading %d temperature %.1f is not in Fahrenheit range", i, reading.Temperature)
+		}
+	}
+}
+
+// TestWeatherHandlerInvalidOptions tests that invalid options are rejected with 400.
+func TestWeatherHandlerInvalidOptions(t *testing.T) {
+	testCases := []struct {
+		name   string
+		method string
+		target string
+		body   string
+	}{
+		{"UnknownUnits", "GET", "/weather?units=kelvin", ""},
+		{"UnknownCity", "GET", "/weather?city=Atlantis", ""},
+		{"StatusOutOfRange", "GET", "/weather?status=99", ""},
+		{"AnomalyRateOutOfRange", "GET", "/weather?anomalyRate=1.5", ""},
+		{"DupRateOutOfRange", "POST", "/weather", `{"dupRate":-0.1}`},
+		{"UnknownCompat", "GET", "/weather?compat=darksky", ""},
+		{"OmitFieldsWithCompat", "GET", "/weather?compat=owm&omitFields=true", ""},
+		{"SoftErrorWithStatus", "GET", "/weather?softError=true&status=500", ""},
+		{"UnknownGroupBy", "GET", "/weather?groupBy=country", ""},
+		{"GroupByWithCompat", "POST", "/weather", `{"groupBy":"city","compat":"owm"}`},
+		{"UnknownHumidityPrecision", "GET", "/weather?humidityPrecision=double", ""},
+		{"FloatHumidityWithCompat", "GET", "/weather?humidityPrecision=float&compat=owm", ""},
+		{"MalformedBody", "POST", "/weather", `{"size":`},
+		{"WrongBodyType", "POST", "/weather", `{"size":"fifty"}`},
+		{"BodyDelayConflict", "POST", "/weather", `{"minDelay":300,"maxDelay":200}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			weatherHandler(sleeper, chooser, rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+// TestWeatherHandlerConflictingOptions tests that every conflict is reported before sleeping.
+func TestWeatherHandlerConflictingOptions(t *testing.T) {
+	recording := &recordingSleeper{}
+	req := httptest.NewRequest("GET", "/weather?delayMs=100&minDelay=300&maxDelay=200&units=kelvin&status=204&badjson=true", nil)
+	rr := httptest.NewRecorder()
+	weatherHandler(recording, chooser, rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	if recording.total != 0 {
+		t.Errorf("Handler slept for %v before rejecting the request", recording.total)
+	}
+
+	var responseData DataResponse
+	if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	for _, want := range []string{"delayMs conflicts", "units", "badjson conflicts"} {
+		if !strings.Contains(responseData.Message, want) {
+			t.Errorf("Response message %q does not mention %q", responseData.Message, want)
+		}
+	}
+}
+
+// TestWeatherHandlerAnomalyRate tests that anomalyRate=1 marks every reading
+// with an out-of-band temperature and that normal readings are unmarked.
+func TestWeatherHandlerAnomalyRate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		rate    string
+		anomaly bool
+	}{
+		{"Always", "1", true},
+		{"Never", "0", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/weather?size=50&anomalyRate="+tc.rate, nil)
+			rr := httptest.NewRecorder()
+			weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusOK}, rr, req)
+
+			var responseData DataResponse
+			if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+				t.Fatalf("Could not decode response: %v", err)
+			}
+			for _, reading := range responseData.Readings {
+				extreme := reading.Temperature >= 60 || reading.Temperature <= -40
+				if reading.Anomaly != tc.anomaly || extreme != tc.anomaly {
+					t.Errorf("Reading has wrong anomaly state: got anomaly=%v temperature=%v want anomaly=%v", reading.Anomaly, reading.Temperature, tc.anomaly)
+				}
+			}
+		})
+	}
+}
+
+// TestWeatherHandlerIcons tests that icons=true adds an icon for every
+// condition and that the field is omitted otherwise.
+func TestWeatherHandlerIcons(t *testing.T) {
+	for _, condition := range conditions {
+		if conditionIcons[condition] == "" {
+			t.Errorf("Condition %q has no icon", condition)
+		}
+	}
+
+	for _, icons := range []bool{true, false} {
+		req := httptest.NewRequest("GET", "/weather?icons="+strconv.FormatBool(icons), nil)
+		rr := httptest.NewRecorder()
+		weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusOK}, rr, req)
+
+		if got := strings.Contains(rr.Body.String(), `"icon"`); got != icons {
+			t.Errorf("Response contains icon field: got %v want %v", got, icons)
+		}
+		var responseData DataResponse
+		if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+			t.Fatalf("Could not decode response: %v", err)
+		}
+		for _, reading := range responseData.Readings {
+			if icons && reading.Icon != conditionIcons[reading.Condition] {
+				t.Errorf("Reading has wrong icon for %q: got %v want %v", reading.Condition, reading.Icon, conditionIcons[reading.Condition])
+			}
+		}
+	}
+}
+
+// TestWeatherHandlerDupRate tests that dupRate=1 repeats the first reading
+// throughout the response.
+func TestWeatherHandlerDupRate(t *testing.T) {
+	req := httptest.NewRequest("GET", "/weather?size=20&dupRate=1", nil)
+	rr := httptest.NewRecorder()
+	weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusOK}, rr, req)
+
+	var responseData DataResponse
+	if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	if len(responseData.Readings) != 20 {
+		t.Fatalf("Handler returned wrong number of readings: got %d want %d", len(responseData.Readings), 20)
+	}
+	first := responseData.Readings[0]
+	for i, reading := range responseData.Readings {
+		if reading != first {
+			t.Errorf("Reading %d is not a duplicate: got %+v want %+v", i, reading, first)
+		}
+	}
+}
+
+// TestWeatherHandlerAt tests that at pins the timestamps so a seeded response
+// is identical across calls, and that a malformed at is rejected.
+func TestWeatherHandlerAt(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var bodies [2]string
+	for i := range bodies {
+		req := httptest.NewRequest("GET", "/weather?seed=7&at=2024-01-01T00:00:00Z", nil)
+		rr := httptest.NewRecorder()
+		weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusOK}, rr, req)
+		bodies[i] = rr.Body.String()
+
+		var responseData DataResponse
+		if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+			t.Fatalf("Could not decode response: %v", err)
+		}
+		for _, reading := range responseData.Readings {
+			if offset := reading.Timestamp.Sub(at); offset < -12*time.Hour || offset > 12*time.Hour {
+				t.Errorf("Reading timestamp %v is not within 12 hours of %v", reading.Timestamp, at)
+			}
+		}
+	}
+	if bodies[0] != bodies[1] {
+		t.Errorf("Same seed and at returned different bodies:\n%s\n%s", bodies[0], bodies[1])
+	}
+
+	req := httptest.NewRequest("GET", "/weather?at=yesterday", nil)
+	rr := httptest.NewRecorder()
+	weatherHandler(sleeper, chooser, rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code for invalid at: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+// TestWeatherHandlerSoftError tests that softError=true returns 200 with no
+// readings and an error message, whatever the chooser would pick.
+func TestWeatherHandlerSoftError(t *testing.T) {
+	req := httptest.NewRequest("GET", "/weather?softError=true", nil)
+	rr := httptest.NewRecorder()
+	weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusInternalServerError}, rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if !strings.Contains(rr.Body.String(), `"readings":[]`) {
+		t.Errorf("Response does not have empty readings: %s", rr.Body.String())
+	}
+	var responseData DataResponse
+	if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	if !strings.HasPrefix(responseData.Message, "Error:") {
+		t.Errorf("Response message does not indicate an error: %q", responseData.Message)
+	}
+}
+
+// TestWeatherHandlerGeneratedAt tests that every /weather response records
+// when it was generated, in UTC.
+func TestWeatherHandlerGeneratedAt(t *testing.T) {
+	testCases := []struct {
+		name   string
+		target string
+		status int
+		want   time.Time
+	}{
+		{"Success", "/weather", http.StatusOK, testEpoch},
+		{"Error", "/weather", http.StatusInternalServerError, testEpoch},
+		{"At", "/weather?at=2024-06-01T08:00:00%2B02:00", http.StatusOK, time.Date(2024, 6, 1, 6, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := newTestService(1, nil, &FixedStatusChooser{Status: tc.status})
+			rr := httptest.NewRecorder()
+			svc.Handler().ServeHTTP(rr, httptest.NewRequest("GET", tc.target, nil))
+
+			var responseData DataResponse
+			if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+				t.Fatalf("Could not decode response: %v", err)
+			}
+			if !responseData.GeneratedAt.Equal(tc.want) || responseData.GeneratedAt.Location() != time.UTC {
+				t.Errorf("Handler returned wrong generated_at: got %v want %v", responseData.GeneratedAt, tc.want)
+			}
+		})
+	}
+
+	// Responses rejected before generation have no generated_at.
+	rr := httptest.NewRecorder()
+	weatherHandler(sleeper, chooser, rr, httptest.NewRequest("GET", "/weather?units=kelvin", nil))
+	if strings.Contains(rr.Body.String(), "generated_at") {
+		t.Errorf("Rejected request has a generated_at: %s", rr.Body.String())
+	}
+}
+
+// TestWeatherHandlerAttribution tests that attribution=true adds the data
+// source and the configured attribution, which are omitted otherwise.
+func TestWeatherHandlerAttribution(t *testing.T) {
+	svc := newTestService(1, nil, &FixedStatusChooser{Status: http.StatusOK})
+	svc.Attribution = "Data by Example Weather"
+
+	for _, attribution := range []bool{true, false} {
+		rr := httptest.NewRecorder()
+		svc.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/weather?attribution="+strconv.FormatBool(attribution), nil))
+
+		var responseData DataResponse
+		if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+			t.Fatalf("Could not decode response: %v", err)
+		}
+		wantSource, wantAttribution := "", ""
+		if attribution {
+			wantSource, wantAttribution = dataSource, svc.Attribution
+		}
+		if responseData.Source != wantSource || responseData.Attribution != wantAttribution {
+			t.Errorf("attribution=%v returned wrong source and attribution: got %q, %q want %q, %q",
+				attribution, responseData.Source, responseData.Attribution, wantSource, wantAttribution)
+		}
+	}
+}
+
+// TestWeatherHandlerHumidityPrecision tests that humidityPrecision=float
+// serializes humidity with a decimal place and that it stays an integer by
+// default.
+func TestWeatherHandlerHumidityPrecision(t *testing.T) {
+	for _, precision := range []string{"", "int", "float"} {
+		req := httptest.NewRequest("GET", "/weather?size=50&seed=1&humidityPrecision="+precision, nil)
+		rr := httptest.NewRecorder()
+		weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusOK}, rr, req)
+
+		var responseData struct {
+			Readings []struct {
+				Humidity json.Number `json:"humidity"`
+			} `json:"readings"`
+		}
+		decoder := json.NewDecoder(rr.Body)
+		decoder.UseNumber()
+		if err := decoder.Decode(&responseData); err != nil {
+			t.Fatalf("Could not decode response: %v", err)
+		}
+		fractional := 0
+		for _, reading := range responseData.Readings {
+			humidity, err := reading.Humidity.Float64()
+			if err != nil || humidity < 20 || humidity >= 100 {
+				t.Errorf("humidityPrecision=%q returned humidity out of range: %v", precision, reading.Humidity)
+			}
+			if strings.Contains(reading.Humidity.String(), ".") {
+				fractional++
+			}
+		}
+		if precision != "float" && fractional > 0 {
+			t.Errorf("humidityPrecision=%q returned %d fractional humidities", precision, fractional)
+		}
+		// A tenth of the readings get .0, which encodes as a whole number.
+		if precision == "float" && fractional < len(responseData.Readings)/2 {
+			t.Errorf("humidityPrecision=float returned only %d of %d fractional humidities", fractional, len(responseData.Readings))
+		}
+	}
+}
+
+// TestGetResponseStatusCodeDistribution tests that, over many draws from a
+// fixed-seed source, getResponseStatusCode picks 2xx, 4xx and 5xx codes
+// about 70%, 15% and 15% of the time, and codes within a class uniformly.
+func TestGetResponseStatusCodeDistribution(t *testing.T) {
+	const draws = 100000
+	rng := rand.New(rand.NewSource(1))
+	counts := make(map[int]int)
+	for range draws {
+		counts[getResponseStatusCode(rng)]++
+	}
+
+	classes := []struct {
+		name   string
+		codes  []int
+		weight float64
+	}{
+		{"2xx", []int{200, 201, 202, 204}, 0.70},
+		{"4xx", []int{400, 401, 403, 404, 405}, 0.15},
+		{"5xx", []int{500, 501, 502, 503, 504}, 0.15},
+	}
+	// About seven standard deviations at this many draws, so only a real
+	// change in the distribution fails.
+	const tolerance = 0.01
+	seen := 0
+	for _, class := range classes {
+		total := 0
+		for _, code := range class.codes {
+			total += counts[code]
+		}
+		seen += total
+		if got := float64(total) / draws; math.Abs(got-class.weight) > tolerance {
+			t.Errorf("%s share is %.4f, want %.2f±%.2f", class.name, got, class.weight, tolerance)
+		}
+		for _, code := range class.codes {
+			want := 1 / float64(len(class.codes))
+			if got := float64(counts[code]) / float64(total); math.Abs(got-want) > 2*tolerance {
+				t.Errorf("%d share of %s is %.4f, want %.2f±%.2f", code, class.name, got, want, 2*tolerance)
+			}
+		}
+	}
+	if seen != draws {
+		t.Errorf("%d of %d status codes are outside the expected classes: %v", draws-seen, draws, counts)
+	}
+}
+
+// TestWeatherHandlerConfiguredBounds tests that the configured size and delay
+// bounds replace the built-in ones.
+func TestWeatherHandlerConfiguredBounds(t *testing.T) {
+	settings.Store(testSettings(func(s *runtimeSettings) {
+		s.DefaultSize, s.MinSize, s.MaxSize = 3, 1, 500
+		s.DefaultMaxDelayMs, s.MaxDelayMs = 0, 100
+	}))
+	defer settings.Store(defaultRuntimeSettings())
+
+	ok := &FixedStatusChooser{Status: http.StatusOK}
+	testCases := []struct {
+		target   string
+		readings int
+		delay    time.Duration
+	}{
+		{"/weather", 3, 0},
+		{"/weather?size=1", 1, 0},
+		{"/weather?size=500&delayMs=100", 500, 100 * time.Millisecond},
+		{"/weather?size=501&delayMs=101", 3, 0},
+	}
+	for _, tc := range testCases {
+		recording := &recordingSleeper{}
+		rr := httptest.NewRecorder()
+		weatherHandler(recording, ok, rr, httptest.NewRequest("GET", tc.target, nil))
+		var responseData DataResponse
+		if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+			t.Fatalf("Could not decode response for %s: %v", tc.target, err)
+		}
+		if len(responseData.Readings) != tc.readings || recording.total != tc.delay {
+			t.Errorf("%s: got %d readings after %v, want %d after %v", tc.target, len(responseData.Readings), recording.total, tc.readings, tc.delay)
+		}
 	}
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Maintenance is a switchable maintenance mode in which every endpoint except
+// the probes answers 503, as during a planned maintenance window.
+type Maintenance struct {
+	// RetryAfter is sent in the Retry-After header of maintenance responses.
+	RetryAfter time.Duration
+
+	enabled atomic.Bool
+}
+
+// MaintenanceResponse is the response of POST /debug/maintenance.
+type MaintenanceResponse struct {
+	Maintenance bool `json:"maintenance"`
+}
+
+// Enabled reports whether maintenance mode is on. A nil Maintenance never is.
+func (m *Maintenance) Enabled() bool {
+	return m != nil && m.enabled.Load()
+}
+
+// SetEnabled turns maintenance mode on or off.
+func (m *Maintenance) SetEnabled(enabled bool) {
+	if m.enabled.Swap(enabled) != enabled {
+		slog.Warn("Maintenance mode changed", "enabled", enabled)
+	}
+}
+
+// maintenanceExemptPaths returns the paths that stay available during
+// maintenance: the health and readiness probes under the base path and every
+// version, /health at the root when it is served there, and the switch itself.
+func (svc *WeatherService) maintenanceExemptPaths() []string {
+	base := normalizeBasePath(svc.BasePath)
+	var paths []string
+	for _, prefix := range apiVersions {
+		paths = append(paths, base+prefix+"/health", base+prefix+"/ready")
+	}
+	if svc.HealthAtRoot && base != "" {
+		paths = append(paths, "/health")
+	}
+	return append(paths, base+"/debug/maintenance")
+}
+
+// maintenanceMiddleware answers every request except those for the exempt
+// paths with 503 and a Retry-After header while maintenance mode is on. Paths
+// are matched exactly, so it must run after trailing slashes are trimmed.
+func maintenanceMiddleware(m *Maintenance, exempt []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !m.Enabled() || slices.Contains(exempt, req.URL.Path) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		slog.Info("Rejecting request during maintenance", "path", req.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", strconv.Itoa(int(m.RetryAfter.Round(time.Second).Seconds())))
+		writeJSON(w, http.StatusServiceUnavailable, DataResponse{
+			Message: fmt.Sprintf("Service is down for scheduled maintenance. Retry after %v.", m.RetryAfter),
+		})
+	})
+}
+
+// maintenanceHandler serves POST /debug/maintenance, which turns maintenance
+// mode on or off with ?enabled=true or false, or toggles it without.
+func maintenanceHandler(m *Maintenance, w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enabled := !m.Enabled()
+	if value := req.URL.Query().Get("enabled"); value != "" {
+		var err error
+		if enabled, err = strconv.ParseBool(value); err != nil {
+			writeJSON(w, http.StatusBadRequest, DataResponse{Message: fmt.Sprintf("Invalid request: invalid 'enabled' parameter %q, expected true or false", value)})
+			return
+		}
+	}
+	m.SetEnabled(enabled)
+	writeJSON(w, http.StatusOK, MaintenanceResponse{Maintenance: enabled})
+}
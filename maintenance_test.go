@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestMaintenanceMode tests that maintenance mode answers 503 with Retry-After
+// everywhere except the probes and that /debug/maintenance switches it.
+func TestMaintenanceMode(t *testing.T) {
+	svc := newTestService(1, nil, nil)
+	svc.Debug = true
+	svc.Maintenance = &Maintenance{RetryAfter: 90 * time.Second}
+	handler := svc.Handler()
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, target, nil))
+		return rr
+	}
+
+	if rr := serve("GET", "/weather"); rr.Code != http.StatusOK {
+		t.Fatalf("Weather returned wrong status code before maintenance: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	rr := serve("POST", "/debug/maintenance?enabled=true")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Maintenance switch returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var resp MaintenanceResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || !resp.Maintenance {
+		t.Fatalf("Maintenance switch returned %s (%v), want maintenance true", rr.Body, err)
+	}
+
+	for _, target := range []string{"/weather", "/v1/weather/Tokyo", "/meta", "/debug/requests", "/weather/health", "/v1/weather/ready"} {
+		rr := serve("GET", target)
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s returned wrong status code during maintenance: got %v want %v", target, rr.Code, http.StatusServiceUnavailable)
+		}
+		if got := rr.Header().Get("Retry-After"); got != "90" {
+			t.Errorf("%s returned wrong Retry-After: got %q want %q", target, got, "90")
+		}
+	}
+	for _, target := range []string{"/health", "/ready", "/v1/health", "/health/", "/v1/ready/"} {
+		if rr := serve("GET", target); rr.Code != http.StatusOK {
+			t.Errorf("%s returned wrong status code during maintenance: got %v want %v", target, rr.Code, http.StatusOK)
+		}
+	}
+
+	// Without enabled the switch toggles.
+	serve("POST", "/debug/maintenance")
+	if svc.Maintenance.Enabled() {
+		t.Fatalf("Maintenance mode was not toggled off")
+	}
+	if rr := serve("GET", "/weather"); rr.Code != http.StatusOK {
+		t.Errorf("Weather returned wrong status code after maintenance: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	if rr := serve("POST", "/debug/maintenance?enabled=maybe"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid switch returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+// TestMaintenanceRouteNeedsDebug tests that the switch is only served in
+// debug mode.
+func TestMaintenanceRouteNeedsDebug(t *testing.T) {
+	svc := newTestService(1, nil, nil)
+	svc.Maintenance = &Maintenance{}
+	rr := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rr, httptest.NewRequest("POST", "/debug/maintenance?enabled=true", nil))
+	if rr.Code == http.StatusOK || svc.Maintenance.Enabled() {
+		t.Errorf("Maintenance switch was served without debug mode: status %v", rr.Code)
+	}
+}
+
+// TestMaintenanceBasePath tests that only the probes under the base path, and
+// /health at the root when it is served there, stay available.
+func TestMaintenanceBasePath(t *testing.T) {
+	svc := newTestService(1, nil, nil)
+	svc.BasePath = "/api"
+	svc.HealthAtRoot = true
+	svc.Maintenance = &Maintenance{}
+	svc.Maintenance.SetEnabled(true)
+	handler := svc.Handler()
+
+	for target, want := range map[string]int{
+		"/health":             http.StatusOK,
+		"/api/health/":        http.StatusOK,
+		"/api/v1/ready":       http.StatusOK,
+		"/ready":              http.StatusServiceUnavailable,
+		"/api/weather/health": http.StatusServiceUnavailable,
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != want {
+			t.Errorf("%s returned wrong status code during maintenance: got %v want %v", target, rr.Code, want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// version is the server version, set at build time with
+// -ldflags "-X main.version=1.2.3".
+var version = "dev"
+
+// buildVersion returns version, falling back to the module version recorded
+// in the build info (set by go install module@version) when it wasn't set.
+func buildVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
+
+// MetaResponse lists the values readings can take.
+type MetaResponse struct {
+	Cities     []string `json:"cities"`
+	Conditions []string `json:"conditions"`
+}
+
+// VersionResponse describes the running build.
+type VersionResponse struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"goVersion"`
+}
+
+// metaETag returns the ETag /meta currently serves. POST requests use it in
+// If-Match, since the cities and conditions are what they are validated against.
+func metaETag() string {
+	_, etag, _ := encodeWithETag(newMetaResponse())
+	return etag
+}
+
+// newMetaResponse describes the current cities and conditions.
+func newMetaResponse() MetaResponse {
+	return MetaResponse{Cities: cities, Conditions: conditions}
+}
+
+// metaHandler handles GET requests to the /meta endpoint.
+func metaHandler(w http.ResponseWriter, req *http.Request) {
+	writeStaticJSON(w, req, newMetaResponse())
+}
+
+// versionHandler handles GET requests to the /version endpoint.
+func versionHandler(w http.ResponseWriter, req *http.Request) {
+	writeStaticJSON(w, req, VersionResponse{Version: buildVersion(), GoVersion: runtime.Version()})
+}
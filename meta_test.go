@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestMetaHandler tests that /meta lists the cities and conditions.
+func TestMetaHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	metaHandler(rr, httptest.NewRequest("GET", "/meta", nil))
+
+	var meta MetaResponse
+	if err := json.NewDecoder(rr.Body).Decode(&meta); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	if len(meta.Cities) != len(cities) || len(meta.Conditions) != len(conditions) {
+		t.Errorf("Metadata has wrong lengths: got %d cities and %d conditions want %d and %d", len(meta.Cities), len(meta.Conditions), len(cities), len(conditions))
+	}
+}
+
+// TestStaticJSONNotModified tests the ETag and If-None-Match handling shared
+// by /meta and /version.
+func TestStaticJSONNotModified(t *testing.T) {
+	handlers := map[string]http.HandlerFunc{"/meta": metaHandler, "/version": versionHandler}
+	for _, path := range sortedKeys(handlers) {
+		t.Run(path, func(t *testing.T) {
+			handler := handlers[path]
+			rr := httptest.NewRecorder()
+			handler(rr, httptest.NewRequest("GET", path, nil))
+			etag := rr.Header().Get("ETag")
+			if rr.Code != http.StatusOK || etag == "" {
+				t.Fatalf("Handler returned status %v with ETag %q", rr.Code, etag)
+			}
+
+			testCases := []struct {
+				ifNoneMatch string
+				expected    int
+			}{
+				{etag, http.StatusNotModified},
+				{`"other", W/` + etag, http.StatusNotModified},
+				{"*", http.StatusNotModified},
+				{`"other"`, http.StatusOK},
+			}
+			for _, tc := range testCases {
+				req := httptest.NewRequest("GET", path, nil)
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+				rr := httptest.NewRecorder()
+				handler(rr, req)
+
+				if rr.Code != tc.expected {
+					t.Errorf("Handler returned wrong status code for If-None-Match %s: got %v want %v", tc.ifNoneMatch, rr.Code, tc.expected)
+				}
+				if rr.Code == http.StatusNotModified && rr.Body.Len() != 0 {
+					t.Errorf("304 response has a body: %q", rr.Body.String())
+				}
+			}
+		})
+	}
+}
+
+// TestIfMatchMiddleware tests that POST requests with a stale If-Match get 412
+// while matching, wildcard and absent preconditions are let through.
+func TestIfMatchMiddleware(t *testing.T) {
+	svc := &WeatherService{Sleeper: sleeper, Chooser: &FixedStatusChooser{Status: http.StatusOK}, RequestLog: NewRequestLog(10), Metrics: &Metrics{}, Health: &HealthChecker{}}
+	handler := svc.Handler()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/meta", nil))
+	etag := rr.Header().Get("ETag")
+
+	testCases := []struct {
+		name     string
+		ifMatch  string
+		expected int
+	}{
+		{"Current", etag, http.StatusOK},
+		{"Wildcard", "*", http.StatusOK},
+		{"Absent", "", http.StatusOK},
+		{"Stale", `"stale"`, http.StatusPreconditionFailed},
+		{"Weak", "W/" + etag, http.StatusPreconditionFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/weather/validate", strings.NewReader(`{"city":"Tokyo","humidity":50,"condition":"Sunny"}`))
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expected {
+				t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, tc.expected)
+			}
+			if rr.Code == http.StatusPreconditionFailed && rr.Header().Get("ETag") != etag {
+				t.Errorf("412 response has wrong ETag: got %v want %v", rr.Header().Get("ETag"), etag)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// Metrics holds the counters exposed on /metrics.
+type Metrics struct {
+	inFlight    atomic.Int64
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
+}
+
+// InFlight returns the number of requests currently being served.
+func (m *Metrics) InFlight() int64 {
+	return m.inFlight.Load()
+}
+
+// inFlightMiddleware tracks how many requests are currently being served.
+func inFlightMiddleware(m *Metrics, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		m.inFlight.Add(1)
+		defer m.inFlight.Add(-1)
+		next.ServeHTTP(w, req)
+	})
+}
+
+// metricsHandler serves the metrics in the Prometheus text exposition format.
+func metricsHandler(m *Metrics, w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# HELP weather_requests_in_flight Number of requests currently being served.")
+	fmt.Fprintln(w, "# TYPE weather_requests_in_flight gauge")
+	fmt.Fprintf(w, "weather_requests_in_flight %d\n", m.InFlight())
+	fmt.Fprintln(w, "# HELP weather_cache_hits_total Number of /weather responses served from the cache.")
+	fmt.Fprintln(w, "# TYPE weather_cache_hits_total counter")
+	fmt.Fprintf(w, "weather_cache_hits_total %d\n", m.cacheHits.Load())
+	fmt.Fprintln(w, "# HELP weather_cache_misses_total Number of /weather responses not found in the cache.")
+	fmt.Fprintln(w, "# TYPE weather_cache_misses_total counter")
+	fmt.Fprintf(w, "weather_cache_misses_total %d\n", m.cacheMisses.Load())
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// newRequestID returns a random 16-character hex identifier.
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// loggingMiddleware logs every request and records it in the given RequestLog.
+// An incoming X-Request-ID is reused; otherwise a new one is generated and
+// echoed back on the response.
+func loggingMiddleware(l *RequestLog, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requestID := req.Header.Get("X-Request-ID")
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, req)
+
+		entry := RequestLogEntry{
+			RequestID: requestID,
+			Method:    req.Method,
+			Path:      req.URL.Path,
+			Status:    rec.Status(),
+			Duration:  time.Since(start),
+			Time:      start,
+		}
+		l.Add(entry)
+		slog.Info("Handled request", "method", entry.Method, "path", entry.Path, "status", entry.Status, "duration", entry.Duration, "request_id", entry.RequestID)
+	})
+}
+
+// concurrencyLimitMiddleware allows at most limit concurrent requests through
+// to next, answering 503 immediately once the limit is reached.
+func concurrencyLimitMiddleware(limit int, next http.Handler) http.Handler {
+	sem := make(chan struct{}, limit)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+		default:
+			slog.Warn("Concurrency limit reached, rejecting request", "limit", limit)
+			w.Header().Set("Content-Type", "application/json")
+			writeJSON(w, http.StatusServiceUnavailable, DataResponse{
+				Message: fmt.Sprintf("Server is saturated: more than %d concurrent requests.", limit),
+			})
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// trailingSlashMiddleware strips a trailing slash from the request path, so
+// /weather/ is served exactly like /weather. The path is rewritten rather than
+// redirected so POST bodies and methods survive unchanged. The root path is
+// left alone.
+func trailingSlashMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if len(req.URL.Path) > 1 && strings.HasSuffix(req.URL.Path, "/") {
+			req.URL.Path = strings.TrimRight(req.URL.Path, "/")
+			if req.URL.Path == "" {
+				req.URL.Path = "/"
+			}
+			req.URL.RawPath = strings.TrimRight(req.URL.RawPath, "/")
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// connectionCloseMiddleware honours ?connection=close on any endpoint by
+// sending Connection: close, which makes net/http close the TCP connection
+// after the response instead of keeping it alive for reuse. HTTP/2 has no
+// per-request connection control, so the header is dropped there.
+func connectionCloseMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.EqualFold(req.URL.Query().Get("connection"), "close") {
+			w.Header().Set("Connection", "close")
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// serverHeaderMiddleware sets the Server response header to value.
+func serverHeaderMiddleware(value string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Server", value)
+		next.ServeHTTP(w, req)
+	})
+}
+
+// clockSkewMiddleware sets the Date response header to the current time
+// shifted by skew, so clients can be tested against a server whose clock is
+// off. net/http only adds its own Date header when none is set.
+func clockSkewMiddleware(skew time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Date", time.Now().Add(skew).UTC().Format(http.TimeFormat))
+		next.ServeHTTP(w, req)
+	})
+}
+
+// maxBodyMiddleware caps request bodies at limit bytes with
+// http.MaxBytesReader; handlers report larger bodies as 413. A limit of 0 or
+// less disables the cap. It goes inside decompressRequestMiddleware, so the
+// limit applies to the decompressed body and a small gzip body can't inflate
+// past it.
+func maxBodyMiddleware(limit int64, next http.Handler) http.Handler {
+	if limit <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.Body = http.MaxBytesReader(w, req.Body, limit)
+		next.ServeHTTP(w, req)
+	})
+}
+
+// decompressRequestMiddleware transparently decompresses request bodies sent
+// with Content-Encoding: gzip, so handlers decode JSON as usual. A body that
+// is not valid gzip is rejected with 400.
+func decompressRequestMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.EqualFold(req.Header.Get("Content-Encoding"), "gzip") {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		body, err := gzip.NewReader(req.Body)
+		if err != nil {
+			status, message := requestBodyError(err)
+			if status == http.StatusBadRequest {
+				message = fmt.Sprintf("Malformed gzip body: %v", err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			writeJSON(w, status, DataResponse{Message: message})
+			return
+		}
+		defer body.Close()
+
+		req.Body = body
+		req.Header.Del("Content-Encoding")
+		req.Header.Del("Content-Length")
+		req.ContentLength = -1
+		next.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRequestLogBounded tests that the request log keeps only the newest entries.
+func TestRequestLogBounded(t *testing.T) {
+	l := NewRequestLog(2)
+	l.Add(RequestLogEntry{RequestID: "a"})
+	l.Add(RequestLogEntry{RequestID: "b"})
+	l.Add(RequestLogEntry{RequestID: "c"})
+
+	entries := l.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Request log returned unexpected number of entries: got %d want %d", len(entries), 2)
+	}
+	if entries[0].RequestID != "b" || entries[1].RequestID != "c" {
+		t.Errorf("Request log returned unexpected entries: got %v, %v want b, c", entries[0].RequestID, entries[1].RequestID)
+	}
+}
+
+// TestLoggingMiddlewareRecordsRequests tests that requests show up on /debug/requests.
+func TestLoggingMiddlewareRecordsRequests(t *testing.T) {
+	l := NewRequestLog(10)
+	handler := loggingMiddleware(l, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := httptest.NewRequest("GET", "/weather", nil)
+	req.Header.Set("X-Request-ID", "abc123")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("X-Request-ID"); got != "abc123" {
+		t.Errorf("Middleware returned wrong request ID: got %v want %v", got, "abc123")
+	}
+
+	rr = httptest.NewRecorder()
+	debugRequestsHandler(l, rr, httptest.NewRequest("GET", "/debug/requests", nil))
+
+	var entries []RequestLogEntry
+	if err := json.NewDecoder(rr.Body).Decode(&entries); err != nil {
+		t.Fatalf("Could not decode request log: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Request log returned unexpected number of entries: got %d want %d", len(entries), 1)
+	}
+	e := entries[0]
+	if e.Method != "GET" || e.Path != "/weather" || e.Status != http.StatusTeapot || e.RequestID != "abc123" {
+		t.Errorf("Request log returned unexpected entry: %+v", e)
+	}
+}
+
+// TestParseLogLevel tests parsing of WEATHER_LOG_LEVEL values.
+func TestParseLogLevel(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected slog.Level
+		wantErr  bool
+	}{
+		{"", slog.LevelInfo, false},
+		{"info", slog.LevelInfo, false},
+		{"DEBUG", slog.LevelDebug, false},
+		{"error", slog.LevelError, false},
+		{"verbose", slog.LevelInfo, true},
+	}
+
+	for _, tc := range testCases {
+		level, err := parseLogLevel(tc.value)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseLogLevel(%q) returned unexpected error: %v", tc.value, err)
+		}
+		if level != tc.expected {
+			t.Errorf("parseLogLevel(%q) returned wrong level: got %v want %v", tc.value, level, tc.expected)
+		}
+	}
+}
+
+// TestInFlightMiddleware tests that the in-flight gauge tracks running requests.
+func TestInFlightMiddleware(t *testing.T) {
+	m := &Metrics{}
+	var during int64
+	handler := inFlightMiddleware(m, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		during = m.InFlight()
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/weather", nil))
+
+	if during != 1 {
+		t.Errorf("In-flight gauge during request: got %d want %d", during, 1)
+	}
+	if got := m.InFlight(); got != 0 {
+		t.Errorf("In-flight gauge after request: got %d want %d", got, 0)
+	}
+
+	rr := httptest.NewRecorder()
+	metricsHandler(m, rr, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(rr.Body.String(), "weather_requests_in_flight 0") {
+		t.Errorf("Metrics output is missing the in-flight gauge: %s", rr.Body.String())
+	}
+}
+
+// TestConcurrencyLimitMiddleware tests that requests over the limit get 503.
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	handler := concurrencyLimitMiddleware(1, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/weather", nil))
+		close(done)
+	}()
+	<-entered
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/weather", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Saturated handler returned wrong status code: got %v want %v", rr.Code, http.StatusServiceUnavailable)
+	}
+
+	close(release)
+	<-done
+
+	// The slot is released once the first request finishes.
+	go func() { <-entered }()
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/weather", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Handler returned wrong status code after release: got %v want %v", rr.Code, http.StatusOK)
+	}
+}
+
+// TestAccessLogMiddleware tests the combined-format access log line.
+func TestAccessLogMiddleware(t *testing.T) {
+	var out strings.Builder
+	handler := accessLogMiddleware(&out, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest("GET", "/weather?size=50", nil)
+	req.RemoteAddr = "192.0.2.1:54321"
+	req.Header.Set("User-Agent", "test-agent")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := out.String()
+	pattern := regexp.MustCompile(`^192\.0\.2\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /weather\?size=50 HTTP/1\.1" 201 5 "" "test-agent" \d+\.\d{3}\n$`)
+	if !pattern.MatchString(line) {
+		t.Errorf("Access log line has unexpected format: %q", line)
+	}
+}
+
+// TestDecompressRequestMiddleware tests that gzip request bodies are decoded
+// and malformed gzip is rejected.
+func TestDecompressRequestMiddleware(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte(`{"size":20,"city":"Tokyo"}`))
+	zw.Close()
+
+	handler := decompressRequestMiddleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusOK}, w, req)
+	}))
+
+	req := httptest.NewRequest("POST", "/weather", &compressed)
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	var response DataResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	if len(response.Readings) != 20 || response.Readings[0].City != "Tokyo" {
+		t.Errorf("Handler ignored the compressed body: got %+v", response.Readings)
+	}
+
+	req = httptest.NewRequest("POST", "/weather", strings.NewReader(`{"size":3}`))
+	req.Header.Set("Content-Encoding", "gzip")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code for malformed gzip: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+// TestMaxBodyMiddleware tests that bodies over the limit get 413 on the POST
+// endpoints while smaller ones are decoded normally.
+func TestMaxBodyMiddleware(t *testing.T) {
+	svc := &WeatherService{
+		Sleeper:      sleeper,
+		Chooser:      &FixedStatusChooser{Status: http.StatusOK},
+		RequestLog:   NewRequestLog(10),
+		Metrics:      &Metrics{},
+		Health:       &HealthChecker{},
+		MaxBodyBytes: 64,
+	}
+	handler := svc.Handler()
+
+	testCases := []struct {
+		name     string
+		target   string
+		body     string
+		expected int
+	}{
+		{"WeatherSmall", "/weather", `{"size":20}`, http.StatusOK},
+		{"WeatherLarge", "/weather", `{"city":"` + strings.Repeat("x", 100) + `"}`, http.StatusRequestEntityTooLarge},
+		{"ValidateLarge", "/weather/validate", `{"city":"` + strings.Repeat("x", 100) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("POST", tc.target, strings.NewReader(tc.body)))
+
+			if rr.Code != tc.expected {
+				t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, tc.expected)
+			}
+		})
+	}
+
+	// The limit applies to the decompressed body, not the gzip bytes.
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte(`{"city":"` + strings.Repeat("x", 10000) + `"}`))
+	zw.Close()
+	if compressed.Len() > 64 {
+		t.Fatalf("Compressed body is %d bytes, want it under the limit", compressed.Len())
+	}
+	req := httptest.NewRequest("POST", "/weather", &compressed)
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Handler returned wrong status code for an inflating body: got %v want %v", rr.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+// TestClockSkewMiddleware tests that the Date header is offset by the skew and
+// that generated timestamps follow it only when requested.
+func TestClockSkewMiddleware(t *testing.T) {
+	for _, skewTimestamps := range []bool{false, true} {
+		svc := &WeatherService{
+			Sleeper:        sleeper,
+			Chooser:        &FixedStatusChooser{Status: http.StatusOK},
+			RequestLog:     NewRequestLog(10),
+			Metrics:        &Metrics{},
+			Health:         &HealthChecker{},
+			ClockSkew:      -48 * time.Hour,
+			SkewTimestamps: skewTimestamps,
+		}
+		rr := httptest.NewRecorder()
+		svc.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/weather", nil))
+
+		date, err := http.ParseTime(rr.Header().Get("Date"))
+		if err != nil {
+			t.Fatalf("Could not parse Date header %q: %v", rr.Header().Get("Date"), err)
+		}
+		if offset := time.Until(date); offset > -47*time.Hour || offset < -49*time.Hour {
+			t.Errorf("Date header is offset by %v, want about %v", offset, -48*time.Hour)
+		}
+
+		var responseData DataResponse
+		if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+			t.Fatalf("Could not decode response: %v", err)
+		}
+		for _, reading := range responseData.Readings {
+			// Readings are within 12 hours of the (possibly skewed) current time.
+			if skewed := time.Until(reading.Timestamp) < -24*time.Hour; skewed != skewTimestamps {
+				t.Errorf("Reading timestamp %v skewed=%v, want %v", reading.Timestamp, skewed, skewTimestamps)
+			}
+		}
+	}
+}
+
+// TestTrailingSlashMiddleware tests that paths with a trailing slash are
+// served like the same path without one.
+func TestTrailingSlashMiddleware(t *testing.T) {
+	svc := &WeatherService{Sleeper: sleeper, Chooser: &FixedStatusChooser{Status: http.StatusOK}, RequestLog: NewRequestLog(10), Metrics: &Metrics{}, Health: &HealthChecker{}}
+	handler := svc.Handler()
+
+	testCases := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"GET", "/weather/", "", http.StatusOK},
+		{"GET", "/v1/health/", "", http.StatusOK},
+		{"GET", "/weather/Tokyo//", "", http.StatusOK},
+		{"POST", "/weather/", `{"size":20}`, http.StatusOK},
+		{"GET", "/", "", http.StatusOK},
+		{"GET", "/nope/", "", http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method+tc.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+
+			if rr.Code != tc.status {
+				t.Errorf("Handler returned wrong status code for %s %s: got %v want %v", tc.method, tc.path, rr.Code, tc.status)
+			}
+		})
+	}
+}
+
+// TestServerHeader tests that the Server header is sent when configured and
+// omitted when empty.
+func TestServerHeader(t *testing.T) {
+	for _, value := range []string{"go-weather/1.2.3", ""} {
+		svc := &WeatherService{Sleeper: sleeper, Chooser: chooser, RequestLog: NewRequestLog(10), Metrics: &Metrics{}, Health: &HealthChecker{}, ServerHeader: value}
+		rr := httptest.NewRecorder()
+		svc.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+
+		if got := rr.Header().Get("Server"); got != value {
+			t.Errorf("Handler returned wrong Server header: got %q want %q", got, value)
+		}
+	}
+}
+
+// TestConnectionClose tests that connection=close stops the client from
+// reusing the TCP connection, while plain requests reuse it.
+func TestConnectionClose(t *testing.T) {
+	svc := &WeatherService{Sleeper: sleeper, Chooser: chooser, RequestLog: NewRequestLog(10), Metrics: &Metrics{}, Health: &HealthChecker{}}
+	server := httptest.NewUnstartedServer(svc.Handler())
+	var mu sync.Mutex
+	conns := 0
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	countConns := func(path string) int {
+		mu.Lock()
+		conns = 0
+		mu.Unlock()
+		client := &http.Client{Transport: &http.Transport{}}
+		defer client.CloseIdleConnections()
+		for i := 0; i < 3; i++ {
+			resp, err := client.Get(server.URL + path)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return conns
+	}
+
+	if got := countConns("/health"); got != 1 {
+		t.Errorf("Keep-alive requests opened %d connections, want 1", got)
+	}
+	if got := countConns("/health?connection=close"); got != 3 {
+		t.Errorf("connection=close requests opened %d connections, want 3", got)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import "math/rand"
+
+// requiredReadingFields are the JSON fields omitFields can drop.
+var requiredReadingFields = []string{"city", "timestamp", "temperature", "humidity", "condition"}
+
+// omitRandomFields leaves, with probability one half, one randomly chosen
+// required field out of each reading.
+func omitRandomFields(partial []readingView, r *rand.Rand) {
+	for i := range partial {
+		if r.Intn(2) == 0 {
+			continue
+		}
+		switch requiredReadingFields[r.Intn(len(requiredReadingFields))] {
+		case "city":
+			partial[i].City = nil
+		case "timestamp":
+			partial[i].Timestamp = nil
+		case "temperature":
+			partial[i].Temperature = nil
+		case "humidity":
+			partial[i].Humidity = nil
+		case "condition":
+			partial[i].Condition = nil
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestWeatherHandlerOmitFields tests that omitFields=true drops at most one
+// required field per reading, and some fields overall, keeping a 200.
+func TestWeatherHandlerOmitFields(t *testing.T) {
+	req := httptest.NewRequest("GET", "/weather?omitFields=true&size=100", nil)
+	rr := httptest.NewRecorder()
+	weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusOK}, rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var responseData struct {
+		Readings []map[string]json.RawMessage `json:"readings"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+
+	incomplete := 0
+	for i, reading := range responseData.Readings {
+		missing := 0
+		for _, field := range requiredReadingFields {
+			if _, ok := reading[field]; !ok {
+				missing++
+			}
+		}
+		if missing > 1 {
+			t.Errorf("Reading %d lacks %d required fields, want at most 1", i, missing)
+		}
+		if missing == 1 {
+			incomplete++
+		}
+	}
+	// With 100 readings and a one in two chance each, none missing is vanishingly unlikely.
+	if incomplete == 0 {
+		t.Errorf("No reading lacks a required field")
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// oneReading serves GET /weather/one?city=, which returns a single reading
+// for the city as a bare WeatherReading object rather than a DataResponse,
+// for clients that expect an object. Errors keep the DataResponse shape.
+func (svc *WeatherService) oneReading(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	city, ok := lookupCity(req.URL.Query().Get("city"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, DataResponse{
+			Message: fmt.Sprintf("Unknown or missing 'city' parameter: %q.", req.URL.Query().Get("city")),
+		})
+		return
+	}
+	if svc.Availability.Offline(city) {
+		writeJSON(w, http.StatusServiceUnavailable, DataResponse{Message: fmt.Sprintf("City %s is offline for scheduled maintenance", city)})
+		return
+	}
+
+	rng := svc.rand()
+	reading := appendDummyWeatherReadings(nil, rng, 1, svc.now())[0]
+	reading.City = city
+	reading.StationID = pickStation(rng, city, svc.StationsPerCity)
+	reading.ID = readingID(reading)
+	slog.Info("Responding with a single reading", "city", city)
+	writeJSON(w, http.StatusOK, reading)
+}
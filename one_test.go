@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestOneReading tests that /weather/one returns a bare reading for a valid
+// city and rejects unknown or missing cities.
+func TestOneReading(t *testing.T) {
+	handler := newTestService(1, nil, nil).Handler()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/weather/one?city=tokyo", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &fields); err != nil {
+		t.Fatalf("Response is not a JSON object: %v", err)
+	}
+	if _, wrapped := fields["readings"]; wrapped {
+		t.Errorf("Response is wrapped in a DataResponse: %s", rr.Body)
+	}
+	var reading WeatherReading
+	if err := json.Unmarshal(rr.Body.Bytes(), &reading); err != nil {
+		t.Fatalf("Could not decode reading: %v", err)
+	}
+	if reading.City != "Tokyo" || reading.ID != readingID(reading) {
+		t.Errorf("Handler returned wrong reading: %+v", reading)
+	}
+	if violations := validateReading(reading); len(violations) > 0 {
+		t.Errorf("Reading is invalid: %v", violations)
+	}
+
+	for _, target := range []string{"/weather/one", "/weather/one?city=Atlantis"} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s returned wrong status code: got %v want %v", target, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
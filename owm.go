@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// compatOWM is the compat option value that shapes /weather responses like
+// OpenWeatherMap's current weather API.
+const compatOWM = "owm"
+
+// owmCondition is an OpenWeatherMap weather condition.
+type owmCondition struct {
+	ID          int    `json:"id"`
+	Main        string `json:"main"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+}
+
+// owmConditions maps each condition to its closest OpenWeatherMap condition.
+var owmConditions = map[string]owmCondition{
+	"Sunny":         {ID: 800, Main: "Clear", Description: "clear sky"},
+	"Partly Cloudy": {ID: 802, Main: "Clouds", Description: "scattered clouds"},
+	"Cloudy":        {ID: 804, Main: "Clouds", Description: "overcast clouds"},
+	"Rainy":         {ID: 500, Main: "Rain", Description: "light rain"},
+	"Stormy":        {ID: 211, Main: "Thunderstorm", Description: "thunderstorm"},
+	"Foggy":         {ID: 741, Main: "Fog", Description: "fog"},
+	"Snowy":         {ID: 600, Main: "Snow", Description: "light snow"},
+}
+
+// owmCoord is a location in OpenWeatherMap's coord object.
+type owmCoord struct {
+	Lon float64 `json:"lon"`
+	Lat float64 `json:"lat"`
+}
+
+// cityCoords holds the approximate coordinates of each city.
+var cityCoords = map[string]owmCoord{
+	"New York": {Lon: -74.006, Lat: 40.7143},
+	"London":   {Lon: -0.1257, Lat: 51.5085},
+	"Paris":    {Lon: 2.3488, Lat: 48.8534},
+	"Tokyo":    {Lon: 139.6917, Lat: 35.6895},
+	"Sydney":   {Lon: 151.2073, Lat: -33.8679},
+	"Lagos":    {Lon: 3.3947, Lat: 6.4541},
+	"Dubai":    {Lon: 55.3047, Lat: 25.2582},
+	"Rio":      {Lon: -43.2075, Lat: -22.9028},
+}
+
+// owmMain is OpenWeatherMap's main measurements object.
+type owmMain struct {
+	Temp     float64 `json:"temp"`
+	Humidity int     `json:"humidity"`
+}
+
+// OWMWeather is a reading shaped like an OpenWeatherMap current weather response.
+type OWMWeather struct {
+	Coord   owmCoord       `json:"coord"`
+	Weather []owmCondition `json:"weather"`
+	Main    owmMain        `json:"main"`
+	Dt      int64          `json:"dt"`
+	Name    string         `json:"name"`
+	Cod     int            `json:"cod"`
+}
+
+// OWMError is an error shaped like an OpenWeatherMap error response, which
+// reports the status code as a string.
+type OWMError struct {
+	Cod     string `json:"cod"`
+	Message string `json:"message"`
+}
+
+// toOWMWeather translates a reading into the OpenWeatherMap shape.
+func toOWMWeather(reading WeatherReading) OWMWeather {
+	condition := owmConditions[reading.Condition]
+	condition.Icon = conditionIcons[reading.Condition]
+	return OWMWeather{
+		Coord:   cityCoords[reading.City],
+		Weather: []owmCondition{condition},
+		Main:    owmMain{Temp: reading.Temperature, Humidity: reading.Humidity},
+		Dt:      reading.Timestamp.Unix(),
+		Name:    reading.City,
+		Cod:     200,
+	}
+}
+
+// owmResponse translates a /weather response into the OpenWeatherMap shape:
+// the first reading for a success, since OpenWeatherMap returns a single
+// location, or an error object otherwise.
+func owmResponse(status int, data DataResponse) any {
+	if status >= 200 && status < 300 && len(data.Readings) > 0 {
+		return toOWMWeather(data.Readings[0])
+	}
+	return OWMError{Cod: strconv.Itoa(status), Message: strings.ToLower(data.Message)}
+}
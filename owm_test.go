@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestOWMMappingsComplete tests that every city and condition has an
+// OpenWeatherMap translation.
+func TestOWMMappingsComplete(t *testing.T) {
+	for _, city := range cities {
+		if _, ok := cityCoords[city]; !ok {
+			t.Errorf("City %q has no coordinates", city)
+		}
+	}
+	for _, condition := range conditions {
+		if _, ok := owmConditions[condition]; !ok {
+			t.Errorf("Condition %q has no OpenWeatherMap condition", condition)
+		}
+	}
+}
+
+// TestWeatherHandlerCompatOWM tests the OpenWeatherMap-shaped success and
+// error responses.
+func TestWeatherHandlerCompatOWM(t *testing.T) {
+	req := httptest.NewRequest("GET", "/weather?compat=owm&city=London&at=2024-01-01T00:00:00Z", nil)
+	rr := httptest.NewRecorder()
+	weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusOK}, rr, req)
+
+	var weather OWMWeather
+	if err := json.NewDecoder(rr.Body).Decode(&weather); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	if weather.Name != "London" || weather.Cod != 200 || weather.Coord != cityCoords["London"] {
+		t.Errorf("Response has wrong location: got %+v", weather)
+	}
+	if len(weather.Weather) != 1 || weather.Weather[0].Description == "" || weather.Weather[0].Icon == "" {
+		t.Errorf("Response has wrong weather conditions: got %+v", weather.Weather)
+	}
+	if weather.Main.Humidity < 20 || weather.Main.Humidity > 99 {
+		t.Errorf("Response has humidity out of range: got %d", weather.Main.Humidity)
+	}
+	if offset := weather.Dt - 1704067200; offset < -12*3600 || offset > 12*3600 {
+		t.Errorf("Response dt %d is not within 12 hours of at", weather.Dt)
+	}
+
+	req = httptest.NewRequest("GET", "/weather?compat=owm", nil)
+	rr = httptest.NewRecorder()
+	weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusServiceUnavailable}, rr, req)
+
+	var owmErr OWMError
+	if err := json.NewDecoder(rr.Body).Decode(&owmErr); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	if owmErr.Cod != "503" || owmErr.Message == "" {
+		t.Errorf("Response has wrong error: got %+v", owmErr)
+	}
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Temperature units accepted by the units option.
+const (
+	unitsCelsius    = "celsius"
+	unitsFahrenheit = "fahrenheit"
+)
+
+// WeatherOptions are the options a client can pass to /weather, either as
+// query parameters on GET or as a JSON body on POST.
+type WeatherOptions struct {
+	Size        *int     `json:"size,omitempty"`
+	Units       string   `json:"units,omitempty"`
+	City        string   `json:"city,omitempty"`
+	DelayMs     *int     `json:"delayMs,omitempty"`
+	MinDelay    *int     `json:"minDelay,omitempty"`
+	MaxDelay    *int     `json:"maxDelay,omitempty"`
+	Seed        *int64   `json:"seed,omitempty"`
+	Status      *int     `json:"status,omitempty"`
+	KeepAlive   bool     `json:"keepAlive,omitempty"`
+	BadJSON     bool     `json:"badjson,omitempty"`
+	ContentType string   `json:"contentType,omitempty"`
+	AnomalyRate *float64 `json:"anomalyRate,omitempty"`
+	DupRate     *float64 `json:"dupRate,omitempty"`
+	Icons       bool     `json:"icons,omitempty"`
+	At          string   `json:"at,omitempty"`
+	Compat      string   `json:"compat,omitempty"`
+	OmitFields  bool     `json:"omitFields,omitempty"`
+	SoftError   bool     `json:"softError,omitempty"`
+	GroupBy     string   `json:"groupBy,omitempty"`
+	Truncate    bool     `json:"truncate,omitempty"`
+	EchoHeaders bool     `json:"echoHeaders,omitempty"`
+	BodyDelay   string   `json:"bodyDelay,omitempty"`
+	Attribution bool     `json:"attribution,omitempty"`
+	// HumidityPrecision is humidityInt or humidityFloat.
+	HumidityPrecision string `json:"humidityPrecision,omitempty"`
+	ShortBody         bool   `json:"shortBody,omitempty"`
+	Season            string `json:"season,omitempty"`
+	CPUMs             *int   `json:"cpuMs,omitempty"`
+	Stream            string `json:"stream,omitempty"`
+	DecompressBomb    bool   `json:"decompressBomb,omitempty"`
+	SizeMB            *int   `json:"sizeMB,omitempty"`
+	DistinctCities    bool   `json:"distinctCities,omitempty"`
+	MinCities         *int   `json:"minCities,omitempty"`
+	MaxCities         *int   `json:"maxCities,omitempty"`
+}
+
+// weatherParams are WeatherOptions after validation, with defaults applied.
+type weatherParams struct {
+	size          int
+	units         string
+	city          string // Empty means any city
+	minCities     int    // Fewest distinct cities; zero draws cities with replacement
+	maxCities     int    // Most distinct cities
+	minDelay      int    // Milliseconds
+	maxDelay      int    // Milliseconds
+	seed          *int64 // Nil means the shared random source
+	status        int    // Zero means the status chooser decides; see chooseRequestedStatus
+	keepAlive     bool
+	badJSON       bool
+	contentType   string
+	anomalyRate   float64 // Probability of an out-of-band temperature per reading
+	dupRate       float64 // Probability of repeating an earlier reading
+	icons         bool
+	at            time.Time // Zero means the current time
+	compat        string    // Empty for the native shape, or compatOWM
+	omitFields    bool
+	softError     bool          // Respond 200 with an error-shaped body
+	itemRange     *itemRange    // From the Range header; nil serves every reading
+	groupBy       string        // Empty for a flat array, or groupByCity
+	truncate      bool          // Cut the body off halfway
+	lang          string        // From Accept-Language; empty or "en" for English
+	bodyDelay     time.Duration // Pause before each body chunk; zero writes the body at once
+	attribution   bool          // Add the data source and attribution
+	floatHumidity bool          // Humidity with a decimal place
+	shortBody     bool          // Advertise a longer Content-Length than is sent
+	season        string        // Empty for the configured condition weights
+	cpu           time.Duration // Busy-loop this long instead of sleeping
+	stream        string        // Empty for a buffered body, or streamArray
+	bombBytes     int64         // Decompressed size of a decompression bomb; zero sends none
+	// requestHeaders are echoed in the response; nil unless echoHeaders is
+	// set in debug mode.
+	requestHeaders map[string][]string
+}
+
+// parseWeatherQuery reads WeatherOptions from query parameters. Non-numeric
+// size and delay values are logged and ignored, so they fall back to defaults.
+func parseWeatherQuery(q url.Values) (WeatherOptions, error) {
+	opts := WeatherOptions{
+		Units:             q.Get("units"),
+		City:              q.Get("city"),
+		KeepAlive:         q.Get("keepAlive") == "true",
+		BadJSON:           q.Get("badjson") == "true",
+		ContentType:       q.Get("contentType"),
+		Icons:             q.Get("icons") == "true",
+		At:                q.Get("at"),
+		Compat:            q.Get("compat"),
+		OmitFields:        q.Get("omitFields") == "true",
+		SoftError:         q.Get("softError") == "true",
+		GroupBy:           q.Get("groupBy"),
+		Truncate:          q.Get("truncate") == "true",
+		EchoHeaders:       q.Get("echoHeaders") == "true",
+		BodyDelay:         q.Get("bodyDelay"),
+		Attribution:       q.Get("attribution") == "true",
+		HumidityPrecision: q.Get("humidityPrecision"),
+		ShortBody:         q.Get("shortBody") == "true",
+		Season:            q.Get("season"),
+		Stream:            q.Get("stream"),
+		DecompressBomb:    q.Get("decompressBomb") == "true",
+		DistinctCities:    q.Get("distinctCities") == "true",
+	}
+	opts.Size = intQueryParam(q, "size")
+	opts.DelayMs = intQueryParam(q, "delayMs")
+	opts.MinDelay = intQueryParam(q, "minDelay")
+	opts.MaxDelay = intQueryParam(q, "maxDelay")
+	opts.Status = intQueryParam(q, "status")
+	opts.CPUMs = intQueryParam(q, "cpuMs")
+	opts.SizeMB = intQueryParam(q, "sizeMB")
+	opts.MinCities = intQueryParam(q, "minCities")
+	opts.MaxCities = intQueryParam(q, "maxCities")
+	opts.AnomalyRate = floatQueryParam(q, "anomalyRate")
+	opts.DupRate = floatQueryParam(q, "dupRate")
+
+	if seedStr := q.Get("seed"); seedStr != "" {
+		seed, err := strconv.ParseInt(seedStr, 10, 64)
+		if err != nil {
+			return opts, errorOf(ErrInvalidParam, "invalid 'seed' parameter %q, expected an integer", seedStr)
+		}
+		opts.Seed = &seed
+	}
+	return opts, nil
+}
+
+// intQueryParam parses the named query parameter as an integer, returning nil
+// when it is missing or not a number.
+func intQueryParam(q url.Values, name string) *int {
+	valueStr := q.Get(name)
+	if valueStr == "" {
+		return nil
+	}
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		slog.Warn("Ignoring non-numeric parameter", "param", name, "value", valueStr)
+		return nil
+	}
+	return &value
+}
+
+// floatQueryParam parses the named query parameter as a float, returning nil
+// when it is missing or not a number.
+func floatQueryParam(q url.Values, name string) *float64 {
+	valueStr := q.Get(name)
+	if valueStr == "" {
+		return nil
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		slog.Warn("Ignoring non-numeric parameter", "param", name, "value", valueStr)
+		return nil
+	}
+	return &value
+}
+
+// paramError lists every invalid or contradictory option in a request.
+type paramError struct {
+	problems []error
+}
+
+func (e *paramError) Error() string {
+	msgs := make([]string, len(e.problems))
+	for i, problem := range e.problems {
+		msgs[i] = problem.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns every problem, so errors.Is matches any of their kinds.
+func (e *paramError) Unwrap() []error {
+	return e.problems
+}
+
+// checkSize returns an ErrInvalidSize error unless size is within the
+// configured bounds, 10 to 100 by default.
+func checkSize(size int) error {
+	current := currentSettings()
+	if size < current.MinSize || size > current.MaxSize {
+		return errorOf(ErrInvalidSize, "invalid 'size' parameter %d, expected %d to %d", size, current.MinSize, current.MaxSize)
+	}
+	return nil
+}
+
+// resolveWeatherOptions validates opts and applies defaults. It is the single
+// validation step shared by every request shape (query string, JSON body,
+// X-Chaos and Prefer headers), so they behave identically. Out-of-range sizes and delays
+// fall back to their defaults; unknown values and contradictory combinations
+// are all collected into one *paramError.
+func resolveWeatherOptions(opts WeatherOptions) (weatherParams, error) {
+	current := currentSettings()
+	p := weatherParams{
+		size:        current.DefaultSize,
+		units:       unitsCelsius,
+		seed:        opts.Seed,
+		keepAlive:   opts.KeepAlive,
+		badJSON:     opts.BadJSON,
+		icons:       opts.Icons,
+		omitFields:  opts.OmitFields,
+		softError:   opts.SoftError,
+		truncate:    opts.Truncate,
+		attribution: opts.Attribution,
+		shortBody:   opts.ShortBody,
+		contentType: "application/json",
+	}
+	var problems []error
+
+	// Only allow content-type overrides from the allowlist.
+	if opts.ContentType != "" {
+		if allowedContentTypes[opts.ContentType] {
+			p.contentType = opts.ContentType
+		} else {
+			slog.Warn("Ignoring unsupported 'contentType' override", "contentType", opts.ContentType)
+		}
+	}
+
+	if opts.Size != nil {
+		if err := checkSize(*opts.Size); err != nil {
+			slog.Warn("Invalid 'size' parameter, using the default", "size", *opts.Size, "default", current.DefaultSize)
+		} else {
+			p.size = *opts.Size
+		}
+	}
+
+	switch strings.ToLower(opts.Units) {
+	case "", unitsCelsius, "metric":
+	case unitsFahrenheit, "imperial":
+		p.units = unitsFahrenheit
+	default:
+		problems = append(problems, errorOf(ErrInvalidParam, "invalid 'units' parameter %q, expected celsius or fahrenheit", opts.Units))
+	}
+
+	if opts.City != "" {
+		city, ok := lookupCity(opts.City)
+		if !ok {
+			problems = append(problems, errorOf(ErrUnknownCity, "unknown 'city' parameter %q", opts.City))
+		}
+		p.city = city
+	}
+
+	// minCities and maxCities imply distinctCities.
+	if opts.DistinctCities || opts.MinCities != nil || opts.MaxCities != nil {
+		p.minCities, p.maxCities = len(cities), len(cities)
+		if opts.MinCities != nil || opts.MaxCities != nil {
+			p.minCities = 1
+		}
+		if opts.MinCities != nil {
+			p.minCities = *opts.MinCities
+		}
+		if opts.MaxCities != nil {
+			p.maxCities = *opts.MaxCities
+		}
+		if p.minCities < 1 || p.maxCities > len(cities) || p.minCities > p.maxCities {
+			problems = append(problems, errorOf(ErrInvalidParam, "invalid 'minCities' %d and 'maxCities' %d, expected 1 <= minCities <= maxCities <= %d", p.minCities, p.maxCities, len(cities)))
+		}
+		if opts.City != "" {
+			problems = append(problems, errorOf(ErrConflictingParams, "distinctCities, minCities and maxCities conflict with city"))
+		}
+	}
+
+	if opts.Status != nil {
+		if *opts.Status < 200 || *opts.Status > 599 {
+			problems = append(problems, errorOf(ErrInvalidParam, "invalid 'status' parameter %d, expected 200 to 599", *opts.Status))
+		}
+		p.status = *opts.Status
+		if p.status == http.StatusNoContent && opts.BadJSON {
+			problems = append(problems, errorOf(ErrConflictingParams, "badjson conflicts with status 204, which has no body"))
+		}
+		if p.status == http.StatusNoContent && opts.Truncate {
+			problems = append(problems, errorOf(ErrConflictingParams, "truncate conflicts with status 204, which has no body"))
+		}
+		if p.status == http.StatusNoContent && opts.ShortBody {
+			problems = append(problems, errorOf(ErrConflictingParams, "shortBody conflicts with status 204, which has no body"))
+		}
+		if p.status != http.StatusOK && opts.SoftError {
+			problems = append(problems, errorOf(ErrConflictingParams, "softError conflicts with any status other than 200"))
+		}
+	}
+
+	if opts.Truncate && (opts.BadJSON || opts.KeepAlive) {
+		problems = append(problems, errorOf(ErrConflictingParams, "truncate conflicts with badjson and keepAlive"))
+	}
+
+	if opts.ShortBody && (opts.BadJSON || opts.KeepAlive || opts.Truncate || opts.BodyDelay != "") {
+		problems = append(problems, errorOf(ErrConflictingParams, "shortBody conflicts with badjson, keepAlive, truncate and bodyDelay"))
+	}
+
+	if opts.BodyDelay != "" {
+		ms, err := parseLatency(opts.BodyDelay)
+		if err != nil || ms < 0 || ms > current.MaxDelayMs {
+			problems = append(problems, errorOf(ErrInvalidParam, "invalid 'bodyDelay' parameter %q, expected a duration or milliseconds up to %d", opts.BodyDelay, current.MaxDelayMs))
+		}
+		p.bodyDelay = time.Duration(ms) * time.Millisecond
+		if opts.KeepAlive || opts.BadJSON || opts.Truncate {
+			problems = append(problems, errorOf(ErrConflictingParams, "bodyDelay conflicts with keepAlive, badjson and truncate"))
+		}
+	}
+
+	switch strings.ToLower(opts.Compat) {
+	case "":
+	case compatOWM:
+		p.compat = compatOWM
+		if opts.OmitFields {
+			problems = append(problems, errorOf(ErrConflictingParams, "omitFields conflicts with compat=owm"))
+		}
+	default:
+		problems = append(problems, errorOf(ErrInvalidParam, "invalid 'compat' parameter %q, expected owm", opts.Compat))
+	}
+
+	switch strings.ToLower(opts.GroupBy) {
+	case "":
+	case groupByCity:
+		p.groupBy = groupByCity
+		if p.compat != "" {
+			problems = append(problems, errorOf(ErrConflictingParams, "groupBy conflicts with compat"))
+		}
+	default:
+		problems = append(problems, errorOf(ErrInvalidParam, "invalid 'groupBy' parameter %q, expected city", opts.GroupBy))
+	}
+
+	switch strings.ToLower(opts.HumidityPrecision) {
+	case "", humidityInt:
+	case humidityFloat:
+		p.floatHumidity = true
+		if p.compat != "" {
+			problems = append(problems, errorOf(ErrConflictingParams, "humidityPrecision=float conflicts with compat"))
+		}
+	default:
+		problems = append(problems, errorOf(ErrInvalidParam, "invalid 'humidityPrecision' parameter %q, expected int or float", opts.HumidityPrecision))
+	}
+
+	if opts.Season != "" {
+		season, ok := lookupSeason(opts.Season)
+		if !ok {
+			problems = append(problems, errorOf(ErrInvalidParam, "invalid 'season' parameter %q, expected winter, spring, summer or autumn", opts.Season))
+		}
+		p.season = season
+	}
+
+	if opts.At != "" {
+		at, err := time.Parse(time.RFC3339, opts.At)
+		if err != nil {
+			problems = append(problems, errorOf(ErrInvalidParam, "invalid 'at' parameter %q, expected an RFC 3339 timestamp", opts.At))
+		}
+		p.at = at
+	}
+
+	p.anomalyRate, problems = rateOption("anomalyRate", opts.AnomalyRate, problems)
+	p.dupRate, problems = rateOption("dupRate", opts.DupRate, problems)
+
+	// An exact delayMs pins the range; otherwise use minDelay/maxDelay.
+	p.minDelay = delayOption("minDelay", opts.MinDelay, 0, current.MaxDelayMs)
+	p.maxDelay = delayOption("maxDelay", opts.MaxDelay, current.DefaultMaxDelayMs, current.MaxDelayMs)
+	if opts.DelayMs != nil {
+		if opts.MinDelay != nil || opts.MaxDelay != nil {
+			problems = append(problems, errorOf(ErrConflictingParams, "delayMs conflicts with minDelay/maxDelay"))
+		}
+		p.minDelay = delayOption("delayMs", opts.DelayMs, p.minDelay, current.MaxDelayMs)
+		p.maxDelay = p.minDelay
+	}
+	if p.minDelay > p.maxDelay {
+		problems = append(problems, errorOf(ErrConflictingParams, "minDelay (%d) must not be greater than maxDelay (%d)", p.minDelay, p.maxDelay))
+	}
+
+	switch strings.ToLower(opts.Stream) {
+	case "":
+	case streamArray:
+		p.stream = streamArray
+		if p.compat != "" || p.groupBy != "" || opts.OmitFields || opts.SoftError || p.floatHumidity {
+			problems = append(problems, errorOf(ErrConflictingParams, "stream=array conflicts with compat, groupBy, omitFields, softError and humidityPrecision=float"))
+		}
+		if opts.KeepAlive || opts.BadJSON || opts.Truncate || opts.ShortBody || opts.BodyDelay != "" {
+			problems = append(problems, errorOf(ErrConflictingParams, "stream=array conflicts with keepAlive, badjson, truncate, shortBody and bodyDelay"))
+		}
+	default:
+		problems = append(problems, errorOf(ErrInvalidParam, "invalid 'stream' parameter %q, expected array", opts.Stream))
+	}
+
+	if opts.DecompressBomb {
+		sizeMB := defaultBombMB
+		if opts.SizeMB != nil {
+			sizeMB = *opts.SizeMB
+			if sizeMB < 1 || sizeMB > maxBombMB {
+				problems = append(problems, errorOf(ErrInvalidParam, "invalid 'sizeMB' parameter %d, expected 1 to %d", sizeMB, maxBombMB))
+			}
+		}
+		p.bombBytes = int64(sizeMB) << 20
+		if p.status == http.StatusNoContent || opts.KeepAlive || opts.BadJSON || opts.Truncate || opts.ShortBody || opts.BodyDelay != "" || p.stream != "" {
+			problems = append(problems, errorOf(ErrConflictingParams, "decompressBomb conflicts with status 204, keepAlive, badjson, truncate, shortBody, bodyDelay and stream"))
+		}
+	}
+
+	// A CPU burn replaces the sleep, so it can't be combined with delays.
+	if opts.CPUMs != nil {
+		if *opts.CPUMs < 0 || *opts.CPUMs > maxCPUMs {
+			problems = append(problems, errorOf(ErrInvalidParam, "invalid 'cpuMs' parameter %d, expected 0 to %d", *opts.CPUMs, maxCPUMs))
+		}
+		p.cpu = time.Duration(*opts.CPUMs) * time.Millisecond
+		if opts.DelayMs != nil || opts.MinDelay != nil || opts.MaxDelay != nil || opts.KeepAlive {
+			problems = append(problems, errorOf(ErrConflictingParams, "cpuMs conflicts with delayMs, minDelay, maxDelay and keepAlive"))
+		}
+	}
+
+	if len(problems) > 0 {
+		return p, &paramError{problems: problems}
+	}
+	return p, nil
+}
+
+// rateOption returns the probability in value, or 0 when it is missing. A
+// value outside 0-1 is appended to problems.
+func rateOption(name string, value *float64, problems []error) (float64, []error) {
+	if value == nil {
+		return 0, problems
+	}
+	if *value < 0 || *value > 1 {
+		problems = append(problems, errorOf(ErrInvalidParam, "invalid '%s' parameter %v, expected 0 to 1", name, *value))
+	}
+	return *value, problems
+}
+
+// delayOption returns the delay in milliseconds, falling back to def when it
+// is missing or outside 0 to limit.
+func delayOption(name string, value *int, def, limit int) int {
+	if value == nil {
+		return def
+	}
+	if *value < 0 || *value > limit {
+		slog.Warn("Invalid delay parameter, using default", "param", name, "value", *value, "default", def)
+		return def
+	}
+	return *value
+}
+
+// celsiusToFahrenheit converts a temperature from Celsius to Fahrenheit.
+func celsiusToFahrenheit(c float64) float64 {
+	return c*9/5 + 32
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// applyPreferHeader applies the preferences in the request's Prefer headers
+// (RFC 7240) to opts and returns the ones that were applied, for the
+// Preference-Applied response header. Supported preferences are:
+//
+//	respond-async  answer 202 Accepted without a delay
+//	latency=500ms  delay exactly this long (a Go duration or milliseconds)
+//	status=200     respond with this status code
+//
+// Options already set by query parameters or the body take precedence, and
+// unknown or invalid preferences are ignored as the RFC requires.
+func applyPreferHeader(h http.Header, opts *WeatherOptions) []string {
+	var applied []string
+	for _, header := range h.Values("Prefer") {
+		for _, pref := range strings.Split(header, ",") {
+			// Drop any ";param" parts; none of the supported preferences use them.
+			pref, _, _ = strings.Cut(pref, ";")
+			name, value, _ := strings.Cut(strings.TrimSpace(pref), "=")
+			name = strings.ToLower(strings.TrimSpace(name))
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+
+			switch name {
+			case "respond-async":
+				if opts.Status == nil && opts.DelayMs == nil && opts.MinDelay == nil && opts.MaxDelay == nil {
+					status, delay := http.StatusAccepted, 0
+					opts.Status, opts.DelayMs = &status, &delay
+					applied = append(applied, name)
+				}
+			case "latency":
+				ms, err := parseLatency(value)
+				if err != nil {
+					slog.Warn("Ignoring invalid Prefer latency", "value", value)
+				} else if opts.DelayMs == nil && opts.MinDelay == nil && opts.MaxDelay == nil {
+					opts.DelayMs = &ms
+					applied = append(applied, name+"="+value)
+				}
+			case "status":
+				status, err := strconv.Atoi(value)
+				if err != nil {
+					slog.Warn("Ignoring invalid Prefer status", "value", value)
+				} else if opts.Status == nil {
+					opts.Status = &status
+					applied = append(applied, name+"="+value)
+				}
+			}
+		}
+	}
+	return applied
+}
+
+// parseLatency parses a latency preference as a Go duration ("500ms") or a
+// plain number of milliseconds ("500"), returning milliseconds.
+func parseLatency(value string) (int, error) {
+	if ms, err := strconv.Atoi(value); err == nil {
+		return ms, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errorOf(ErrInvalidParam, "invalid latency %q", value)
+	}
+	return int(d / time.Millisecond), nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestWeatherHandlerPreferHeader tests that Prefer sets the delay and status.
+func TestWeatherHandlerPreferHeader(t *testing.T) {
+	testCases := []struct {
+		name    string
+		prefer  string
+		target  string
+		status  int
+		delay   time.Duration
+		applied string
+	}{
+		{"LatencyAndStatus", "latency=500ms, status=418", "/weather", http.StatusTeapot, 500 * time.Millisecond, "latency=500ms, status=418"},
+		{"PlainMilliseconds", `latency="250"`, "/weather?status=200", http.StatusOK, 250 * time.Millisecond, "latency=250"},
+		{"RespondAsync", "respond-async; wait=10", "/weather", http.StatusAccepted, 0, "respond-async"},
+		{"QueryWins", "status=500", "/weather?status=200&delayMs=0", http.StatusOK, 0, ""},
+		{"UnknownIgnored", "handling=lenient, status=201", "/weather?delayMs=0", http.StatusCreated, 0, "status=201"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recording := &recordingSleeper{}
+			req := httptest.NewRequest("GET", tc.target, nil)
+			req.Header.Set("Prefer", tc.prefer)
+			rr := httptest.NewRecorder()
+			weatherHandler(recording, chooser, rr, req)
+
+			if rr.Code != tc.status {
+				t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, tc.status)
+			}
+			if recording.total != tc.delay {
+				t.Errorf("Handler slept for wrong duration: got %v want %v", recording.total, tc.delay)
+			}
+			if got := rr.Header().Get("Preference-Applied"); got != tc.applied {
+				t.Errorf("Handler returned wrong Preference-Applied: got %q want %q", got, tc.applied)
+			}
+		})
+	}
+}
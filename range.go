@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// rangeUnit is the custom range unit /weather accepts: reading indexes.
+const rangeUnit = "items"
+
+// itemRange is a single range from a "Range: items=..." request header.
+type itemRange struct {
+	first int // First index; unused for a suffix range
+	last  int // Last index, inclusive; -1 runs to the end
+	// suffix, when positive, selects the last suffix readings ("items=-10").
+	suffix int
+}
+
+// parseItemRange parses a Range header such as "items=0-49", "items=50-" or
+// "items=-10". Headers in another unit, with several ranges or that are
+// malformed are reported as not ok, and ignored like any unsupported Range.
+func parseItemRange(header string) (itemRange, bool) {
+	spec, ok := strings.CutPrefix(header, rangeUnit+"=")
+	if !ok || strings.Contains(spec, ",") {
+		return itemRange{}, false
+	}
+	firstStr, lastStr, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return itemRange{}, false
+	}
+	if firstStr == "" {
+		suffix, err := strconv.Atoi(lastStr)
+		if err != nil || suffix < 1 {
+			return itemRange{}, false
+		}
+		return itemRange{suffix: suffix}, true
+	}
+
+	first, err := strconv.Atoi(firstStr)
+	if err != nil || first < 0 {
+		return itemRange{}, false
+	}
+	last := -1
+	if lastStr != "" {
+		if last, err = strconv.Atoi(lastStr); err != nil || last < first {
+			return itemRange{}, false
+		}
+	}
+	return itemRange{first: first, last: last}, true
+}
+
+// resolve returns the inclusive indexes the range selects out of total
+// readings, clamping the end to the last reading. It is not ok when the range
+// selects nothing.
+func (ir itemRange) resolve(total int) (start, end int, ok bool) {
+	if total == 0 {
+		return 0, 0, false
+	}
+	if ir.suffix > 0 {
+		return max(total-ir.suffix, 0), total - 1, true
+	}
+	if ir.first >= total {
+		return 0, 0, false
+	}
+	end = total - 1
+	if ir.last >= 0 {
+		end = min(ir.last, end)
+	}
+	return ir.first, end, true
+}
+
+// applyItemRange narrows readings to ir and returns them with the status to
+// respond with: 206 with a Content-Range header, or 416 and no readings when
+// the range can't be satisfied.
+func applyItemRange(h http.Header, ir itemRange, readings []WeatherReading) ([]WeatherReading, int) {
+	total := len(readings)
+	start, end, ok := ir.resolve(total)
+	if !ok {
+		slog.Info("Unsatisfiable range", "readings", total)
+		h.Set("Content-Range", fmt.Sprintf("%s */%d", rangeUnit, total))
+		return nil, http.StatusRequestedRangeNotSatisfiable
+	}
+	slog.Info("Serving a range of readings", "start", start, "end", end, "readings", total)
+	h.Set("Content-Range", fmt.Sprintf("%s %d-%d/%d", rangeUnit, start, end, total))
+	return readings[start : end+1], http.StatusPartialContent
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestParseItemRange tests parsing of items Range headers.
+func TestParseItemRange(t *testing.T) {
+	testCases := []struct {
+		header string
+		want   itemRange
+		ok     bool
+	}{
+		{"items=0-49", itemRange{first: 0, last: 49}, true},
+		{"items=50-", itemRange{first: 50, last: -1}, true},
+		{"items=-10", itemRange{suffix: 10}, true},
+		{"bytes=0-49", itemRange{}, false},
+		{"items=0-9,20-29", itemRange{}, false},
+		{"items=9-0", itemRange{}, false},
+		{"items=-0", itemRange{}, false},
+		{"items=a-b", itemRange{}, false},
+		{"", itemRange{}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.header, func(t *testing.T) {
+			got, ok := parseItemRange(tc.header)
+			if ok != tc.ok || got != tc.want {
+				t.Errorf("parseItemRange(%q) = %+v, %v; want %+v, %v", tc.header, got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+// TestWeatherHandlerRange tests that Range requests return 206 with the
+// selected readings, or 416 when nothing can be selected.
+func TestWeatherHandlerRange(t *testing.T) {
+	ok := &FixedStatusChooser{Status: http.StatusOK}
+
+	testCases := []struct {
+		name         string
+		rangeHeader  string
+		status       int
+		contentRange string
+		readings     int
+	}{
+		{"NoRange", "", http.StatusOK, "", 100},
+		{"Range", "items=0-49", http.StatusPartialContent, "items 0-49/100", 50},
+		{"ClampedEnd", "items=90-200", http.StatusPartialContent, "items 90-99/100", 10},
+		{"OpenEnd", "items=95-", http.StatusPartialContent, "items 95-99/100", 5},
+		{"Suffix", "items=-3", http.StatusPartialContent, "items 97-99/100", 3},
+		{"Unsatisfiable", "items=100-", http.StatusRequestedRangeNotSatisfiable, "items */100", 0},
+		{"OtherUnit", "bytes=0-49", http.StatusOK, "", 100},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/weather?size=100", nil)
+			if tc.rangeHeader != "" {
+				req.Header.Set("Range", tc.rangeHeader)
+			}
+			rr := httptest.NewRecorder()
+			weatherHandler(sleeper, ok, rr, req)
+
+			if rr.Code != tc.status {
+				t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, tc.status)
+			}
+			if got := rr.Header().Get("Content-Range"); got != tc.contentRange {
+				t.Errorf("Handler returned wrong Content-Range: got %q want %q", got, tc.contentRange)
+			}
+			if got := rr.Header().Get("Accept-Ranges"); got != "items" {
+				t.Errorf("Handler returned wrong Accept-Ranges: got %q want %q", got, "items")
+			}
+			var responseData DataResponse
+			if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+				t.Fatalf("Could not decode response: %v", err)
+			}
+			if len(responseData.Readings) != tc.readings {
+				t.Errorf("Handler returned wrong number of readings: got %v want %v", len(responseData.Readings), tc.readings)
+			}
+		})
+	}
+}
+
+// TestWeatherHandlerRangeIgnoredOnError tests that a Range header doesn't
+// change error responses.
+func TestWeatherHandlerRangeIgnoredOnError(t *testing.T) {
+	req := httptest.NewRequest("GET", "/weather", nil)
+	req.Header.Set("Range", "items=0-4")
+	rr := httptest.NewRecorder()
+	weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusInternalServerError}, rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusInternalServerError)
+	}
+	if got := rr.Header().Get("Content-Range"); got != "" {
+		t.Errorf("Error response has a Content-Range header: %q", got)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/hex"
+	"math"
+	"math/bits"
+)
+
+// FNV-1a 128-bit parameters. The prime is 2^88 + 0x13b.
+const (
+	fnv128OffsetHigh = 0x6c62272e07bb0142
+	fnv128OffsetLow  = 0x62b821756295c58d
+	fnv128PrimeLow   = 0x13b
+	fnv128PrimeShift = 24 // 88 - 64
+)
+
+// fnv128a is an FNV-1a 128-bit hash kept on the stack, unlike hash/fnv's,
+// which escapes through the hash.Hash interface.
+type fnv128a struct {
+	high, low uint64
+}
+
+func newFNV128a() fnv128a {
+	return fnv128a{high: fnv128OffsetHigh, low: fnv128OffsetLow}
+}
+
+// addByte returns h with c hashed in. The hash is passed by value, so it
+// stays in registers.
+func (h fnv128a) addByte(c byte) fnv128a {
+	h.low ^= uint64(c)
+	// Multiply by the prime modulo 2^128.
+	high, low := bits.Mul64(h.low, fnv128PrimeLow)
+	high += h.high*fnv128PrimeLow + h.low<<fnv128PrimeShift
+	return fnv128a{high: high, low: low}
+}
+
+// addString returns h with s and a terminating zero byte hashed in.
+func (h fnv128a) addString(s string) fnv128a {
+	for i := 0; i < len(s); i++ {
+		h = h.addByte(s[i])
+	}
+	return h.addByte(0)
+}
+
+// addUint64 returns h with the little-endian bytes of v hashed in.
+func (h fnv128a) addUint64(v uint64) fnv128a {
+	for range 8 {
+		h = h.addByte(byte(v))
+		v >>= 8
+	}
+	return h
+}
+
+// readingID returns a name-based UUID (version 8, RFC 9562) from an FNV-1a
+// 128-bit hash of the reading's city, timestamp and generated values. It
+// uses no randomness, so a fixed seed and clock reproduce the same IDs, and
+// allocates only the returned string, since it runs for every generated
+// reading. Readings keep the ID they were generated with when later altered,
+// e.g. by injected anomalies, so exact duplicates and corrupted copies of a
+// reading share its ID.
+func readingID(reading WeatherReading) string {
+	h := newFNV128a().
+		addString(reading.City).
+		addUint64(uint64(reading.Timestamp.UnixNano())).
+		addUint64(math.Float64bits(reading.Temperature)).
+		addUint64(uint64(reading.Humidity)).
+		addString(reading.Condition)
+
+	var uuid [16]byte
+	for i := range 8 {
+		uuid[i] = byte(h.high >> (56 - 8*i))
+		uuid[8+i] = byte(h.low >> (56 - 8*i))
+	}
+	uuid[6] = uuid[6]&0x0f | 0x80 // Version 8
+	uuid[8] = uuid[8]&0x3f | 0x80 // RFC 9562 variant
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], uuid[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], uuid[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], uuid[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], uuid[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], uuid[10:16])
+	return string(buf[:])
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"hash/fnv"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-8[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// TestReadingIDs tests that readings carry well-formed IDs that are unique
+// within a response and reproducible with a fixed seed and clock.
+func TestReadingIDs(t *testing.T) {
+	ids := func(seed int64) []string {
+		rr := httptest.NewRecorder()
+		newTestService(seed, nil, nil).Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/weather?size=50", nil))
+		var responseData DataResponse
+		if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+			t.Fatalf("Could not decode response: %v", err)
+		}
+		ids := make([]string, len(responseData.Readings))
+		for i, reading := range responseData.Readings {
+			ids[i] = reading.ID
+		}
+		return ids
+	}
+
+	first := ids(3)
+	seen := make(map[string]bool)
+	for _, id := range first {
+		if !uuidPattern.MatchString(id) {
+			t.Errorf("Reading ID %q is not a version 8 UUID", id)
+		}
+		if seen[id] {
+			t.Errorf("Reading ID %q is not unique", id)
+		}
+		seen[id] = true
+	}
+
+	for i, id := range ids(3) {
+		if id != first[i] {
+			t.Errorf("Reading %d has ID %q with the same seed, want %q", i, id, first[i])
+		}
+	}
+}
+
+// TestReadingIDChanges tests that readings differing in any generated value
+// get different IDs.
+func TestReadingIDChanges(t *testing.T) {
+	reading := WeatherReading{City: "Tokyo", Timestamp: testEpoch, Temperature: 21.5, Humidity: 60, Condition: "Sunny"}
+	id := readingID(reading)
+	for _, changed := range []WeatherReading{
+		{City: "Lagos", Timestamp: testEpoch, Temperature: 21.5, Humidity: 60, Condition: "Sunny"},
+		{City: "Tokyo", Timestamp: testEpoch.Add(1), Temperature: 21.5, Humidity: 60, Condition: "Sunny"},
+		{City: "Tokyo", Timestamp: testEpoch, Temperature: 21.6, Humidity: 60, Condition: "Sunny"},
+		{City: "Tokyo", Timestamp: testEpoch, Temperature: 21.5, Humidity: 61, Condition: "Sunny"},
+		{City: "Tokyo", Timestamp: testEpoch, Temperature: 21.5, Humidity: 60, Condition: "Rainy"},
+	} {
+		if readingID(changed) == id {
+			t.Errorf("%+v has the same ID as %+v", changed, reading)
+		}
+	}
+}
+
+// TestReadingIDPinnedCity tests that the ID of a reading for a pinned city is
+// derived from that city, so different cities never share IDs.
+func TestReadingIDPinnedCity(t *testing.T) {
+	readings := func(target string) []WeatherReading {
+		rr := httptest.NewRecorder()
+		newTestService(1, nil, nil).Handler().ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		var responseData DataResponse
+		if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+			t.Fatalf("Could not decode response: %v", err)
+		}
+		return responseData.Readings
+	}
+
+	tokyo, paris := readings("/weather/Tokyo"), readings("/weather?city=Paris")
+	for i := range tokyo {
+		if tokyo[i].ID == paris[i].ID {
+			t.Errorf("Reading %d has the same ID %s for Tokyo and Paris", i, tokyo[i].ID)
+		}
+		if tokyo[i].ID != readingID(tokyo[i]) {
+			t.Errorf("Reading %d has ID %s, want the ID of its Tokyo values %s", i, tokyo[i].ID, readingID(tokyo[i]))
+		}
+	}
+}
+
+// TestFNV128a tests the stack FNV-1a hash against hash/fnv.
+func TestFNV128a(t *testing.T) {
+	for _, input := range []string{"", "a", "Tokyo\x00Sunny", strings.Repeat("weather", 50)} {
+		h := newFNV128a()
+		for i := 0; i < len(input); i++ {
+			h = h.addByte(input[i])
+		}
+		var got [16]byte
+		binary.BigEndian.PutUint64(got[:8], h.high)
+		binary.BigEndian.PutUint64(got[8:], h.low)
+
+		want := fnv.New128a()
+		want.Write([]byte(input))
+		if !bytes.Equal(got[:], want.Sum(nil)) {
+			t.Errorf("FNV-1a of %q is %x, want %x", input, got, want.Sum(nil))
+		}
+	}
+}
+
+// TestReadingIDAllocs tests that deriving an ID allocates only the string.
+func TestReadingIDAllocs(t *testing.T) {
+	reading := WeatherReading{City: "Tokyo", Timestamp: testEpoch, Temperature: 21.5, Humidity: 60, Condition: "Sunny"}
+	if allocs := testing.AllocsPerRun(100, func() { readingID(reading) }); allocs > 1 {
+		t.Errorf("readingID made %v allocations, want 1", allocs)
+	}
+}
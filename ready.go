@@ -0,0 +1,64 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Readiness statuses reported by /ready.
+const (
+	readyStatusReady    = "ready"
+	readyStatusStarting = "starting"
+)
+
+// Readiness tracks whether the server should receive traffic. Unlike /health,
+// which reports whether the process works, /ready lets orchestrators hold
+// traffic back while it starts up.
+type Readiness struct {
+	ready atomic.Bool
+}
+
+// ReadyResponse is the response of /ready.
+type ReadyResponse struct {
+	Status string `json:"status"`
+}
+
+// Ready reports whether the server is ready. A nil Readiness is always ready.
+func (rd *Readiness) Ready() bool {
+	return rd == nil || rd.ready.Load()
+}
+
+// SetReady marks the server ready or not ready.
+func (rd *Readiness) SetReady(ready bool) {
+	rd.ready.Store(ready)
+}
+
+// newDelayedReadiness returns a Readiness that becomes ready after delay,
+// flipped by a background goroutine, or immediately when delay is zero.
+func newDelayedReadiness(delay time.Duration) *Readiness {
+	rd := &Readiness{}
+	if delay <= 0 {
+		rd.SetReady(true)
+		return rd
+	}
+	slog.Info("Delaying readiness", "delay", delay)
+	go func() {
+		time.Sleep(delay)
+		rd.SetReady(true)
+		slog.Info("Server is ready")
+	}()
+	return rd
+}
+
+// readyHandler handles requests to the /ready endpoint, returning 503 until
+// the server is ready.
+func readyHandler(rd *Readiness, w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !rd.Ready() {
+		writeJSON(w, http.StatusServiceUnavailable, ReadyResponse{Status: readyStatusStarting})
+		return
+	}
+	writeJSON(w, http.StatusOK, ReadyResponse{Status: readyStatusReady})
+}
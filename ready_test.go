@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestReadyHandler tests that /ready returns 503 until the startup delay has
+// passed and 200 afterwards.
+func TestReadyHandler(t *testing.T) {
+	rd := newDelayedReadiness(50 * time.Millisecond)
+
+	rr := httptest.NewRecorder()
+	readyHandler(rd, rr, httptest.NewRequest("GET", "/ready", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Ready handler returned wrong status code during startup: got %v want %v", rr.Code, http.StatusServiceUnavailable)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !rd.Ready() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	rr = httptest.NewRecorder()
+	readyHandler(rd, rr, httptest.NewRequest("GET", "/ready", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Ready handler returned wrong status code after startup: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	rr = httptest.NewRecorder()
+	readyHandler(nil, rr, httptest.NewRequest("GET", "/ready", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Ready handler returned wrong status code without a Readiness: got %v want %v", rr.Code, http.StatusOK)
+	}
+}
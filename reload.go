@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// runtimeSettings are the Config fields that can change while the server is
+// running. Handlers load them once per request through currentSettings, so a
+// reload never mixes old and new values within one response.
+type runtimeSettings struct {
+	// FixedDelay replaces the random /weather delay when non-negative.
+	FixedDelay time.Duration
+	// SizeDelayBase and SizeDelayPerItem scale the delay with the requested
+	// size when SizeDelayPerItem is positive; see sizeDelay.
+	SizeDelayBase    time.Duration
+	SizeDelayPerItem time.Duration
+	// ErrorDelay replaces the delay of 5xx responses when positive.
+	ErrorDelay time.Duration
+	// ConditionWeights weights each entry in conditions; nil is uniform.
+	ConditionWeights []int
+	// StatusWeights weights the 2xx, 4xx and 5xx classes of random statuses.
+	StatusWeights []int
+	// DefaultMaxDelayMs is the upper bound of the random delay without
+	// maxDelay, and MaxDelayMs the largest delay a client may request.
+	DefaultMaxDelayMs int
+	MaxDelayMs        int
+	// DefaultSize is the number of readings without size, which must be
+	// between MinSize and MaxSize.
+	DefaultSize int
+	MinSize     int
+	MaxSize     int
+}
+
+// settings holds the active runtimeSettings, replaced wholesale on reload.
+var settings atomic.Pointer[runtimeSettings]
+
+func init() {
+	settings.Store(defaultRuntimeSettings())
+}
+
+// defaultRuntimeSettings returns the settings of a server without any
+// configuration.
+func defaultRuntimeSettings() *runtimeSettings {
+	return &runtimeSettings{
+		FixedDelay:        -1,
+		StatusWeights:     defaultStatusWeights,
+		DefaultMaxDelayMs: defaultMaxDelayMs,
+		MaxDelayMs:        maxDelayMs,
+		DefaultSize:       defaultSize,
+		MinSize:           minSize,
+		MaxSize:           maxSize,
+	}
+}
+
+// currentSettings returns the active runtime settings. They must not be modified.
+func currentSettings() *runtimeSettings {
+	return settings.Load()
+}
+
+// sizeDelay returns the delay for a response of size readings when the
+// delay scales with size, capped at maxDelayMs.
+func (s *runtimeSettings) sizeDelay(size int) (time.Duration, bool) {
+	if s.SizeDelayPerItem <= 0 {
+		return 0, false
+	}
+	return min(s.SizeDelayBase+time.Duration(size)*s.SizeDelayPerItem, maxDelayMs*time.Millisecond), true
+}
+
+// runtimeSettings returns the reloadable part of cfg.
+func (cfg Config) runtimeSettings() *runtimeSettings {
+	return &runtimeSettings{
+		FixedDelay:        cfg.FixedDelay,
+		SizeDelayBase:     cfg.SizeDelayBase,
+		SizeDelayPerItem:  cfg.SizeDelayPerItem,
+		ErrorDelay:        cfg.ErrorDelay,
+		ConditionWeights:  cfg.ConditionWeights,
+		StatusWeights:     cfg.StatusWeights,
+		DefaultMaxDelayMs: cfg.DefaultMaxDelayMs,
+		MaxDelayMs:        cfg.MaxDelayMs,
+		DefaultSize:       cfg.DefaultSize,
+		MinSize:           cfg.MinSize,
+		MaxSize:           cfg.MaxSize,
+	}
+}
+
+// withRuntimeSettings returns cfg with its reloadable fields taken from other.
+func (cfg Config) withRuntimeSettings(other Config) Config {
+	cfg.FixedDelay, cfg.ErrorDelay = other.FixedDelay, other.ErrorDelay
+	cfg.SizeDelayBase, cfg.SizeDelayPerItem = other.SizeDelayBase, other.SizeDelayPerItem
+	cfg.ConditionWeights, cfg.StatusWeights = other.ConditionWeights, other.StatusWeights
+	cfg.DefaultMaxDelayMs, cfg.MaxDelayMs = other.DefaultMaxDelayMs, other.MaxDelayMs
+	cfg.DefaultSize, cfg.MinSize, cfg.MaxSize = other.DefaultSize, other.MinSize, other.MaxSize
+	return cfg
+}
+
+// envFileSaved holds, for each variable set from WEATHER_ENV_FILE, the value
+// it had before (nil when it was unset), so that removing its line from the
+// file restores it on the next reload.
+var envFileSaved = map[string]*string{}
+
+// loadEnvFile sets environment variables from the KEY=VALUE lines in the file
+// named by WEATHER_ENV_FILE, if any, so settings can be edited on disk and
+// picked up by a reload. Blank lines and lines starting with # are skipped.
+// Variables set by an earlier load whose lines were removed go back to their
+// value from before, usually unset and so the default. A malformed file
+// changes nothing.
+func loadEnvFile() error {
+	path := os.Getenv("WEATHER_ENV_FILE")
+	values := map[string]string{}
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			name, value, ok := strings.Cut(text, "=")
+			if !ok {
+				return errorOf(ErrBadConfig, "%s:%d: expected KEY=VALUE", path, line)
+			}
+			values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	for name, saved := range envFileSaved {
+		if _, ok := values[name]; ok {
+			continue
+		}
+		if saved != nil {
+			os.Setenv(name, *saved)
+		} else {
+			os.Unsetenv(name)
+		}
+		delete(envFileSaved, name)
+	}
+	for name, value := range values {
+		if _, ok := envFileSaved[name]; !ok {
+			var saved *string
+			if old, ok := os.LookupEnv(name); ok {
+				saved = &old
+			}
+			envFileSaved[name] = saved
+		}
+		os.Setenv(name, value)
+	}
+	return nil
+}
+
+// reloadConfig reloads the Config from the environment and applies the
+// reloadable settings, logging each one that changed. Other changes, such as
+// the port, are logged as needing a restart and otherwise ignored. A Config
+// that fails to load leaves the running settings untouched. It returns the
+// Config to compare the next reload against.
+func reloadConfig(running Config) Config {
+	if err := loadEnvFile(); err != nil {
+		slog.Error("Could not read WEATHER_ENV_FILE, keeping the current settings", "error", err)
+		return running
+	}
+	cfg, err := LoadConfig()
+	if err != nil {
+		slog.Error("Could not reload configuration, keeping the current settings", "error", err)
+		return running
+	}
+
+	if cfg.FixedDelay != running.FixedDelay {
+		slog.Info("Reloaded fixed delay", "old", running.FixedDelay, "new", cfg.FixedDelay)
+	}
+	if cfg.SizeDelayBase != running.SizeDelayBase || cfg.SizeDelayPerItem != running.SizeDelayPerItem {
+		slog.Info("Reloaded size-scaled delay", "old_base", running.SizeDelayBase, "old_per_item", running.SizeDelayPerItem,
+			"new_base", cfg.SizeDelayBase, "new_per_item", cfg.SizeDelayPerItem)
+	}
+	if cfg.ErrorDelay != running.ErrorDelay {
+		slog.Info("Reloaded error delay", "old", running.ErrorDelay, "new", cfg.ErrorDelay)
+	}
+	if !slices.Equal(cfg.ConditionWeights, running.ConditionWeights) {
+		slog.Info("Reloaded condition weights", "old", running.ConditionWeights, "new", cfg.ConditionWeights)
+	}
+	if !slices.Equal(cfg.StatusWeights, running.StatusWeights) {
+		slog.Info("Reloaded status weights", "old", running.StatusWeights, "new", cfg.StatusWeights)
+	}
+	if cfg.DefaultMaxDelayMs != running.DefaultMaxDelayMs || cfg.MaxDelayMs != running.MaxDelayMs {
+		slog.Info("Reloaded delay bounds", "old_default_max_ms", running.DefaultMaxDelayMs, "old_max_ms", running.MaxDelayMs,
+			"new_default_max_ms", cfg.DefaultMaxDelayMs, "new_max_ms", cfg.MaxDelayMs)
+	}
+	if cfg.DefaultSize != running.DefaultSize || cfg.MinSize != running.MinSize || cfg.MaxSize != running.MaxSize {
+		slog.Info("Reloaded size bounds", "old_default", running.DefaultSize, "old_min", running.MinSize, "old_max", running.MaxSize,
+			"new_default", cfg.DefaultSize, "new_min", cfg.MinSize, "new_max", cfg.MaxSize)
+	}
+	settings.Store(cfg.runtimeSettings())
+
+	// Compare everything else with the reloadable fields masked out.
+	if !reflect.DeepEqual(cfg.withRuntimeSettings(running), running) {
+		slog.Warn("Some changed settings only take effect after a restart")
+	}
+	return running.withRuntimeSettings(cfg)
+}
+
+// watchReload reloads the configuration on every SIGHUP until ctx is done.
+func watchReload(ctx context.Context, cfg Config) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			slog.Info("Received SIGHUP, reloading configuration")
+			cfg = reloadConfig(cfg)
+		}
+	}
+}
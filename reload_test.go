@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestReloadConfig tests that a reload applies the reloadable settings and
+// leaves the others as they were.
+func TestReloadConfig(t *testing.T) {
+	running, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+	defer settings.Store(defaultRuntimeSettings())
+
+	t.Setenv("WEATHER_FIXED_DELAY_MS", "300")
+	t.Setenv("WEATHER_CONDITION_WEIGHTS", "Snowy:0")
+	t.Setenv("WEATHER_PORT", "9090")
+	running = reloadConfig(running)
+
+	current := currentSettings()
+	if current.FixedDelay != 300*time.Millisecond {
+		t.Errorf("Wrong fixed delay after reload: got %v want %v", current.FixedDelay, 300*time.Millisecond)
+	}
+	if want := []int{1, 1, 1, 1, 1, 1, 0}; !slices.Equal(current.ConditionWeights, want) {
+		t.Errorf("Wrong condition weights after reload: got %v want %v", current.ConditionWeights, want)
+	}
+	if running.Port != 8080 {
+		t.Errorf("Port changed without a restart: got %v want %v", running.Port, 8080)
+	}
+	if running.FixedDelay != 300*time.Millisecond {
+		t.Errorf("Returned config has wrong fixed delay: got %v want %v", running.FixedDelay, 300*time.Millisecond)
+	}
+}
+
+// TestReloadConfigInvalid tests that an invalid configuration is not applied.
+func TestReloadConfigInvalid(t *testing.T) {
+	running, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+	defer settings.Store(defaultRuntimeSettings())
+
+	t.Setenv("WEATHER_FIXED_DELAY_MS", "300")
+	t.Setenv("WEATHER_PORT", "0")
+	reloadConfig(running)
+
+	if current := currentSettings(); current.FixedDelay != -1 {
+		t.Errorf("Invalid configuration was applied: fixed delay %v", current.FixedDelay)
+	}
+}
+
+// TestReloadConfigFromEnvFile tests that a reload picks up edits to WEATHER_ENV_FILE.
+func TestReloadConfigFromEnvFile(t *testing.T) {
+	running, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+	defer settings.Store(defaultRuntimeSettings())
+
+	// Setting the variable first makes t.Setenv restore it afterwards.
+	t.Setenv("WEATHER_FIXED_DELAY_MS", "")
+	t.Cleanup(func() { clear(envFileSaved) })
+	path := filepath.Join(t.TempDir(), "weather.env")
+	t.Setenv("WEATHER_ENV_FILE", path)
+	if err := os.WriteFile(path, []byte("# chaos settings\n\nWEATHER_FIXED_DELAY_MS = 125\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reloadConfig(running)
+
+	if current := currentSettings(); current.FixedDelay != 125*time.Millisecond {
+		t.Errorf("Wrong fixed delay after reload: got %v want %v", current.FixedDelay, 125*time.Millisecond)
+	}
+
+	// A malformed file is rejected without changing the settings.
+	if err := os.WriteFile(path, []byte("WEATHER_FIXED_DELAY_MS\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reloadConfig(running)
+	if current := currentSettings(); current.FixedDelay != 125*time.Millisecond {
+		t.Errorf("Malformed env file changed the fixed delay to %v", current.FixedDelay)
+	}
+
+	// Removing the line restores the default.
+	if err := os.WriteFile(path, []byte("# nothing\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reloadConfig(running)
+	if current := currentSettings(); current.FixedDelay != -1 {
+		t.Errorf("Removed setting kept the fixed delay %v", current.FixedDelay)
+	}
+}
+
+// TestReloadStatusWeightsAndBounds tests that status weights and the delay
+// and size bounds are reloaded.
+func TestReloadStatusWeightsAndBounds(t *testing.T) {
+	running, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+	defer settings.Store(defaultRuntimeSettings())
+
+	t.Setenv("WEATHER_STATUS_WEIGHTS", "2xx:1")
+	t.Setenv("WEATHER_MAX_DELAY_MS", "1000")
+	t.Setenv("WEATHER_DEFAULT_MAX_DELAY_MS", "500")
+	t.Setenv("WEATHER_MAX_SIZE", "200")
+	running = reloadConfig(running)
+
+	current := currentSettings()
+	if !slices.Equal(current.StatusWeights, []int{1, 0, 0}) {
+		t.Errorf("Wrong status weights after reload: got %v", current.StatusWeights)
+	}
+	if current.DefaultMaxDelayMs != 500 || current.MaxDelayMs != 1000 || current.MaxSize != 200 {
+		t.Errorf("Wrong bounds after reload: default max delay %d, max delay %d, max size %d",
+			current.DefaultMaxDelayMs, current.MaxDelayMs, current.MaxSize)
+	}
+	if running.MaxDelayMs != 1000 {
+		t.Errorf("Returned config has wrong max delay: got %v want %v", running.MaxDelayMs, 1000)
+	}
+}
+
+// testSettings returns the default runtime settings changed by set.
+func testSettings(set func(s *runtimeSettings)) *runtimeSettings {
+	s := defaultRuntimeSettings()
+	set(s)
+	return s
+}
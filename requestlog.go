@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// RequestLogEntry is a single request recorded by the logging middleware.
+type RequestLogEntry struct {
+	RequestID string        `json:"request_id"`
+	Method    string        `json:"method"`
+	Path      string        `json:"path"`
+	Status    int           `json:"status"`
+	Duration  time.Duration `json:"duration_ns"`
+	Time      time.Time     `json:"time"`
+}
+
+// RequestLog is a bounded, thread-safe in-memory sink holding the most recent
+// request log entries. Once full, the oldest entry is overwritten.
+type RequestLog struct {
+	buf *ringBuffer[RequestLogEntry]
+}
+
+// NewRequestLog creates a RequestLog that keeps at most size entries.
+func NewRequestLog(size int) *RequestLog {
+	return &RequestLog{buf: newRingBuffer[RequestLogEntry](size)}
+}
+
+// Add records an entry, evicting the oldest one if the buffer is full.
+func (l *RequestLog) Add(e RequestLogEntry) {
+	l.buf.Add(e)
+}
+
+// Entries returns a copy of the recorded entries, oldest first.
+func (l *RequestLog) Entries() []RequestLogEntry {
+	return l.buf.Items()
+}
+
+// Reset discards every recorded entry.
+func (l *RequestLog) Reset() {
+	l.buf.Reset()
+}
+
+// debugRequestsHandler serves the recorded request log entries as JSON.
+func debugRequestsHandler(l *RequestLog, w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, http.StatusOK, l.Entries())
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// resetter is implemented by status choosers that keep state between
+// requests, such as the circuit breaker, so /debug/reset can clear it.
+type resetter interface {
+	Reset()
+}
+
+// resetChooser resets c if it keeps state.
+func resetChooser(c StatusChooser) {
+	if r, ok := c.(resetter); ok {
+		r.Reset()
+	}
+}
+
+// ResetResponse is the body of POST /debug/reset.
+type ResetResponse struct {
+	Status string `json:"status"`
+	Seed   *int64 `json:"seed,omitempty"` // The random source was re-seeded with this
+}
+
+// debugReset serves POST /debug/reset, which returns the service to a known
+// state for test isolation: the history, request log, status stats, response
+// cache and idempotency store are emptied, the circuit breaker, outage bursts
+// and forced failure count are reset, growing responses shrink back, and with
+// ?seed= the random source is re-seeded.
+func (svc *WeatherService) debugReset(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var seed *int64
+	if seedStr := req.URL.Query().Get("seed"); seedStr != "" {
+		n, err := strconv.ParseInt(seedStr, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, DataResponse{Message: fmt.Sprintf("Invalid request: invalid 'seed' parameter %q, expected an integer", seedStr)})
+			return
+		}
+		seed = &n
+	}
+
+	if svc.History != nil {
+		svc.History.Reset()
+	}
+	svc.RequestLog.Reset()
+	if svc.Stats != nil {
+		svc.Stats.Reset()
+	}
+	if svc.Cache != nil {
+		svc.Cache.Reset()
+	}
+	if svc.Idempotency != nil {
+		svc.Idempotency.Reset()
+	}
+	resetChooser(svc.Chooser)
+	svc.Growth.Reset()
+	if seed != nil {
+		svc.rand().Seed(*seed)
+	}
+
+	slog.Warn("Reset all in-memory state", "seed", seed)
+	writeJSON(w, http.StatusOK, ResetResponse{Status: "reset", Seed: seed})
+}
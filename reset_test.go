@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestDebugReset tests that /debug/reset clears recorded state and that
+// re-seeding makes the following responses reproducible.
+func TestDebugReset(t *testing.T) {
+	svc := newTestService(1, nil, nil)
+	svc.Debug = true
+	svc.History = NewHistory(100)
+	svc.Cache = &ResponseCache{TTL: time.Minute}
+	handler := svc.Handler()
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, target, nil))
+		return rr
+	}
+
+	serve("GET", "/weather")
+	if len(svc.History.Readings()) == 0 || len(svc.Cache.entries) == 0 {
+		t.Fatalf("Expected the request to be recorded in the history and cache")
+	}
+
+	if rr := serve("POST", "/debug/reset?seed=5"); rr.Code != http.StatusOK {
+		t.Fatalf("Reset returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if n := len(svc.History.Readings()); n != 0 {
+		t.Errorf("History was not cleared: %d readings", n)
+	}
+	if n := len(svc.Cache.entries); n != 0 {
+		t.Errorf("Cache was not cleared: %d entries", n)
+	}
+	// The log is written after the handler returns, so only the reset itself remains.
+	if entries := svc.RequestLog.Entries(); len(entries) != 1 || entries[0].Path != "/debug/reset" {
+		t.Errorf("Request log was not cleared: %+v", entries)
+	}
+
+	first := serve("GET", "/weather?size=20").Body.String()
+	serve("POST", "/debug/reset?seed=5")
+	if second := serve("GET", "/weather?size=20").Body.String(); second != first {
+		t.Errorf("Re-seeded responses differ:\n%s\n%s", first, second)
+	}
+
+	if rr := serve("POST", "/debug/reset?seed=abc"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Reset returned wrong status code for a bad seed: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+// TestDebugResetRequiresDebug tests that /debug/reset isn't served outside debug mode.
+func TestDebugResetRequiresDebug(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newTestService(1, nil, nil).Handler().ServeHTTP(rr, httptest.NewRequest("POST", "/debug/reset", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+// TestResetChooser tests that resetting a chooser chain closes an open circuit.
+func TestResetChooser(t *testing.T) {
+	breaker := &CircuitBreaker{Threshold: 1, Cooldown: time.Hour}
+	chooser := &BurstChooser{
+		Inner:  &CircuitBreakerChooser{Inner: &FixedStatusChooser{Status: http.StatusInternalServerError}, Breaker: breaker},
+		Outage: &OutageSimulator{},
+	}
+	chooser.ChooseStatus(nil)
+	if breaker.State() != circuitOpen {
+		t.Fatalf("Expected the circuit to open, got %v", breaker.State())
+	}
+
+	resetChooser(chooser)
+	if breaker.State() != circuitClosed {
+		t.Errorf("Circuit was not closed by the reset: got %v", breaker.State())
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+// reshapedResponse is a DataResponse whose readings are written in another
+// shape. Its Readings hides the embedded DataResponse's in the JSON, so every
+// shape shares the rest of the envelope.
+type reshapedResponse struct {
+	Readings any `json:"readings"`
+	DataResponse
+}
+
+// readingView is a WeatherReading as written when options change its fields:
+// required fields can be left out and humidity can have a decimal place.
+type readingView struct {
+	ID          string     `json:"id,omitempty"`
+	City        *string    `json:"city,omitempty"`
+	StationID   string     `json:"station_id,omitempty"`
+	Timestamp   *time.Time `json:"timestamp,omitempty"`
+	Temperature *float64   `json:"temperature,omitempty"`
+	Humidity    *float64   `json:"humidity,omitempty"`
+	Condition   *string    `json:"condition,omitempty"`
+	Anomaly     bool       `json:"anomaly,omitempty"`
+	Icon        string     `json:"icon,omitempty"`
+}
+
+// readingViews returns a view of each reading with every field present.
+func readingViews(readings []WeatherReading) []readingView {
+	views := make([]readingView, len(readings))
+	for i := range readings {
+		reading := &readings[i]
+		humidity := float64(reading.Humidity)
+		views[i] = readingView{
+			ID:          reading.ID,
+			City:        &reading.City,
+			StationID:   reading.StationID,
+			Timestamp:   &reading.Timestamp,
+			Temperature: &reading.Temperature,
+			Humidity:    &humidity,
+			Condition:   &reading.Condition,
+			Anomaly:     reading.Anomaly,
+			Icon:        reading.Icon,
+		}
+	}
+	return views
+}
+
+// reshapeReadings applies the humidityPrecision, omitFields and groupBy
+// options of p to the readings of data, in that order, so they combine.
+func reshapeReadings(data DataResponse, p weatherParams, r *rand.Rand) reshapedResponse {
+	if !p.floatHumidity && !p.omitFields {
+		return reshapedResponse{Readings: groupReadingsByCity(data.Readings, data.Readings), DataResponse: data}
+	}
+
+	views := readingViews(data.Readings)
+	if p.floatHumidity {
+		withFloatHumidity(views, data.Readings, r)
+	}
+	if p.omitFields {
+		omitRandomFields(views, r)
+	}
+	if p.groupBy == groupByCity {
+		return reshapedResponse{Readings: groupReadingsByCity(views, data.Readings), DataResponse: data}
+	}
+	return reshapedResponse{Readings: views, DataResponse: data}
+}
@@ -0,0 +1,54 @@
+package main
+
+import "sync"
+
+// ringBuffer is a bounded, thread-safe buffer holding the most recent items.
+// Once full, the oldest item is overwritten.
+type ringBuffer[T any] struct {
+	mu    sync.Mutex
+	items []T
+	next  int
+	full  bool
+}
+
+// newRingBuffer creates a ringBuffer that keeps at most size items.
+func newRingBuffer[T any](size int) *ringBuffer[T] {
+	if size < 1 {
+		size = 1
+	}
+	return &ringBuffer[T]{items: make([]T, size)}
+}
+
+// Add records items, evicting the oldest ones if the buffer is full.
+func (b *ringBuffer[T]) Add(items ...T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, item := range items {
+		b.items[b.next] = item
+		b.next = (b.next + 1) % len(b.items)
+		if b.next == 0 {
+			b.full = true
+		}
+	}
+}
+
+// Items returns a copy of the buffered items, oldest first.
+func (b *ringBuffer[T]) Items() []T {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.full {
+		return append([]T(nil), b.items[:b.next]...)
+	}
+	out := make([]T, 0, len(b.items))
+	out = append(out, b.items[b.next:]...)
+	return append(out, b.items[:b.next]...)
+}
+
+// Reset empties the buffer.
+func (b *ringBuffer[T]) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	clear(b.items)
+	b.next = 0
+	b.full = false
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultRollupWindow is the span /weather/rollup covers without window.
+	defaultRollupWindow = 24 * time.Hour
+	// maxRollupWindow is the longest rollup window (one week of hourly buckets).
+	maxRollupWindow = 168 * time.Hour
+)
+
+// Rollup aggregates one hour of a city's readings.
+type Rollup struct {
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	AvgTemperature float64   `json:"avg_temperature"` // Celsius
+	MinTemperature float64   `json:"min_temperature"`
+	MaxTemperature float64   `json:"max_temperature"`
+	AvgHumidity    float64   `json:"avg_humidity"` // Percentage
+}
+
+// RollupResponse is the response of /weather/rollup.
+type RollupResponse struct {
+	City        string    `json:"city"`
+	Window      string    `json:"window"`
+	Seed        int64     `json:"seed"`
+	Rollups     []Rollup  `json:"rollups"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// rollupSeed derives a random seed from the client's seed, the city and, for
+// a bucket, its start time, so every value is reproducible on its own.
+func rollupSeed(seed int64, city string, start time.Time) int64 {
+	h := fnv.New64a()
+	binary.Write(h, binary.LittleEndian, seed)
+	h.Write([]byte(city))
+	binary.Write(h, binary.LittleEndian, start.Unix())
+	return int64(h.Sum64())
+}
+
+// generateRollups returns hourly rollups for city covering window and ending
+// at end, which must be on the hour, oldest first. Each city has its own base
+// temperature and daily swing, and each hour is derived only from the seed,
+// city and hour, so overlapping windows agree on the hours they share.
+func generateRollups(seed int64, city string, window time.Duration, end time.Time) []Rollup {
+	cityRand := rand.New(rand.NewSource(rollupSeed(seed, city, time.Time{})))
+	base := float64(cityRand.Intn(20)+10) + cityRand.Float64() // 10.0 to 30.0 Celsius
+	amplitude := 3 + cityRand.Float64()*5                      // 3 to 8 degrees either side
+	humidity := float64(cityRand.Intn(50) + 35)                // 35% to 84%
+
+	hours := int(window / time.Hour)
+	rollups := make([]Rollup, hours)
+	for i := range rollups {
+		start := end.Add(time.Duration(i-hours) * time.Hour)
+		hourRand := rand.New(rand.NewSource(rollupSeed(seed, city, start)))
+
+		// Peak around 15:00, lowest around 03:00, as in forecasts.
+		phase := 2 * math.Pi * float64(start.Hour()-9) / 24
+		avg := base + amplitude*math.Sin(phase) + hourRand.Float64() - 0.5
+		rollups[i] = Rollup{
+			Start:          start,
+			End:            start.Add(time.Hour),
+			AvgTemperature: avg,
+			MinTemperature: avg - 0.5 - hourRand.Float64()*2,
+			MaxTemperature: avg + 0.5 + hourRand.Float64()*2,
+			AvgHumidity:    min(max(humidity-amplitude*math.Sin(phase)+hourRand.Float64()*10-5, 20), 99),
+		}
+	}
+	return rollups
+}
+
+// rollup serves GET /weather/rollup?city=&window=&seed=, which returns
+// synthetic hourly aggregates of a city's readings over window, ending at the
+// current hour, like a time-series rollup API.
+func (svc *WeatherService) rollup(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	q := req.URL.Query()
+
+	city, ok := lookupCity(q.Get("city"))
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, DataResponse{
+			Message: fmt.Sprintf("Unknown or missing 'city' parameter: %q.", q.Get("city")),
+		})
+		return
+	}
+
+	window := defaultRollupWindow
+	if windowStr := q.Get("window"); windowStr != "" {
+		var err error
+		window, err = time.ParseDuration(windowStr)
+		if err != nil || window < time.Hour || window > maxRollupWindow || window%time.Hour != 0 {
+			writeJSON(w, http.StatusBadRequest, DataResponse{
+				Message: fmt.Sprintf("Invalid 'window' parameter %q, expected whole hours from 1h to %v.", windowStr, maxRollupWindow),
+			})
+			return
+		}
+	}
+
+	var seed int64
+	if seedStr := q.Get("seed"); seedStr != "" {
+		var err error
+		seed, err = strconv.ParseInt(seedStr, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, DataResponse{
+				Message: fmt.Sprintf("Invalid 'seed' parameter %q, expected an integer.", seedStr),
+			})
+			return
+		}
+	}
+
+	now := svc.now()
+	rollups := generateRollups(seed, city, window, now.UTC().Truncate(time.Hour))
+	slog.Info("Responding with rollups", "city", city, "window", window, "rollups", len(rollups))
+	writeJSON(w, http.StatusOK, RollupResponse{
+		City:        city,
+		Window:      window.String(),
+		Seed:        seed,
+		Rollups:     rollups,
+		GeneratedAt: now.UTC(),
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestRollup tests that /weather/rollup returns consistent hourly rollups
+// ending at the current hour, reproducible from the seed and city.
+func TestRollup(t *testing.T) {
+	handler := newTestService(1, nil, nil).Handler()
+	get := func(target string) RollupResponse {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s returned wrong status code: got %v want %v", target, rr.Code, http.StatusOK)
+		}
+		var response RollupResponse
+		if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+			t.Fatalf("Could not decode rollups: %v", err)
+		}
+		return response
+	}
+
+	response := get("/weather/rollup?city=tokyo&window=3h&seed=7")
+	if response.City != "Tokyo" || response.Window != "3h0m0s" || len(response.Rollups) != 3 {
+		t.Fatalf("Handler returned wrong rollups: %+v", response)
+	}
+	end := testEpoch.Truncate(time.Hour)
+	for i, rollup := range response.Rollups {
+		if want := end.Add(time.Duration(i-3) * time.Hour); !rollup.Start.Equal(want) || !rollup.End.Equal(want.Add(time.Hour)) {
+			t.Errorf("Rollup %d covers %v to %v, want the hour from %v", i, rollup.Start, rollup.End, want)
+		}
+		if rollup.MinTemperature > rollup.AvgTemperature || rollup.AvgTemperature > rollup.MaxTemperature {
+			t.Errorf("Rollup %d temperatures are out of order: %+v", i, rollup)
+		}
+		if rollup.AvgHumidity < 20 || rollup.AvgHumidity > 99 {
+			t.Errorf("Rollup %d humidity is out of range: %v", i, rollup.AvgHumidity)
+		}
+	}
+
+	if again := get("/weather/rollup?city=Tokyo&window=3h&seed=7"); !reflect.DeepEqual(again.Rollups, response.Rollups) {
+		t.Errorf("The same seed and city returned different rollups")
+	}
+	// Overlapping windows agree on the hours they share.
+	if shorter := get("/weather/rollup?city=Tokyo&window=2h&seed=7"); !reflect.DeepEqual(shorter.Rollups, response.Rollups[1:]) {
+		t.Errorf("Overlapping windows returned different rollups")
+	}
+	if other := get("/weather/rollup?city=Tokyo&window=3h&seed=8"); reflect.DeepEqual(other.Rollups, response.Rollups) {
+		t.Errorf("Different seeds returned the same rollups")
+	}
+	if other := get("/weather/rollup?city=Paris&window=3h&seed=7"); reflect.DeepEqual(other.Rollups, response.Rollups) {
+		t.Errorf("Different cities returned the same rollups")
+	}
+	if defaults := get("/weather/rollup?city=Tokyo"); len(defaults.Rollups) != 24 {
+		t.Errorf("Default window returned %d rollups, want 24", len(defaults.Rollups))
+	}
+}
+
+// TestRollupInvalid tests that a missing or unknown city and invalid windows
+// and seeds are rejected.
+func TestRollupInvalid(t *testing.T) {
+	handler := newTestService(1, nil, nil).Handler()
+	for _, target := range []string{
+		"/weather/rollup",
+		"/weather/rollup?city=Atlantis",
+		"/weather/rollup?city=Tokyo&window=30m",
+		"/weather/rollup?city=Tokyo&window=90m",
+		"/weather/rollup?city=Tokyo&window=169h",
+		"/weather/rollup?city=Tokyo&window=hourly",
+		"/weather/rollup?city=Tokyo&seed=x",
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s returned wrong status code: got %v want %v", target, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
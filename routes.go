@@ -0,0 +1,274 @@
+package main
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// apiVersions lists the path prefixes the API routes are mirrored under.
+// The empty prefix keeps the original unversioned routes.
+var apiVersions = []string{"", "/v1"}
+
+// WeatherService holds the shared components the routes are wired to.
+type WeatherService struct {
+	Sleeper    Sleeper
+	Chooser    StatusChooser
+	RequestLog *RequestLog
+	Metrics    *Metrics
+	Health     *HealthChecker
+	// Stats counts /weather response statuses for /debug/stats; nil disables it.
+	Stats *StatusStats
+	// Rand generates readings, delays and statuses; nil uses the shared source.
+	// It is used by concurrent requests, so it must be safe for concurrent use.
+	Rand *rand.Rand
+	// Now returns the time readings are generated around; nil uses time.Now.
+	Now func() time.Time
+	// Readiness gates /ready; nil means always ready.
+	Readiness *Readiness
+	// Maintenance answers 503 to everything but the probes while enabled;
+	// nil disables maintenance mode entirely.
+	Maintenance *Maintenance
+	// MaxBodyBytes caps request bodies; larger ones get 413. 0 means unlimited.
+	MaxBodyBytes int64
+	// MaxConcurrency caps in-flight /weather requests; 0 means unlimited.
+	MaxConcurrency int
+	// Deprecation adds deprecation headers to /weather responses when set.
+	Deprecation Deprecation
+	// DropConnectionRate is the probability of closing a /weather request's
+	// connection without any response, simulating connection-phase failures.
+	DropConnectionRate float64
+	// GzipLevel compresses responses for clients that accept gzip at this
+	// level, from 1 to 9 or gzip.DefaultCompression; 0 disables compression.
+	GzipLevel int
+	// InjectedHeaders are added to every response, duplicating any the
+	// handlers set, to exercise header parsing in clients and proxies.
+	InjectedHeaders []injectedHeader
+	// ServerHeader is sent as the Server response header; empty omits it.
+	ServerHeader string
+	// SeasonWeights are the condition weights of each season option; nil
+	// means defaultSeasonWeights.
+	SeasonWeights map[string][]int
+	// StationsPerCity is how many sensor stations each city has.
+	StationsPerCity int
+	// DecompressBomb allows the decompressBomb option.
+	DecompressBomb bool
+	// Growth pads successive /weather responses more and more; nil disables it.
+	Growth *Growth
+	// Tracer records a span for each /weather request; nil disables tracing.
+	Tracer *Tracer
+	// ShortBodyBytes is how many bytes more than the body shortBody responses
+	// advertise in Content-Length; 0 means defaultShortBodyBytes.
+	ShortBodyBytes int
+	// Attribution is the credit line of responses that ask for attribution.
+	Attribution string
+	// ClockSkew shifts the Date response header away from real time.
+	ClockSkew time.Duration
+	// SkewTimestamps also shifts generated /weather timestamps by ClockSkew.
+	SkewTimestamps bool
+	// Warmup adds decaying cold-start latency to /weather; nil disables it.
+	Warmup *Warmup
+	// Availability takes cities offline on a schedule; nil keeps them online.
+	Availability *AvailabilitySchedule
+	// History records served readings for export; nil disables it.
+	History *History
+	// Cache serves repeated identical /weather queries; nil disables caching.
+	Cache *ResponseCache
+	// Idempotency stores POST responses by Idempotency-Key; nil disables replays.
+	Idempotency *ResponseCache
+	// AccessLog receives combined-format access log lines; nil disables them.
+	AccessLog io.Writer
+	// BasePath mounts every route under a prefix such as "/api/weather-sim".
+	BasePath string
+	// HealthAtRoot also serves /health at the root when BasePath is set, for probes.
+	HealthAtRoot bool
+	// Debug enables endpoints that must never be exposed outside tests, such
+	// as /debug/reset.
+	Debug bool
+	// DisabledRoutes lists routes, such as "/metrics", that are not registered
+	// under any base path or version.
+	DisabledRoutes []string
+}
+
+func (svc *WeatherService) now() time.Time {
+	if svc.Now != nil {
+		return svc.Now()
+	}
+	return time.Now()
+}
+
+// rand returns the service's random source, falling back to the shared one.
+func (svc *WeatherService) rand() *rand.Rand {
+	if svc.Rand != nil {
+		return svc.Rand
+	}
+	return r
+}
+
+// Handler builds the complete server handler: the router wrapped in the
+// request-shaping middleware (trailing slashes, If-Match, connection=close,
+// maintenance mode, idempotency, decompression, body limits), the logging and in-flight
+// tracking, and the optional compression, header, clock skew and access log
+// middleware.
+func (svc *WeatherService) Handler() http.Handler {
+	var handler http.Handler = ifMatchMiddleware(metaETag, svc.Router())
+	if svc.Maintenance != nil {
+		handler = maintenanceMiddleware(svc.Maintenance, svc.maintenanceExemptPaths(), handler)
+	}
+	// Idempotency keys include the path, so it is normalized first.
+	if svc.Idempotency != nil {
+		handler = idempotencyMiddleware(svc.Idempotency, handler)
+	}
+	handler = connectionCloseMiddleware(trailingSlashMiddleware(handler))
+	handler = loggingMiddleware(svc.RequestLog, inFlightMiddleware(svc.Metrics, decompressRequestMiddleware(maxBodyMiddleware(svc.MaxBodyBytes, handler))))
+	if svc.GzipLevel != 0 {
+		handler = gzipMiddleware(svc.GzipLevel, handler)
+	}
+	if len(svc.InjectedHeaders) > 0 {
+		handler = headerInjectionMiddleware(svc.InjectedHeaders, handler)
+	}
+	if svc.ServerHeader != "" {
+		handler = serverHeaderMiddleware(svc.ServerHeader, handler)
+	}
+	if svc.ClockSkew != 0 {
+		handler = clockSkewMiddleware(svc.ClockSkew, handler)
+	}
+	if svc.AccessLog != nil {
+		handler = accessLogMiddleware(svc.AccessLog, handler)
+	}
+	return handler
+}
+
+// Router builds the ServeMux with every route the server exposes.
+func (svc *WeatherService) Router() *http.ServeMux {
+	// Define the handler for the /weather endpoint, injecting the sleeper and chooser.
+	// It is built once so the concurrency limit is shared by every version.
+	var weather http.Handler = http.HandlerFunc(svc.weather)
+	if svc.MaxConcurrency > 0 {
+		weather = concurrencyLimitMiddleware(svc.MaxConcurrency, weather)
+	}
+	if svc.Cache != nil {
+		weather = cacheMiddleware(svc.Cache, svc.Metrics, weather)
+	}
+	if svc.Deprecation != (Deprecation{}) {
+		weather = deprecationMiddleware(svc.Deprecation, weather)
+	}
+	if svc.DropConnectionRate > 0 {
+		weather = dropConnectionMiddleware(svc.DropConnectionRate, svc.rand, weather)
+	}
+	if svc.Tracer != nil {
+		weather = tracingMiddleware(svc.Tracer, weather)
+	}
+
+	base := normalizeBasePath(svc.BasePath)
+	mux := &routeMux{ServeMux: http.NewServeMux()}
+	for _, prefix := range apiVersions {
+		svc.registerRoutes(mux, base+prefix, weather)
+	}
+	if svc.HealthAtRoot && base != "" {
+		svc.handle(mux, "", "", "/health", http.HandlerFunc(svc.health))
+	}
+
+	// Debug and metrics routes are not part of the versioned API.
+	svc.handle(mux, "GET", base, "/metrics", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		metricsHandler(svc.Metrics, w, req)
+	}))
+	svc.handle(mux, "GET", base, "/debug/requests", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		debugRequestsHandler(svc.RequestLog, w, req)
+	}))
+	if svc.Stats != nil {
+		svc.handle(mux, "GET", base, "/debug/stats", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			debugStatsHandler(svc.Stats, w, req)
+		}))
+	}
+	if svc.Debug {
+		svc.handle(mux, "POST", base, "/debug/reset", http.HandlerFunc(svc.debugReset))
+		if svc.Maintenance != nil {
+			svc.handle(mux, "POST", base, "/debug/maintenance", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				maintenanceHandler(svc.Maintenance, w, req)
+			}))
+		}
+	}
+
+	// The root lists every route registered above. A route pattern ending in
+	// a slash would match subpaths too, so a base path is matched exactly.
+	index, root := slices.Clone(mux.routes), "/{$}"
+	if base != "" {
+		root = ""
+	}
+	svc.handle(mux, "GET", base, root, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		indexHandler(index, w, req)
+	}))
+	return mux.ServeMux
+}
+
+// normalizeBasePath returns path with a leading slash and without a trailing
+// one, or "" for the root.
+func normalizeBasePath(path string) string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return ""
+	}
+	return "/" + path
+}
+
+// parseRouteList parses a comma-separated list of routes such as
+// "/metrics, debug/requests/" into normalized paths.
+func parseRouteList(value string) []string {
+	var routes []string
+	for _, route := range strings.Split(value, ",") {
+		if route = normalizeBasePath(strings.TrimSpace(route)); route != "" {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// health serves /health from the registered health checks.
+func (svc *WeatherService) health(w http.ResponseWriter, req *http.Request) {
+	healthHandler(svc.Health, w, req)
+}
+
+// registerRoutes registers the API routes on mux under the given path prefix.
+func (svc *WeatherService) registerRoutes(mux *routeMux, prefix string, weather http.Handler) {
+	svc.handle(mux, "", prefix, "/weather", weather)
+	svc.handle(mux, "", prefix, "/weather/{city}", weather)
+	svc.handle(mux, "", prefix, "/weather/forecast", http.HandlerFunc(forecastHandler))
+	svc.handle(mux, "GET", prefix, "/weather/one", http.HandlerFunc(svc.oneReading))
+	svc.handle(mux, "GET", prefix, "/weather/backfill", http.HandlerFunc(backfillHandler))
+	svc.handle(mux, "GET", prefix, "/weather/rollup", http.HandlerFunc(svc.rollup))
+	svc.handle(mux, "GET", prefix, "/weather/batch", http.HandlerFunc(svc.batch))
+	if svc.History != nil {
+		svc.handle(mux, "GET", prefix, "/weather/history.ndjson", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			historyNDJSONHandler(svc.History, w, req)
+		}))
+	}
+	svc.handle(mux, "GET", prefix, "/weather/stress", http.HandlerFunc(stressHandler))
+	svc.handle(mux, "GET", prefix, "/weather/sse", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		sseHandler(sseInterval, w, req)
+	}))
+	svc.handle(mux, "POST", prefix, "/weather/validate", http.HandlerFunc(validateHandler))
+	svc.handle(mux, "", prefix, "/health", http.HandlerFunc(svc.health))
+	svc.handle(mux, "", prefix, "/ready", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		readyHandler(svc.Readiness, w, req)
+	}))
+	svc.handle(mux, "GET", prefix, "/meta", http.HandlerFunc(metaHandler))
+	svc.handle(mux, "GET", prefix, "/version", http.HandlerFunc(versionHandler))
+}
+
+// handle registers h on mux for route under prefix, restricted to method
+// unless it is empty. Routes listed in DisabledRoutes are skipped, so they
+// fall through to 404.
+func (svc *WeatherService) handle(mux *routeMux, method, prefix, route string, h http.Handler) {
+	if slices.Contains(svc.DisabledRoutes, route) {
+		return
+	}
+	pattern := prefix + route
+	if method != "" {
+		pattern = method + " " + pattern
+	}
+	mux.Handle(pattern, h)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// TestRouterVersionedRoutes tests that routes are served with and without the /v1 prefix.
+func TestRouterVersionedRoutes(t *testing.T) {
+	svc := &WeatherService{Sleeper: sleeper, Chooser: chooser, RequestLog: NewRequestLog(10), Metrics: &Metrics{}, Health: &HealthChecker{}}
+	router := svc.Router()
+
+	testCases := []struct {
+		path   string
+		status int
+	}{
+		{"/health", http.StatusOK},
+		{"/v1/health", http.StatusOK},
+		{"/v1/weather/forecast?city=Tokyo", http.StatusOK},
+		{"/v2/health", http.StatusNotFound},
+		{"/v1/debug/requests", http.StatusNotFound},
+		{"/metrics", http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", tc.path, nil))
+
+			if rr.Code != tc.status {
+				t.Errorf("Router returned wrong status code for %s: got %v want %v", tc.path, rr.Code, tc.status)
+			}
+		})
+	}
+}
+
+// TestRouterBasePath tests mounting every route under a base path.
+func TestRouterBasePath(t *testing.T) {
+	svc := &WeatherService{Sleeper: sleeper, Chooser: chooser, RequestLog: NewRequestLog(10), Metrics: &Metrics{}, Health: &HealthChecker{}, BasePath: "/api/weather-sim/"}
+
+	testCases := []struct {
+		path         string
+		healthAtRoot bool
+		status       int
+	}{
+		{"/api/weather-sim/health", false, http.StatusOK},
+		{"/api/weather-sim/v1/health", false, http.StatusOK},
+		{"/api/weather-sim/metrics", false, http.StatusOK},
+		{"/api/weather-sim/weather/forecast?city=Tokyo", false, http.StatusOK},
+		{"/health", false, http.StatusNotFound},
+		{"/weather/forecast?city=Tokyo", false, http.StatusNotFound},
+		{"/health", true, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			svc.HealthAtRoot = tc.healthAtRoot
+			rr := httptest.NewRecorder()
+			svc.Router().ServeHTTP(rr, httptest.NewRequest("GET", tc.path, nil))
+
+			if rr.Code != tc.status {
+				t.Errorf("Router returned wrong status code for %s: got %v want %v", tc.path, rr.Code, tc.status)
+			}
+		})
+	}
+}
+
+// TestRouterDisabledRoutes tests that disabled routes return 404 under every
+// version while the rest are still served.
+func TestRouterDisabledRoutes(t *testing.T) {
+	svc := &WeatherService{
+		Sleeper:        sleeper,
+		Chooser:        chooser,
+		RequestLog:     NewRequestLog(10),
+		Metrics:        &Metrics{},
+		Health:         &HealthChecker{},
+		DisabledRoutes: parseRouteList(" /metrics, debug/requests/,/weather/forecast"),
+	}
+	router := svc.Router()
+
+	testCases := []struct {
+		path   string
+		status int
+	}{
+		{"/metrics", http.StatusNotFound},
+		{"/debug/requests", http.StatusNotFound},
+		{"/weather/forecast?city=Tokyo", http.StatusNotFound},
+		{"/v1/weather/forecast?city=Tokyo", http.StatusNotFound},
+		{"/health", http.StatusOK},
+		{"/meta", http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", tc.path, nil))
+
+			if rr.Code != tc.status {
+				t.Errorf("Router returned wrong status code for %s: got %v want %v", tc.path, rr.Code, tc.status)
+			}
+		})
+	}
+}
+
+// TestRouterIndex tests that the root lists the registered routes, under the
+// base path and without the disabled ones.
+func TestRouterIndex(t *testing.T) {
+	svc := &WeatherService{Sleeper: sleeper, Chooser: chooser, RequestLog: NewRequestLog(10), Metrics: &Metrics{}, Health: &HealthChecker{},
+		BasePath: "/api", DisabledRoutes: []string{"/metrics"}}
+	router := svc.Router()
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Router returned wrong status code for the index: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var index IndexResponse
+	if err := json.NewDecoder(rr.Body).Decode(&index); err != nil {
+		t.Fatalf("Could not decode index: %v", err)
+	}
+	want := []RouteInfo{{Path: "/api/weather"}, {Method: "GET", Path: "/api/v1/weather/one"}, {Method: "GET", Path: "/api/debug/requests"}}
+	for _, route := range want {
+		if !slices.Contains(index.Endpoints, route) {
+			t.Errorf("Index is missing %+v: %+v", route, index.Endpoints)
+		}
+	}
+	for _, route := range index.Endpoints {
+		if route.Path == "/api/metrics" {
+			t.Errorf("Index lists the disabled route %+v", route)
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/nope", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Router returned wrong status code for an unknown path: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
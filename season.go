@@ -0,0 +1,90 @@
+package main
+
+import (
+	"maps"
+	"math/rand"
+	"strings"
+)
+
+// seasons are the accepted values of the season option. "fall" is accepted
+// as an alias of autumn.
+var seasons = []string{"winter", "spring", "summer", "autumn"}
+
+// defaultSeasonWeights are the condition weights of each season, in the
+// WEATHER_CONDITION_WEIGHTS format: winter favours Snowy and Cloudy, summer
+// Sunny.
+var defaultSeasonWeights = map[string]string{
+	"winter": "Sunny:1,Partly Cloudy:2,Cloudy:4,Rainy:2,Stormy:1,Foggy:2,Snowy:5",
+	"spring": "Sunny:3,Partly Cloudy:3,Cloudy:2,Rainy:3,Stormy:1,Foggy:1,Snowy:0",
+	"summer": "Sunny:6,Partly Cloudy:3,Cloudy:1,Rainy:1,Stormy:1,Foggy:0,Snowy:0",
+	"autumn": "Sunny:2,Partly Cloudy:2,Cloudy:3,Rainy:3,Stormy:1,Foggy:3,Snowy:0",
+}
+
+// lookupSeason returns the canonical name of a season, case-insensitively.
+func lookupSeason(name string) (string, bool) {
+	name = strings.ToLower(name)
+	if name == "fall" {
+		name = "autumn"
+	}
+	for _, season := range seasons {
+		if season == name {
+			return season, true
+		}
+	}
+	return "", false
+}
+
+// parseSeasonWeights parses a WEATHER_SEASON_WEIGHTS value such as
+// "winter=Snowy:8,Cloudy:4;summer=Sunny:9" and returns the weights of every
+// season, the listed ones replaced and the others as in defaultSeasonWeights.
+// A replaced season's unlisted conditions get a weight of 1, as in
+// WEATHER_CONDITION_WEIGHTS.
+func parseSeasonWeights(value string) (map[string][]int, error) {
+	specs := maps.Clone(defaultSeasonWeights)
+	if value != "" {
+		for _, entry := range strings.Split(value, ";") {
+			name, weights, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			if !ok {
+				return nil, errorOf(ErrBadConfig, "invalid season weights %q, expected season=Condition:weight,...", entry)
+			}
+			season, ok := lookupSeason(strings.TrimSpace(name))
+			if !ok {
+				return nil, errorOf(ErrBadConfig, "unknown season %q, expected one of %s", name, strings.Join(seasons, ", "))
+			}
+			specs[season] = weights
+		}
+	}
+
+	parsed := make(map[string][]int, len(specs))
+	// Check the seasons in order, so a bad value always reports the same one.
+	for _, season := range sortedKeys(specs) {
+		weights, err := parseConditionWeights(specs[season])
+		if err != nil {
+			return nil, errorOf(ErrBadConfig, "invalid weights for %s: %v", season, err)
+		}
+		parsed[season] = weights
+	}
+	return parsed, nil
+}
+
+// seasonWeights returns the condition weights of season, or nil for no
+// season. Services without configured weights use defaultSeasonWeights.
+func (svc *WeatherService) seasonWeights(season string) []int {
+	if season == "" {
+		return nil
+	}
+	if svc.SeasonWeights != nil {
+		return svc.SeasonWeights[season]
+	}
+	weights, _ := parseConditionWeights(defaultSeasonWeights[season])
+	return weights
+}
+
+// pickConditionWeighted selects a condition with the given weights, or with
+// the configured condition weights when weights is nil.
+func pickConditionWeighted(r *rand.Rand, weights []int) string {
+	if weights == nil {
+		return pickCondition(r)
+	}
+	return conditions[weightedIndex(r, weights)]
+}
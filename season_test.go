@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestParseSeasonWeights tests parsing of WEATHER_SEASON_WEIGHTS values.
+func TestParseSeasonWeights(t *testing.T) {
+	weights, err := parseSeasonWeights("Winter=Snowy:0,Cloudy:9; fall=Foggy:7")
+	if err != nil {
+		t.Fatalf("parseSeasonWeights returned an error: %v", err)
+	}
+	if len(weights) != len(seasons) {
+		t.Errorf("Got weights for %d seasons, want %d", len(weights), len(seasons))
+	}
+	index := func(condition string) int {
+		for i, c := range conditions {
+			if c == condition {
+				return i
+			}
+		}
+		t.Fatalf("Unknown condition %q", condition)
+		return -1
+	}
+	expected := []struct {
+		season, condition string
+		weight            int
+	}{
+		{"winter", "Snowy", 0},
+		{"winter", "Cloudy", 9},
+		{"winter", "Sunny", 1},
+		{"autumn", "Foggy", 7},
+		{"summer", "Sunny", 6},
+		{"summer", "Snowy", 0},
+	}
+	for _, want := range expected {
+		if got := weights[want.season][index(want.condition)]; got != want.weight {
+			t.Errorf("Wrong %s weight for %s: got %d want %d", want.season, want.condition, got, want.weight)
+		}
+	}
+
+	for _, value := range []string{"winter", "monsoon=Rainy:5", "summer=Hail:1", "winter=Sunny:0,Partly Cloudy:0,Cloudy:0,Rainy:0,Stormy:0,Foggy:0,Snowy:0"} {
+		if _, err := parseSeasonWeights(value); err == nil {
+			t.Errorf("Expected an error for %q, but got none.", value)
+		}
+	}
+}
+
+// TestWeatherHandlerSeason tests that the season option biases the
+// conditions: no snow in summer, and mostly snow and clouds in winter.
+func TestWeatherHandlerSeason(t *testing.T) {
+	counts := func(season string) map[string]int {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/weather?size=100&seed=1&season="+season, nil)
+		weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusOK}, rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("season=%s returned wrong status code: got %v want %v", season, rr.Code, http.StatusOK)
+		}
+		var responseData DataResponse
+		if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+			t.Fatalf("Could not decode response: %v", err)
+		}
+		counts := make(map[string]int)
+		for _, reading := range responseData.Readings {
+			counts[reading.Condition]++
+		}
+		return counts
+	}
+
+	summer := counts("summer")
+	if summer["Snowy"] != 0 || summer["Sunny"] < 30 {
+		t.Errorf("Summer conditions aren't mostly sunny: %v", summer)
+	}
+	winter := counts("WINTER")
+	if winter["Snowy"]+winter["Cloudy"] < 40 || winter["Sunny"] > 20 {
+		t.Errorf("Winter conditions aren't mostly snowy and cloudy: %v", winter)
+	}
+
+	rr := httptest.NewRecorder()
+	weatherHandler(sleeper, chooser, rr, httptest.NewRequest("GET", "/weather?season=monsoon", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Unknown season returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestWeatherHandlerShortBody tests that shortBody sends the whole body but
+// advertises more, then holds the connection open.
+func TestWeatherHandlerShortBody(t *testing.T) {
+	svc := newTestService(1, nil, &FixedStatusChooser{Status: http.StatusOK})
+	recording := &recordingSleeper{}
+	svc.Sleeper = recording
+	svc.ShortBodyBytes = 100
+
+	rr := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/weather?delayMs=0&shortBody=true", nil))
+
+	if rr.Code != http.StatusOK || !rr.Flushed {
+		t.Fatalf("Handler returned status %v, flushed %v; want 200 flushed", rr.Code, rr.Flushed)
+	}
+	if got, want := rr.Header().Get("Content-Length"), strconv.Itoa(rr.Body.Len()+100); got != want {
+		t.Errorf("Content-Length is %s, want %s", got, want)
+	}
+	if !json.Valid(rr.Body.Bytes()) {
+		t.Errorf("Body is not valid JSON: %s", rr.Body)
+	}
+	if recording.total != shortBodyHold {
+		t.Errorf("Handler held the connection for %v, want %v", recording.total, shortBodyHold)
+	}
+}
+
+// TestWeatherHandlerShortBodyInvalid tests that shortBody conflicts with the
+// other body faults.
+func TestWeatherHandlerShortBodyInvalid(t *testing.T) {
+	for _, target := range []string{
+		"/weather?shortBody=true&truncate=true",
+		"/weather?shortBody=true&keepAlive=true",
+		"/weather?shortBody=true&status=204",
+	} {
+		rr := httptest.NewRecorder()
+		weatherHandler(sleeper, chooser, rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s returned wrong status code: got %v want %v", target, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+// TestIntegrationShortBody tests that a real client reading a shortBody
+// response fails with an unexpected EOF once the server gives up.
+func TestIntegrationShortBody(t *testing.T) {
+	svc := newTestService(1, nil, &FixedStatusChooser{Status: http.StatusOK})
+	server := httptest.NewServer(svc.Handler())
+	defer server.Close()
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(server.URL + "/weather?delayMs=0&shortBody=true")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if want := int64(defaultShortBodyBytes); resp.ContentLength < want {
+		t.Fatalf("Content-Length is %d, want more than %d", resp.ContentLength, want)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Reading the body returned %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if got, want := int64(len(body)), resp.ContentLength-defaultShortBodyBytes; got != want {
+		t.Errorf("Read %d bytes, want %d", got, want)
+	}
+}
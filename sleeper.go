@@ -1,7 +1,10 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"math/rand"
+	"strings"
 	"time"
 )
 
@@ -10,6 +13,24 @@ type Sleeper interface {
 	Sleep(d time.Duration)
 }
 
+// ContextSleeper is a Sleeper that can also stop sleeping early when a
+// context is done. Handlers use SleepContext when the Sleeper supports it.
+type ContextSleeper interface {
+	Sleeper
+	// SleepContext sleeps for d, returning ctx.Err() early if ctx is done.
+	SleepContext(ctx context.Context, d time.Duration) error
+}
+
+// sleepContext sleeps for d with s, stopping early when ctx is done if s is a
+// ContextSleeper. Other Sleepers always sleep for the full duration.
+func sleepContext(ctx context.Context, s Sleeper, d time.Duration) error {
+	if cs, ok := s.(ContextSleeper); ok {
+		return cs.SleepContext(ctx, d)
+	}
+	s.Sleep(d)
+	return nil
+}
+
 // DefaultSleeper implements Sleeper using time.Sleep.
 type DefaultSleeper struct{}
 
@@ -24,6 +45,89 @@ type NoOpSleeper struct{}
 
 // Sleep does nothing.
 func (s *NoOpSleeper) Sleep(d time.Duration) {
-	log.Printf("NoOpSleeper: sleep called for %v\n", d)
+	slog.Debug("NoOpSleeper: sleep called", "duration", d)
 	// No operation, effectively zero delay
 }
+
+// ContextAwareSleeper implements ContextSleeper using a timer, so a request
+// whose client disconnects stops sleeping instead of holding its goroutine
+// for the rest of the delay.
+type ContextAwareSleeper struct{}
+
+// Sleep pauses the current goroutine for at least the duration d.
+func (s *ContextAwareSleeper) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// SleepContext pauses for d, or until ctx is done, whichever comes first.
+func (s *ContextAwareSleeper) SleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// JitterSleeper implements ContextSleeper by sleeping for d scaled by a random
+// factor in [1-Jitter, 1+Jitter], so even a fixed delay varies between
+// requests. The sleep itself is delegated to Inner.
+type JitterSleeper struct {
+	// Jitter is the largest relative deviation from d, between 0 and 1.
+	Jitter float64
+	// Inner does the sleeping; nil uses DefaultSleeper.
+	Inner Sleeper
+	// Rand chooses the factor; nil uses the shared random source.
+	Rand *rand.Rand
+}
+
+// jittered returns d scaled by a random factor within the jitter.
+func (s *JitterSleeper) jittered(d time.Duration) time.Duration {
+	rng := s.Rand
+	if rng == nil {
+		rng = r
+	}
+	factor := 1 + s.Jitter*(2*rng.Float64()-1)
+	return time.Duration(float64(d) * factor)
+}
+
+func (s *JitterSleeper) inner() Sleeper {
+	if s.Inner != nil {
+		return s.Inner
+	}
+	return &DefaultSleeper{}
+}
+
+// Sleep pauses for d with jitter applied.
+func (s *JitterSleeper) Sleep(d time.Duration) {
+	s.inner().Sleep(s.jittered(d))
+}
+
+// SleepContext pauses for d with jitter applied, stopping early when ctx is
+// done if Inner supports it.
+func (s *JitterSleeper) SleepContext(ctx context.Context, d time.Duration) error {
+	return sleepContext(ctx, s.inner(), s.jittered(d))
+}
+
+// newSleeper returns the Sleeper named by a WEATHER_SLEEPER value: "default"
+// (or empty), "noop", "context" or "jitter". The jitter sleeper uses the given
+// jitter and stops early when the client disconnects.
+func newSleeper(name string, jitter float64) (Sleeper, error) {
+	switch strings.ToLower(name) {
+	case "", "default":
+		return &DefaultSleeper{}, nil
+	case "noop":
+		return &NoOpSleeper{}, nil
+	case "context":
+		return &ContextAwareSleeper{}, nil
+	case "jitter":
+		if jitter < 0 || jitter > 1 {
+			return nil, errorOf(ErrBadConfig, "sleep jitter %v is out of range 0 to 1", jitter)
+		}
+		return &JitterSleeper{Jitter: jitter, Inner: &ContextAwareSleeper{}}, nil
+	default:
+		return nil, errorOf(ErrBadConfig, "unknown sleeper %q, expected default, noop, context or jitter", name)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestContextAwareSleeper tests that SleepContext stops when the context is
+// done and otherwise sleeps for the full duration.
+func TestContextAwareSleeper(t *testing.T) {
+	s := &ContextAwareSleeper{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if err := s.SleepContext(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("SleepContext returned wrong error: got %v want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("SleepContext did not return early: slept %v", elapsed)
+	}
+
+	start = time.Now()
+	if err := s.SleepContext(context.Background(), 20*time.Millisecond); err != nil {
+		t.Errorf("SleepContext returned an error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("SleepContext returned too early: slept %v", elapsed)
+	}
+}
+
+// TestJitterSleeper tests that sleeps vary within the jitter around the delay.
+func TestJitterSleeper(t *testing.T) {
+	recording := &recordingSleeper{}
+	s := &JitterSleeper{Jitter: 0.5, Inner: recording, Rand: rand.New(rand.NewSource(1))}
+
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		before := recording.total
+		s.Sleep(time.Second)
+		slept := recording.total - before
+		if slept < 500*time.Millisecond || slept > 1500*time.Millisecond {
+			t.Fatalf("Slept outside the jitter: got %v want 500ms to 1.5s", slept)
+		}
+		seen[slept] = true
+	}
+	if len(seen) < 50 {
+		t.Errorf("Expected varied sleeps, got %d distinct durations", len(seen))
+	}
+
+	exact := &recordingSleeper{}
+	(&JitterSleeper{Inner: exact}).Sleep(time.Second)
+	if exact.total != time.Second {
+		t.Errorf("Zero jitter changed the delay: got %v want %v", exact.total, time.Second)
+	}
+}
+
+// TestNewSleeper tests selecting a Sleeper by name.
+func TestNewSleeper(t *testing.T) {
+	testCases := []struct {
+		name   string
+		jitter float64
+		want   Sleeper
+	}{
+		{"", 0.2, &DefaultSleeper{}},
+		{"default", 0.2, &DefaultSleeper{}},
+		{"noop", 0.2, &NoOpSleeper{}},
+		{"Context", 0.2, &ContextAwareSleeper{}},
+		{"jitter", 0.2, &JitterSleeper{}},
+	}
+	for _, tc := range testCases {
+		got, err := newSleeper(tc.name, tc.jitter)
+		if err != nil {
+			t.Errorf("newSleeper(%q) returned an error: %v", tc.name, err)
+			continue
+		}
+		if gotType, wantType := fmt.Sprintf("%T", got), fmt.Sprintf("%T", tc.want); gotType != wantType {
+			t.Errorf("newSleeper(%q) returned wrong sleeper: got %s want %s", tc.name, gotType, wantType)
+		}
+	}
+
+	for _, bad := range []struct {
+		name   string
+		jitter float64
+	}{{"slow", 0.2}, {"jitter", 1.5}} {
+		if _, err := newSleeper(bad.name, bad.jitter); !errors.Is(err, ErrBadConfig) {
+			t.Errorf("newSleeper(%q, %v) should fail with ErrBadConfig, got %v", bad.name, bad.jitter, err)
+		}
+	}
+}
+
+// TestWeatherHandlerClientGone tests that a context-aware sleeper abandons the
+// response once the client has gone away.
+func TestWeatherHandlerClientGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("GET", "/weather?delayMs=60000", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+
+	start := time.Now()
+	weatherHandler(&ContextAwareSleeper{}, chooser, rr, req)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Handler kept sleeping after the client went away: %v", elapsed)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("Handler wrote a body for a client that went away: %q", rr.Body.String())
+	}
+}
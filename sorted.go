@@ -0,0 +1,18 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+)
+
+// sortedKeys returns the keys of m in ascending order. Use it whenever a map
+// is iterated to build a response, so output is stable across runs.
+// encoding/json already sorts map keys when marshaling a map directly.
+func sortedKeys[K cmp.Ordered, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestSortedKeys tests that map keys come back in a stable sorted order.
+func TestSortedKeys(t *testing.T) {
+	m := make(map[string]int)
+	for i, city := range cities {
+		m[city] = i
+	}
+
+	expected := slices.Clone(cities)
+	slices.Sort(expected)
+	for i := 0; i < 10; i++ {
+		if got := sortedKeys(m); !slices.Equal(got, expected) {
+			t.Fatalf("sortedKeys returned unexpected order: got %v want %v", got, expected)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// sseInterval is how often /weather/sse emits a new reading.
+const sseInterval = time.Second
+
+// sseHandler streams a new WeatherReading every interval as server-sent events
+// until the client disconnects.
+func sseHandler(interval time.Duration, w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	ctx := req.Context()
+	for id := 1; ; id++ {
+		reading := generateDummyWeatherReadings(r, 1)[0]
+		reading.Timestamp = time.Now().UTC()
+		reading.ID = readingID(reading)
+		data, err := json.Marshal(reading)
+		if err != nil {
+			slog.Error("Could not encode SSE reading", "error", err)
+			return
+		}
+		fmt.Fprintf(w, "id: %d\nevent: reading\ndata: %s\n\n", id, data)
+		flusher.Flush()
+
+		select {
+		case <-ctx.Done():
+			slog.Info("SSE client disconnected", "events", id)
+			return
+		case <-ticker.C:
+		}
+	}
+}
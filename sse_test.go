@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSSEHandler tests that /weather/sse streams readings until the client disconnects.
+func TestSSEHandler(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		sseHandler(10*time.Millisecond, w, req)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /weather/sse failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("SSE handler returned wrong content type: got %v want %v", contentType, "text/event-stream")
+	}
+
+	events := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for events < 3 && scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var reading WeatherReading
+		if err := json.Unmarshal([]byte(data), &reading); err != nil {
+			t.Fatalf("Could not decode SSE reading: %v", err)
+		}
+		if reading.City == "" {
+			t.Errorf("SSE reading is missing a city: %+v", reading)
+		}
+		events++
+	}
+	if events != 3 {
+		t.Errorf("SSE handler sent unexpected number of events: got %d want %d", events, 3)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// maxStationsPerCity is the most stations WEATHER_STATIONS_PER_CITY allows,
+// so station numbers fit in three digits.
+const maxStationsPerCity = 999
+
+// stationID returns the ID of a city's nth station, counting from zero, such
+// as "TOK-001" for Tokyo's first. IDs depend only on the city and number, so
+// they are the same on every server and for every seed.
+func stationID(city string, n int) string {
+	prefix := strings.ToUpper(strings.ReplaceAll(city, " ", ""))
+	return fmt.Sprintf("%s-%03d", prefix[:min(3, len(prefix))], n+1)
+}
+
+// pickStation returns the ID of a random one of city's perCity stations.
+// Fewer than one station per city means one.
+func pickStation(r *rand.Rand, city string, perCity int) string {
+	if perCity <= 1 {
+		return stationID(city, 0)
+	}
+	return stationID(city, r.Intn(perCity))
+}
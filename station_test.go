@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// TestStationID tests the station ID format.
+func TestStationID(t *testing.T) {
+	for _, tc := range []struct {
+		city string
+		n    int
+		want string
+	}{
+		{"Tokyo", 0, "TOK-001"},
+		{"New York", 11, "NEW-012"},
+		{"Rio", 998, "RIO-999"},
+	} {
+		if got := stationID(tc.city, tc.n); got != tc.want {
+			t.Errorf("stationID(%q, %d) = %q, want %q", tc.city, tc.n, got, tc.want)
+		}
+	}
+}
+
+// TestPickStation tests that stations are picked within the city's set and
+// that every station is used.
+func TestPickStation(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	want := []string{"PAR-001", "PAR-002", "PAR-003"}
+	seen := map[string]bool{}
+	for range 100 {
+		station := pickStation(rng, "Paris", 3)
+		if !slices.Contains(want, station) {
+			t.Fatalf("Picked station %q, want one of %v", station, want)
+		}
+		seen[station] = true
+	}
+	if len(seen) != len(want) {
+		t.Errorf("Picked %d of %d stations", len(seen), len(want))
+	}
+	if got := pickStation(rng, "Paris", 0); got != "PAR-001" {
+		t.Errorf("With no stations configured, picked %q, want PAR-001", got)
+	}
+}
+
+// TestWeatherHandlerStations tests that readings carry a station of their city
+// and that a seed reproduces the stations.
+func TestWeatherHandlerStations(t *testing.T) {
+	stations := func() []string {
+		svc := newTestService(1, nil, &FixedStatusChooser{Status: http.StatusOK})
+		svc.StationsPerCity = 4
+		rr := httptest.NewRecorder()
+		svc.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/weather?seed=7&size=50", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		var responseData DataResponse
+		if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+			t.Fatalf("Could not decode response: %v", err)
+		}
+		var ids []string
+		for _, reading := range responseData.Readings {
+			valid := false
+			for n := range 4 {
+				valid = valid || reading.StationID == stationID(reading.City, n)
+			}
+			if !valid {
+				t.Errorf("Reading for %s has station %q", reading.City, reading.StationID)
+			}
+			ids = append(ids, reading.StationID)
+		}
+		return ids
+	}
+
+	if first, second := stations(), stations(); !slices.Equal(first, second) {
+		t.Errorf("Same seed gave different stations: %v and %v", first, second)
+	}
+}
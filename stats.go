@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// StatusStats counts the status codes of the /weather responses produced
+// since start, to check the configured status distribution empirically.
+type StatusStats struct {
+	counts [600]atomic.Int64 // Indexed by status code
+}
+
+// StatsResponse is the response of GET /debug/stats.
+type StatsResponse struct {
+	Total int64 `json:"total"`
+	// Classes counts responses by class: "2xx", "3xx", "4xx" and "5xx".
+	Classes map[string]int64 `json:"classes"`
+	// Codes counts responses by exact status code, omitting unused codes.
+	Codes map[string]int64 `json:"codes"`
+}
+
+// Record counts a response with the given status. Nil StatusStats and codes
+// outside 100 to 599 are ignored.
+func (s *StatusStats) Record(status int) {
+	if s == nil || status < 100 || status >= len(s.counts) {
+		return
+	}
+	s.counts[status].Add(1)
+}
+
+// Snapshot returns the current counts. Counters are read one by one, so a
+// snapshot taken under load may be off by the requests recorded meanwhile.
+func (s *StatusStats) Snapshot() StatsResponse {
+	resp := StatsResponse{
+		Classes: map[string]int64{"2xx": 0, "3xx": 0, "4xx": 0, "5xx": 0},
+		Codes:   map[string]int64{},
+	}
+	for status := 100; status < len(s.counts); status++ {
+		n := s.counts[status].Load()
+		if n == 0 {
+			continue
+		}
+		resp.Total += n
+		resp.Classes[strconv.Itoa(status/100)+"xx"] += n
+		resp.Codes[strconv.Itoa(status)] = n
+	}
+	return resp
+}
+
+// Reset sets every count back to zero.
+func (s *StatusStats) Reset() {
+	for i := range s.counts {
+		s.counts[i].Store(0)
+	}
+}
+
+// debugStatsHandler serves the status code counts as JSON.
+func debugStatsHandler(s *StatusStats, w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, http.StatusOK, s.Snapshot())
+}
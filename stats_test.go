@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestDebugStats tests that /debug/stats counts the statuses produced by
+// /weather and that /debug/reset clears them.
+func TestDebugStats(t *testing.T) {
+	svc := newTestService(1, nil, &sequenceChooser{statuses: []int{200, 503, 200, 503}})
+	svc.Debug = true
+	handler := svc.Handler()
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, target, nil))
+		return rr
+	}
+	stats := func() StatsResponse {
+		var resp StatsResponse
+		if err := json.NewDecoder(serve("GET", "/debug/stats").Body).Decode(&resp); err != nil {
+			t.Fatalf("Could not decode stats: %v", err)
+		}
+		return resp
+	}
+
+	for range 4 {
+		serve("GET", "/weather")
+	}
+	serve("GET", "/weather?status=404")
+	serve("GET", "/weather?units=kelvin") // Rejected before a status is chosen
+	serve("GET", "/health")
+
+	got := stats()
+	if got.Total != 5 {
+		t.Errorf("Stats counted %d responses, want 5", got.Total)
+	}
+	for class, want := range map[string]int64{"2xx": 2, "3xx": 0, "4xx": 1, "5xx": 2} {
+		if got.Classes[class] != want {
+			t.Errorf("Stats counted %d %s responses, want %d", got.Classes[class], class, want)
+		}
+	}
+	if got.Codes["503"] != 2 || got.Codes["404"] != 1 || len(got.Codes) != 3 {
+		t.Errorf("Stats returned wrong codes: %v", got.Codes)
+	}
+
+	serve("POST", "/debug/reset")
+	if got := stats(); got.Total != 0 || len(got.Codes) != 0 {
+		t.Errorf("Reset did not clear the stats: %+v", got)
+	}
+}
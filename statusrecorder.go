@@ -0,0 +1,57 @@
+package main
+
+import "net/http"
+
+// statusRecorder wraps an http.ResponseWriter to remember the status code
+// and count the bytes written, for middleware that needs them after the
+// handler has run (metrics, access logs, ETags).
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// WriteHeader records the first status code before forwarding it.
+func (rec *statusRecorder) WriteHeader(code int) {
+	if rec.status == 0 {
+		rec.status = code
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+// Write records an implicit 200 if no status was written yet and counts the
+// bytes the underlying writer accepted.
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+// Flush forwards to the underlying writer if it supports flushing.
+func (rec *statusRecorder) Flush() {
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer so http.ResponseController can reach it.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Status returns the recorded status code, or 200 if the handler never
+// called WriteHeader or Write, matching what net/http sends in that case.
+func (rec *statusRecorder) Status() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
+
+// BytesWritten returns the total number of body bytes written.
+func (rec *statusRecorder) BytesWritten() int64 {
+	return rec.bytes
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestStatusRecorderDefaultsTo200 tests the status when the handler never sets one.
+func TestStatusRecorderDefaultsTo200(t *testing.T) {
+	rec := &statusRecorder{ResponseWriter: httptest.NewRecorder()}
+	if rec.Status() != http.StatusOK {
+		t.Errorf("Recorder returned wrong default status: got %v want %v", rec.Status(), http.StatusOK)
+	}
+
+	rec.Write([]byte("body"))
+	if rec.Status() != http.StatusOK {
+		t.Errorf("Recorder returned wrong status after implicit write: got %v want %v", rec.Status(), http.StatusOK)
+	}
+}
+
+// TestStatusRecorderForwards tests that status and body reach the underlying writer.
+func TestStatusRecorderForwards(t *testing.T) {
+	rr := httptest.NewRecorder()
+	rec := &statusRecorder{ResponseWriter: rr}
+
+	rec.Header().Set("X-Test", "1")
+	rec.WriteHeader(http.StatusNotFound)
+	rec.WriteHeader(http.StatusInternalServerError) // Superfluous; the first code wins
+	rec.Write([]byte("hello, "))
+	rec.Write([]byte("world"))
+	rec.Flush()
+
+	if rec.Status() != http.StatusNotFound {
+		t.Errorf("Recorder returned wrong status: got %v want %v", rec.Status(), http.StatusNotFound)
+	}
+	if rec.BytesWritten() != 12 {
+		t.Errorf("Recorder counted wrong number of bytes: got %d want %d", rec.BytesWritten(), 12)
+	}
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Underlying writer got wrong status: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+	if rr.Body.String() != "hello, world" {
+		t.Errorf("Underlying writer got wrong body: got %q want %q", rr.Body.String(), "hello, world")
+	}
+	if rr.Header().Get("X-Test") != "1" {
+		t.Errorf("Underlying writer is missing the header set through the recorder.")
+	}
+	if !rr.Flushed {
+		t.Errorf("Flush was not forwarded to the underlying writer.")
+	}
+	if http.NewResponseController(rec).Flush() != nil {
+		t.Errorf("ResponseController could not reach the underlying writer.")
+	}
+}
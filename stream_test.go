@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// flushCounter counts the flushes of a ResponseRecorder.
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (f *flushCounter) Flush() {
+	f.flushes++
+	f.ResponseRecorder.Flush()
+}
+
+// TestWriteJSONArray tests the brackets and commas of streamed arrays,
+// including the empty and single-element edge cases.
+func TestWriteJSONArray(t *testing.T) {
+	testCases := []struct {
+		items []int
+		want  string
+	}{
+		{nil, "[]\n"},
+		{[]int{}, "[]\n"},
+		{[]int{1}, "[1]\n"},
+		{[]int{1, 2, 3}, "[1,2,3]\n"},
+	}
+	for _, tc := range testCases {
+		w := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+		if err := writeJSONArray(w, http.StatusOK, tc.items); err != nil {
+			t.Fatalf("writeJSONArray(%v) returned an error: %v", tc.items, err)
+		}
+		if got := w.Body.String(); got != tc.want {
+			t.Errorf("writeJSONArray(%v) wrote %q, want %q", tc.items, got, tc.want)
+		}
+		// One flush for each bracket and one per element.
+		if want := len(tc.items) + 2; w.flushes != want {
+			t.Errorf("writeJSONArray(%v) flushed %d times, want %d", tc.items, w.flushes, want)
+		}
+	}
+}
+
+// TestWeatherHandlerStreamArray tests that stream=array serves the readings
+// as a bare JSON array, while errors keep their message object.
+func TestWeatherHandlerStreamArray(t *testing.T) {
+	w := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+	weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusOK}, w, httptest.NewRequest("GET", "/weather?size=12&stream=array", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Handler returned wrong Content-Type: got %q want %q", got, "application/json")
+	}
+	if got := w.Header().Get("Content-Length"); got != "" {
+		t.Errorf("Streamed response has a Content-Length: %s", got)
+	}
+	var readings []WeatherReading
+	if err := json.Unmarshal(w.Body.Bytes(), &readings); err != nil {
+		t.Fatalf("Body is not a JSON array of readings: %v: %s", err, w.Body)
+	}
+	if len(readings) != 12 || w.flushes != 14 {
+		t.Errorf("Handler streamed %d readings in %d flushes, want 12 in 14", len(readings), w.flushes)
+	}
+
+	// A one-reading range is still a valid array.
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/weather?stream=array", nil)
+	req.Header.Set("Range", rangeUnit+"=0-0")
+	weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusOK}, rr, req)
+	if err := json.Unmarshal(rr.Body.Bytes(), &readings); err != nil || len(readings) != 1 {
+		t.Errorf("One-reading range returned %s, %v; want an array of one reading", rr.Body, err)
+	}
+
+	rr = httptest.NewRecorder()
+	weatherHandler(sleeper, &FixedStatusChooser{Status: http.StatusInternalServerError}, rr, httptest.NewRequest("GET", "/weather?stream=array", nil))
+	var responseData DataResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &responseData); err != nil || responseData.Message == "" {
+		t.Errorf("Error response is not a message object: %s", rr.Body)
+	}
+
+	for _, target := range []string{"/weather?stream=object", "/weather?stream=array&groupBy=city", "/weather?stream=array&truncate=true"} {
+		rr := httptest.NewRecorder()
+		weatherHandler(sleeper, chooser, rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s returned wrong status code: got %v want %v", target, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxStressDuration caps how long a single stress run may take.
+const maxStressDuration = 60 * time.Second
+
+// StressResponse summarizes a generation throughput run.
+type StressResponse struct {
+	Duration          string  `json:"duration"`
+	RPS               int     `json:"rps"`
+	Size              int     `json:"size"`
+	Batches           int     `json:"batches"`
+	Readings          int     `json:"readings"`
+	ElapsedMs         float64 `json:"elapsed_ms"`
+	ReadingsPerSecond float64 `json:"readings_per_second"`
+	Message           string  `json:"message,omitempty"`
+}
+
+// runStress calls generateDummyWeatherReadings with size readings per batch
+// for duration, limited to rps batches per second (0 means unlimited), and
+// reports how many batches were generated. It stops early if stop is closed.
+func runStress(duration time.Duration, rps, size int, stop <-chan struct{}) StressResponse {
+	rng := rand.New(rand.NewSource(r.Int63()))
+	start := time.Now()
+	deadline := start.Add(duration)
+
+	batches := 0
+	for now := start; now.Before(deadline); now = time.Now() {
+		select {
+		case <-stop:
+			deadline = now
+			continue
+		default:
+		}
+		if rps > 0 {
+			// Pace batches evenly; wait until the next batch is due.
+			due := start.Add(time.Duration(batches) * time.Second / time.Duration(rps))
+			if wait := due.Sub(now); wait > 0 {
+				time.Sleep(min(wait, deadline.Sub(now)))
+				continue
+			}
+		}
+		generateDummyWeatherReadings(rng, size)
+		batches++
+	}
+
+	elapsed := time.Since(start)
+	return StressResponse{
+		Duration:          duration.String(),
+		RPS:               rps,
+		Size:              size,
+		Batches:           batches,
+		Readings:          batches * size,
+		ElapsedMs:         float64(elapsed) / float64(time.Millisecond),
+		ReadingsPerSecond: float64(batches*size) / elapsed.Seconds(),
+	}
+}
+
+// stressHandler handles requests to the /weather/stress endpoint.
+func stressHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	query := req.URL.Query()
+
+	duration := time.Second
+	if durationStr := query.Get("duration"); durationStr != "" {
+		var err error
+		duration, err = time.ParseDuration(durationStr)
+		if err != nil || duration <= 0 || duration > maxStressDuration {
+			writeJSON(w, http.StatusBadRequest, StressResponse{
+				Message: fmt.Sprintf("Invalid 'duration' parameter %q, expected a duration up to %v.", durationStr, maxStressDuration),
+			})
+			return
+		}
+	}
+
+	rps := 0 // Unlimited by default
+	if rpsStr := query.Get("rps"); rpsStr != "" {
+		var err error
+		rps, err = strconv.Atoi(rpsStr)
+		if err != nil || rps < 0 {
+			writeJSON(w, http.StatusBadRequest, StressResponse{
+				Message: fmt.Sprintf("Invalid 'rps' parameter %q, expected a non-negative integer.", rpsStr),
+			})
+			return
+		}
+	}
+
+	size, err := strconv.Atoi(query.Get("size"))
+	if err != nil || checkSize(size) != nil {
+		size = 10 // Default size, matching /weather
+	}
+
+	slog.Info("Starting stress run", "duration", duration, "rps", rps, "size", size)
+	result := runStress(duration, rps, size, req.Context().Done())
+	result.Message = fmt.Sprintf("Generated %d readings in %d batches.", result.Readings, result.Batches)
+	slog.Info("Finished stress run", "batches", result.Batches, "readings_per_second", result.ReadingsPerSecond)
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestRunStressPaced tests that the rps limit paces batch generation.
+func TestRunStressPaced(t *testing.T) {
+	result := runStress(200*time.Millisecond, 50, 10, nil)
+
+	// 50 batches per second for 200ms is 10 batches.
+	if result.Batches < 8 || result.Batches > 11 {
+		t.Errorf("Paced stress run produced unexpected number of batches: got %d want about %d", result.Batches, 10)
+	}
+	if result.Readings != result.Batches*10 {
+		t.Errorf("Stress run reported %d readings for %d batches of 10", result.Readings, result.Batches)
+	}
+}
+
+// TestStressHandler tests the /weather/stress endpoint.
+func TestStressHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/weather/stress?duration=20ms", nil)
+	rr := httptest.NewRecorder()
+	stressHandler(rr, req)
+
+	var responseData StressResponse
+	if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	if rr.Code != http.StatusOK || responseData.Batches == 0 {
+		t.Errorf("Stress handler returned unexpected result: status %d, %+v", rr.Code, responseData)
+	}
+
+	for _, query := range []string{"duration=abc", "duration=5m", "rps=-1"} {
+		req := httptest.NewRequest("GET", "/weather/stress?"+query, nil)
+		rr := httptest.NewRecorder()
+		stressHandler(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Stress handler returned wrong status code for %s: got %v want %v", query, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
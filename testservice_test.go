@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math/rand"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testEpoch is the fixed time a newTestService generates readings around.
+var testEpoch = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestService returns a fully deterministic WeatherService for tests: its
+// readings, delays and chosen statuses come from a source seeded with seed and
+// its timestamps are generated around testEpoch, so two services built with
+// the same arguments serve identical responses to the same requests. A nil
+// sleeper never sleeps and a nil chooser always picks 200. Optional features
+// such as caching and history are disabled; set the fields to enable them.
+func newTestService(seed int64, sleeper Sleeper, chooser StatusChooser) *WeatherService {
+	if sleeper == nil {
+		sleeper = &NoOpSleeper{}
+	}
+	if chooser == nil {
+		chooser = &FixedStatusChooser{Status: 200}
+	}
+	return &WeatherService{
+		Sleeper:    sleeper,
+		Chooser:    chooser,
+		RequestLog: NewRequestLog(100),
+		Metrics:    &Metrics{},
+		Health:     &HealthChecker{},
+		Stats:      &StatusStats{},
+		Rand:       rand.New(newLockedSource(seed)),
+		Now:        func() time.Time { return testEpoch },
+	}
+}
+
+// TestTestServiceDeterministic tests that services with the same seed serve
+// identical bodies and that different seeds differ.
+func TestTestServiceDeterministic(t *testing.T) {
+	body := func(seed int64) string {
+		rr := httptest.NewRecorder()
+		newTestService(seed, nil, nil).Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/weather?size=20", nil))
+		return rr.Body.String()
+	}
+
+	if a, b := body(7), body(7); a != b {
+		t.Errorf("Same seed produced different bodies:\n%s\n%s", a, b)
+	}
+	if a, b := body(7), body(8); a == b {
+		t.Errorf("Different seeds produced the same body: %s", a)
+	}
+}
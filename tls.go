@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"log/slog"
+	"math/big"
+	"net"
+	"time"
+)
+
+// selfSignedValidity is how long a generated self-signed certificate is valid.
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// selfSignedCertificate generates an in-memory ECDSA certificate for
+// localhost, 127.0.0.1 and ::1, valid from now for selfSignedValidity. It is
+// its own CA, so clients must either trust it or skip verification.
+func selfSignedCertificate(now time.Time) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"go-weather self-signed"}, CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:             now.Add(-time.Minute), // Tolerate small clock differences
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// newTLSConfig returns the TLS configuration described by cfg: the key pair
+// in TLSCert and TLSKey, a generated self-signed certificate with
+// TLSSelfSigned, or nil to serve plain HTTP.
+func newTLSConfig(cfg Config) (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+	switch {
+	case cfg.TLSCert != "":
+		if cert, err = tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey); err != nil {
+			return nil, errorOf(ErrBadConfig, "could not load WEATHER_TLS_CERT and WEATHER_TLS_KEY: %v", err)
+		}
+		slog.Info("Serving TLS", "cert", cfg.TLSCert)
+	case cfg.TLSSelfSigned:
+		if cert, err = selfSignedCertificate(time.Now()); err != nil {
+			return nil, err
+		}
+		// Clients can pin the fingerprint instead of skipping verification.
+		fingerprint := sha256.Sum256(cert.Certificate[0])
+		slog.Warn("Serving TLS with a generated self-signed certificate", "sha256", hex.EncodeToString(fingerprint[:]), "expires", cert.Leaf.NotAfter)
+	default:
+		return nil, nil
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
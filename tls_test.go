@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestSelfSignedCertificate tests that a client trusting the generated
+// certificate can connect and that one with the system roots cannot.
+func TestSelfSignedCertificate(t *testing.T) {
+	tlsConfig, err := newTLSConfig(Config{TLSSelfSigned: true})
+	if err != nil {
+		t.Fatalf("Could not set up TLS: %v", err)
+	}
+	server := httptest.NewUnstartedServer(newTestService(1, nil, nil).Handler())
+	server.TLS = tlsConfig
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(tlsConfig.Certificates[0].Leaf)
+	trusting := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	resp, err := trusting.Get(server.URL + "/health")
+	if err != nil {
+		t.Fatalf("Client trusting the certificate could not connect: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Health returned wrong status code over TLS: got %v want %v", resp.StatusCode, http.StatusOK)
+	}
+
+	if _, err := http.Get(server.URL + "/health"); err == nil {
+		t.Errorf("Client with the system roots accepted the self-signed certificate")
+	}
+}
+
+// TestNewTLSConfigFromFiles tests loading a key pair and the plain HTTP default.
+func TestNewTLSConfigFromFiles(t *testing.T) {
+	if tlsConfig, err := newTLSConfig(Config{}); tlsConfig != nil || err != nil {
+		t.Errorf("Expected plain HTTP without TLS settings, got %v, %v", tlsConfig, err)
+	}
+
+	cert, err := selfSignedCertificate(time.Now())
+	if err != nil {
+		t.Fatalf("Could not generate a certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatalf("Could not encode the key: %v", err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600)
+
+	tlsConfig, err := newTLSConfig(Config{TLSCert: certFile, TLSKey: keyFile})
+	if err != nil || len(tlsConfig.Certificates) != 1 {
+		t.Fatalf("Could not load the key pair: %v", err)
+	}
+	if _, err := newTLSConfig(Config{TLSCert: certFile, TLSKey: certFile}); err == nil {
+		t.Errorf("Expected an error for a certificate used as the key")
+	}
+}
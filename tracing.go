@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// tracerExportTimeout bounds each export and the final flush, so a stuck
+	// collector can't hold up shutdown.
+	tracerExportTimeout = 10 * time.Second
+	// tracerScope names this service as the instrumentation scope.
+	tracerScope = "github.com/salus-templates/go-weather"
+)
+
+// traceContext extracts the W3C traceparent of incoming requests.
+var traceContext = propagation.TraceContext{}
+
+// Tracer records a span for each /weather request with the OpenTelemetry SDK
+// and exports them in batches, every 5 seconds and on shutdown.
+type Tracer struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+	exported atomic.Int64
+	// exportErr is the error of the last export, nil once one succeeds.
+	exportErr atomic.Pointer[error]
+}
+
+// NewTracer returns a Tracer exporting to endpoint, an OTLP/HTTP traces URL
+// such as http://localhost:4318/v1/traces, with serviceName as the
+// service.name resource attribute (go-weather when empty).
+func NewTracer(endpoint, serviceName string) (*Tracer, error) {
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(endpoint),
+		otlptracehttp.WithTimeout(tracerExportTimeout))
+	if err != nil {
+		return nil, err
+	}
+	return newTracer(exporter, serviceName), nil
+}
+
+// newTracer returns a Tracer sending its spans to exporter. Requests whose
+// parent wasn't sampled are not recorded, so the stub follows the caller's
+// sampling.
+func newTracer(exporter sdktrace.SpanExporter, serviceName string) *Tracer {
+	if serviceName == "" {
+		serviceName = "go-weather"
+	}
+	t := &Tracer{}
+	t.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(&countingExporter{SpanExporter: exporter, tracer: t}),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+	)
+	t.tracer = t.provider.Tracer(tracerScope, trace.WithInstrumentationVersion(buildVersion()))
+	return t
+}
+
+// Shutdown exports the spans still queued and stops the Tracer, returning how
+// many spans were exported since the last Shutdown or ForceFlush.
+func (t *Tracer) Shutdown() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tracerExportTimeout)
+	defer cancel()
+	err := t.provider.Shutdown(ctx)
+	return int(t.exported.Swap(0)), err
+}
+
+// ForceFlush exports the queued spans without stopping the Tracer, returning
+// how many spans were exported since the last Shutdown or ForceFlush.
+func (t *Tracer) ForceFlush() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tracerExportTimeout)
+	defer cancel()
+	err := t.provider.ForceFlush(ctx)
+	return int(t.exported.Swap(0)), err
+}
+
+// ExportError returns the error of the last span export, or nil when it
+// succeeded or nothing has been exported yet.
+func (t *Tracer) ExportError() error {
+	if err := t.exportErr.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// countingExporter records the outcome of its SpanExporter's exports on tracer.
+type countingExporter struct {
+	sdktrace.SpanExporter
+	tracer *Tracer
+}
+
+func (e *countingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if err := e.SpanExporter.ExportSpans(ctx, spans); err != nil {
+		e.tracer.exportErr.Store(&err)
+		return err
+	}
+	e.tracer.exportErr.Store(nil)
+	e.tracer.exported.Add(int64(len(spans)))
+	return nil
+}
+
+// tracingMiddleware records a server span for each request, as a child of the
+// incoming traceparent when there is one. Handlers add attributes through
+// trace.SpanFromContext, and 5xx responses mark the span as an error.
+func tracingMiddleware(t *Tracer, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := traceContext.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		ctx, span := t.tracer.Start(ctx, req.Method+" "+req.URL.Path,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", req.Method),
+				attribute.String("url.path", req.URL.Path),
+			))
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, req.WithContext(ctx))
+
+		// The pattern is only known once the mux has matched the request.
+		if req.Pattern != "" {
+			span.SetName(req.Pattern)
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", rec.status))
+		if rec.status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+	})
+}
+
+// tracesEndpoint returns the OTLP/HTTP traces URL from the standard
+// OpenTelemetry variables: the signal-specific one as is, or the base
+// endpoint with /v1/traces appended. Empty means tracing is off.
+func tracesEndpoint(tracesURL, baseURL string) string {
+	if tracesURL != "" {
+		return tracesURL
+	}
+	if baseURL != "" {
+		return strings.TrimSuffix(baseURL, "/") + "/v1/traces"
+	}
+	return ""
+}
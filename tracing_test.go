@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestTracingTraceparent tests which incoming traceparent headers are
+// continued, which start a new trace and which aren't recorded.
+func TestTracingTraceparent(t *testing.T) {
+	const parentTraceID, parentSpanID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	testCases := []struct {
+		value     string
+		recorded  bool
+		continued bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", false, false},
+		{"", true, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", true, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", true, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", true, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01", true, false},
+	}
+	for _, tc := range testCases {
+		exporter := tracetest.NewInMemoryExporter()
+		tracer := newTracer(exporter, "")
+		handler := tracingMiddleware(tracer, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+
+		req := httptest.NewRequest("GET", "/weather", nil)
+		if tc.value != "" {
+			req.Header.Set("traceparent", tc.value)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		tracer.ForceFlush()
+
+		spans := exporter.GetSpans()
+		if len(spans) != 1 {
+			if tc.recorded {
+				t.Errorf("traceparent %q: recorded %d spans, want 1", tc.value, len(spans))
+			}
+			continue
+		}
+		if !tc.recorded {
+			t.Errorf("traceparent %q: recorded a span for an unsampled parent", tc.value)
+			continue
+		}
+		continued := spans[0].SpanContext.TraceID().String() == parentTraceID && spans[0].Parent.SpanID().String() == parentSpanID
+		if continued != tc.continued {
+			t.Errorf("traceparent %q: continued the trace = %v, want %v", tc.value, continued, tc.continued)
+		}
+	}
+}
+
+// TestTracingExport tests that a traced /weather request records a server
+// span that continues the incoming trace and carries the delay, status and
+// size.
+func TestTracingExport(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	svc := newTestService(1, nil, &FixedStatusChooser{Status: http.StatusServiceUnavailable})
+	svc.Tracer = newTracer(exporter, "")
+	handler := svc.Handler()
+
+	req := httptest.NewRequest("GET", "/weather?size=20&delayMs=0", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if n, err := svc.Tracer.ForceFlush(); n != 1 || err != nil {
+		t.Fatalf("ForceFlush() = %d, %v; want 1, nil", n, err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Recorded %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("Span doesn't continue the incoming trace: trace %s, parent %s", span.SpanContext.TraceID(), span.Parent.SpanID())
+	}
+	if !strings.Contains(span.Name, "/weather") || span.SpanKind != trace.SpanKindServer || span.Status.Code != codes.Error {
+		t.Errorf("Span has wrong name, kind or status: %q, %v, %v", span.Name, span.SpanKind, span.Status)
+	}
+	attributes := map[string]string{}
+	for _, attribute := range span.Attributes {
+		attributes[string(attribute.Key)] = attribute.Value.Emit()
+	}
+	for key, want := range map[string]string{"weather.delay_ms": "0", "weather.status": "503", "weather.size": "20", "http.response.status_code": "503"} {
+		if attributes[key] != want {
+			t.Errorf("Span attribute %s is %q, want %q", key, attributes[key], want)
+		}
+	}
+	if name, _ := span.Resource.Set().Value("service.name"); name.AsString() != "go-weather" {
+		t.Errorf("service.name is %q, want go-weather", name.AsString())
+	}
+
+	// Nothing is left to export.
+	if n, err := svc.Tracer.Shutdown(); n != 0 || err != nil {
+		t.Errorf("Shutdown() = %d, %v; want 0, nil", n, err)
+	}
+}
+
+// TestTracerOTLP tests that NewTracer exports spans to the configured
+// collector with OTLP over HTTP.
+func TestTracerOTLP(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests <- req
+	}))
+	defer collector.Close()
+
+	tracer, err := NewTracer(collector.URL+"/v1/traces", "weather-test")
+	if err != nil {
+		t.Fatalf("NewTracer() error: %v", err)
+	}
+	handler := tracingMiddleware(tracer, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/weather", nil))
+
+	if n, err := tracer.Shutdown(); n != 1 || err != nil {
+		t.Fatalf("Shutdown() = %d, %v; want 1, nil", n, err)
+	}
+	req := <-requests
+	if req.Method != http.MethodPost || req.URL.Path != "/v1/traces" || req.Header.Get("Content-Type") != "application/x-protobuf" {
+		t.Errorf("Collector got %s %s with Content-Type %q, want an OTLP/HTTP protobuf export",
+			req.Method, req.URL.Path, req.Header.Get("Content-Type"))
+	}
+}
+
+// failingExporter is a SpanExporter whose exports always fail.
+type failingExporter struct{}
+
+func (failingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	return errors.New("collector unavailable")
+}
+
+func (failingExporter) Shutdown(ctx context.Context) error { return nil }
+
+// TestTracerExportError tests that ExportError reports the last failed export
+// until one succeeds.
+func TestTracerExportError(t *testing.T) {
+	tracer := newTracer(failingExporter{}, "")
+	if err := tracer.ExportError(); err != nil {
+		t.Errorf("ExportError() before any export = %v, want nil", err)
+	}
+	handler := tracingMiddleware(tracer, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/weather", nil))
+	tracer.ForceFlush()
+	if err := tracer.ExportError(); err == nil {
+		t.Errorf("ExportError() after a failed export = nil, want an error")
+	}
+}
+
+// TestTracesEndpoint tests how the OTLP endpoint variables combine.
+func TestTracesEndpoint(t *testing.T) {
+	testCases := []struct{ traces, base, want string }{
+		{"", "", ""},
+		{"", "http://collector:4318", "http://collector:4318/v1/traces"},
+		{"", "http://collector:4318/", "http://collector:4318/v1/traces"},
+		{"http://collector:4318/custom", "http://other:4318", "http://collector:4318/custom"},
+	}
+	for _, tc := range testCases {
+		if got := tracesEndpoint(tc.traces, tc.base); got != tc.want {
+			t.Errorf("tracesEndpoint(%q, %q) = %q, want %q", tc.traces, tc.base, got, tc.want)
+		}
+	}
+}
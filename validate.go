@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ValidationResponse reports whether a submitted reading is valid.
+type ValidationResponse struct {
+	Valid      bool     `json:"valid"`
+	Violations []string `json:"violations,omitempty"`
+	Message    string   `json:"message,omitempty"`
+}
+
+// validateReading checks a reading against the rules the generator obeys and
+// returns a description of every violated rule.
+func validateReading(reading WeatherReading) []string {
+	var violations []string
+	if strings.TrimSpace(reading.City) == "" {
+		violations = append(violations, "city must not be empty")
+	}
+	if reading.Humidity < 0 || reading.Humidity > 100 {
+		violations = append(violations, fmt.Sprintf("humidity must be between 0 and 100, got %d", reading.Humidity))
+	}
+	if !slices.Contains(conditions, reading.Condition) {
+		violations = append(violations, fmt.Sprintf("condition must be one of %s, got %q", strings.Join(conditions, ", "), clipInput(reading.Condition)))
+	}
+	return violations
+}
+
+const (
+	// maxReadingBytes is the largest body /weather/validate accepts; a single
+	// reading is well under 1 KB.
+	maxReadingBytes = 16 << 10
+	// maxReadingDepth is the deepest JSON nesting /weather/validate accepts. A
+	// reading is a flat object, so anything deeper is rejected.
+	maxReadingDepth = 2
+)
+
+// errReadingTooLarge reports a /weather/validate body over maxReadingBytes.
+var errReadingTooLarge = fmt.Errorf("body exceeds %d bytes", maxReadingBytes)
+
+// jsonDepthError reports JSON nested deeper than allowed.
+type jsonDepthError struct {
+	max int
+}
+
+func (e *jsonDepthError) Error() string {
+	return fmt.Sprintf("JSON nesting exceeds a depth of %d", e.max)
+}
+
+// decodeReading strictly decodes a single reading from body: it must be at
+// most maxReadingBytes, nest no deeper than maxReadingDepth and contain no
+// unknown fields.
+func decodeReading(body io.Reader) (WeatherReading, error) {
+	var reading WeatherReading
+	data, err := io.ReadAll(io.LimitReader(body, maxReadingBytes+1))
+	if err != nil {
+		return reading, err
+	}
+	if len(data) > maxReadingBytes {
+		return reading, errReadingTooLarge
+	}
+	if err := checkJSONDepth(data, maxReadingDepth); err != nil {
+		return reading, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&reading); err != nil {
+		return reading, err
+	}
+	return reading, nil
+}
+
+// checkJSONDepth returns a *jsonDepthError if data nests objects or arrays
+// more than max levels deep. Syntax errors are left for the real decode.
+func checkJSONDepth(data []byte, max int) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			if depth > max {
+				return &jsonDepthError{max: max}
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
+
+// validateHandler handles POST requests to the /weather/validate endpoint.
+func validateHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	reading, err := decodeReading(req.Body)
+	if err != nil {
+		var tooDeep *jsonDepthError
+		status, message := requestBodyError(err)
+		if errors.As(err, &tooDeep) || errors.Is(err, errReadingTooLarge) {
+			message = fmt.Sprintf("Invalid request body: %v", err)
+		}
+		writeJSON(w, status, ValidationResponse{Message: message})
+		return
+	}
+
+	violations := validateReading(reading)
+	if len(violations) > 0 {
+		slog.Info("Rejected submitted reading", "violations", len(violations))
+		writeJSON(w, http.StatusUnprocessableEntity, ValidationResponse{Violations: violations})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ValidationResponse{Valid: true})
+}
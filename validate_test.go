@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestValidateHandler tests the /weather/validate endpoint.
+func TestValidateHandler(t *testing.T) {
+	testCases := []struct {
+		name       string
+		body       string
+		status     int
+		violations int
+	}{
+		{"Valid", `{"city":"Tokyo","humidity":50,"condition":"Sunny"}`, http.StatusOK, 0},
+		{"EmptyCity", `{"city":"","humidity":50,"condition":"Sunny"}`, http.StatusUnprocessableEntity, 1},
+		{"HumidityTooHigh", `{"city":"Tokyo","humidity":101,"condition":"Sunny"}`, http.StatusUnprocessableEntity, 1},
+		{"UnknownCondition", `{"city":"Tokyo","humidity":50,"condition":"Hail"}`, http.StatusUnprocessableEntity, 1},
+		{"AllInvalid", `{"humidity":-1}`, http.StatusUnprocessableEntity, 3},
+		{"MalformedJSON", `{"city":`, http.StatusBadRequest, 0},
+		{"UnknownField", `{"city":"Tokyo","humidity":50,"condition":"Sunny","pressure":1013}`, http.StatusBadRequest, 0},
+		{"TooDeep", `{"city":{"name":[[["Tokyo"]]]}}`, http.StatusBadRequest, 0},
+		{"TooLarge", `{"city":"` + strings.Repeat("x", maxReadingBytes) + `"}`, http.StatusBadRequest, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/weather/validate", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			validateHandler(rr, req)
+
+			if rr.Code != tc.status {
+				t.Errorf("Validate handler returned wrong status code: got %v want %v", rr.Code, tc.status)
+			}
+
+			var responseData ValidationResponse
+			if err := json.NewDecoder(rr.Body).Decode(&responseData); err != nil {
+				t.Fatalf("Could not decode response: %v", err)
+			}
+			if responseData.Valid != (tc.status == http.StatusOK) {
+				t.Errorf("Validate handler returned wrong validity: got %v", responseData.Valid)
+			}
+			if len(responseData.Violations) != tc.violations {
+				t.Errorf("Validate handler returned unexpected violations: got %v want %d", responseData.Violations, tc.violations)
+			}
+		})
+	}
+}
+
+// TestGeneratedReadingsAreValid tests that the generator obeys the validation rules.
+func TestGeneratedReadingsAreValid(t *testing.T) {
+	for _, reading := range generateDummyWeatherReadings(r, 100) {
+		if violations := validateReading(reading); len(violations) > 0 {
+			t.Errorf("Generated reading %+v is invalid: %v", reading, violations)
+		}
+	}
+}
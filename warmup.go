@@ -0,0 +1,48 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Warmup models cold-start latency: for Duration after Start, or for the
+// first Requests requests, requests get an extra delay that starts at Delay
+// and decays linearly to zero. With both set, the warmup lasts until both
+// are over, and each request gets the larger of the two delays.
+type Warmup struct {
+	Start    time.Time
+	Duration time.Duration
+	Requests int64
+	Delay    time.Duration
+	// Now returns the current time; tests can replace it. Defaults to time.Now.
+	Now func() time.Time
+
+	served atomic.Int64 // Requests that have asked for their delay
+}
+
+func (wu *Warmup) now() time.Time {
+	if wu.Now != nil {
+		return wu.Now()
+	}
+	return time.Now()
+}
+
+// ExtraDelay returns the warmup delay to add to the current request and
+// counts it towards Requests. It is zero for a nil Warmup and once the
+// warmup is over.
+func (wu *Warmup) ExtraDelay() time.Duration {
+	if wu == nil {
+		return 0
+	}
+	var remaining float64
+	if wu.Duration > 0 {
+		remaining = max(remaining, float64(wu.Duration-wu.now().Sub(wu.Start))/float64(wu.Duration))
+	}
+	if wu.Requests > 0 {
+		remaining = max(remaining, float64(wu.Requests-wu.served.Add(1)+1)/float64(wu.Requests))
+	}
+	if remaining <= 0 {
+		return 0
+	}
+	return time.Duration(float64(wu.Delay) * remaining)
+}
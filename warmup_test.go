@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestWarmupExtraDelay tests that the warmup delay decays linearly to zero.
+func TestWarmupExtraDelay(t *testing.T) {
+	start := time.Unix(0, 0)
+	now := start
+	wu := &Warmup{Start: start, Duration: 10 * time.Second, Delay: time.Second, Now: func() time.Time { return now }}
+
+	testCases := []struct {
+		elapsed  time.Duration
+		expected time.Duration
+	}{
+		{0, time.Second},
+		{5 * time.Second, 500 * time.Millisecond},
+		{10 * time.Second, 0},
+		{time.Minute, 0},
+	}
+	for _, tc := range testCases {
+		now = start.Add(tc.elapsed)
+		if got := wu.ExtraDelay(); got != tc.expected {
+			t.Errorf("Wrong warmup delay after %v: got %v want %v", tc.elapsed, got, tc.expected)
+		}
+	}
+
+	var disabled *Warmup
+	if got := disabled.ExtraDelay(); got != 0 {
+		t.Errorf("Nil warmup returned a delay: %v", got)
+	}
+}
+
+// TestWarmupRequests tests that a request-count warmup decays linearly over
+// the first Requests requests, and that with a duration as well the larger
+// delay wins.
+func TestWarmupRequests(t *testing.T) {
+	wu := &Warmup{Requests: 4, Delay: time.Second}
+	for i, expected := range []time.Duration{time.Second, 750 * time.Millisecond, 500 * time.Millisecond, 250 * time.Millisecond, 0, 0} {
+		if got := wu.ExtraDelay(); got != expected {
+			t.Errorf("Wrong warmup delay for request %d: got %v want %v", i+1, got, expected)
+		}
+	}
+
+	start := time.Unix(0, 0)
+	now := start.Add(5 * time.Second)
+	both := &Warmup{Start: start, Duration: 10 * time.Second, Requests: 4, Delay: time.Second, Now: func() time.Time { return now }}
+	if got := both.ExtraDelay(); got != time.Second {
+		t.Errorf("Wrong warmup delay for the first request: got %v want %v", got, time.Second)
+	}
+	for range 3 {
+		both.ExtraDelay()
+	}
+	if got := both.ExtraDelay(); got != 500*time.Millisecond {
+		t.Errorf("Wrong warmup delay once the requests are over: got %v want %v", got, 500*time.Millisecond)
+	}
+}
+
+// TestWeatherHandlerWarmup tests that the warmup delay is added to the sleep.
+func TestWeatherHandlerWarmup(t *testing.T) {
+	recording := &recordingSleeper{}
+	svc := &WeatherService{
+		Sleeper: recording,
+		Chooser: chooser,
+		Warmup:  &Warmup{Start: time.Now(), Duration: time.Hour, Delay: time.Second},
+	}
+	svc.weather(httptest.NewRecorder(), httptest.NewRequest("GET", "/weather?delayMs=100", nil))
+
+	if recording.total <= 1000*time.Millisecond || recording.total > 1100*time.Millisecond {
+		t.Errorf("Handler slept for wrong duration: got %v want just under %v", recording.total, 1100*time.Millisecond)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// parseConditionWeights parses a value such as "Sunny:5,Rainy:2,Stormy:1" into
+// weights parallel to conditions. Conditions that aren't listed keep a weight
+// of 1; a weight of 0 excludes a condition.
+func parseConditionWeights(value string) ([]int, error) {
+	weights := make([]int, len(conditions))
+	for i := range weights {
+		weights[i] = 1
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		name, weightStr, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, errorOf(ErrBadConfig, "invalid condition weight %q, expected Condition:weight", pair)
+		}
+		index := -1
+		for i, condition := range conditions {
+			if strings.EqualFold(condition, strings.TrimSpace(name)) {
+				index = i
+			}
+		}
+		if index < 0 {
+			return nil, errorOf(ErrBadConfig, "unknown condition %q", name)
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(weightStr))
+		if err != nil || weight < 0 {
+			return nil, errorOf(ErrBadConfig, "invalid weight %q for condition %q", weightStr, name)
+		}
+		weights[index] = weight
+	}
+
+	total := 0
+	for _, weight := range weights {
+		total += weight
+	}
+	if total == 0 {
+		return nil, errorOf(ErrBadConfig, "condition weights must not all be zero")
+	}
+	return weights, nil
+}
+
+// pickCondition selects a condition using the configured condition weights
+// and the given random source.
+func pickCondition(r *rand.Rand) string {
+	weights := currentSettings().ConditionWeights
+	if weights == nil {
+		return conditions[r.Intn(len(conditions))]
+	}
+	return conditions[weightedIndex(r, weights)]
+}
+
+// weightedIndex returns an index into weights chosen with probability
+// proportional to its weight. The weights must not all be zero.
+func weightedIndex(r *rand.Rand, weights []int) int {
+	total := 0
+	for _, weight := range weights {
+		total += weight
+	}
+	n := r.Intn(total)
+	for i, weight := range weights {
+		if n < weight {
+			return i
+		}
+		n -= weight
+	}
+	return len(weights) - 1
+}
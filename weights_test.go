@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// TestParseConditionWeights tests parsing of WEATHER_CONDITION_WEIGHTS values.
+func TestParseConditionWeights(t *testing.T) {
+	weights, err := parseConditionWeights("Sunny:5, rainy:2,Stormy:0")
+	if err != nil {
+		t.Fatalf("parseConditionWeights returned an error: %v", err)
+	}
+	expected := map[string]int{"Sunny": 5, "Rainy": 2, "Stormy": 0, "Cloudy": 1}
+	for i, condition := range conditions {
+		if want, ok := expected[condition]; ok && weights[i] != want {
+			t.Errorf("Wrong weight for %s: got %d want %d", condition, weights[i], want)
+		}
+	}
+
+	for _, value := range []string{"Sunny", "Hail:1", "Sunny:-1", "Sunny:x"} {
+		if _, err := parseConditionWeights(value); err == nil {
+			t.Errorf("Expected an error for %q, but got none.", value)
+		}
+	}
+
+	allZero := ""
+	for i, condition := range conditions {
+		if i > 0 {
+			allZero += ","
+		}
+		allZero += condition + ":0"
+	}
+	if _, err := parseConditionWeights(allZero); err == nil {
+		t.Errorf("Expected an error when all weights are zero, but got none.")
+	}
+}
+
+// TestPickConditionWeighted tests that weighted selection honours the weights.
+func TestPickConditionWeighted(t *testing.T) {
+	weights, err := parseConditionWeights("Sunny:10")
+	if err != nil {
+		t.Fatalf("parseConditionWeights returned an error: %v", err)
+	}
+	settings.Store(testSettings(func(s *runtimeSettings) { s.ConditionWeights = weights }))
+	defer settings.Store(defaultRuntimeSettings())
+
+	rng := rand.New(rand.NewSource(1))
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[pickCondition(rng)]++
+	}
+
+	// Sunny has weight 10 out of a total of 16.
+	if sunny := float64(counts["Sunny"]) / 10000; sunny < 0.58 || sunny > 0.67 {
+		t.Errorf("Sunny was picked with unexpected frequency: got %.3f want about 0.625", sunny)
+	}
+}
+
+// TestParseStatusWeights tests parsing of WEATHER_STATUS_WEIGHTS values.
+func TestParseStatusWeights(t *testing.T) {
+	weights, err := parseStatusWeights("2xx:80, 5xx:20")
+	if err != nil {
+		t.Fatalf("parseStatusWeights returned an error: %v", err)
+	}
+	if !slices.Equal(weights, []int{80, 0, 20}) {
+		t.Errorf("Wrong weights: got %v want %v", weights, []int{80, 0, 20})
+	}
+	for _, value := range []string{"2xx", "3xx:1", "2xx:-1", "2xx:0,5xx:0"} {
+		if _, err := parseStatusWeights(value); !errors.Is(err, ErrBadConfig) {
+			t.Errorf("Expected an ErrBadConfig error for %q, got %v", value, err)
+		}
+	}
+}
+
+// TestStatusWeights tests that random statuses follow the configured weights.
+func TestStatusWeights(t *testing.T) {
+	settings.Store(testSettings(func(s *runtimeSettings) { s.StatusWeights = []int{0, 0, 1} }))
+	defer settings.Store(defaultRuntimeSettings())
+
+	rng := rand.New(rand.NewSource(1))
+	for range 100 {
+		if status := getResponseStatusCode(rng); status < 500 {
+			t.Fatalf("Picked status %d with only 5xx weighted", status)
+		}
+	}
+}